@@ -0,0 +1,225 @@
+// Command devicesim simulates an in-car telematics unit for local testing.
+//
+// It drives a random walk around a starting point and sends signed telemetry batches to the API,
+// using the credential issued with POST /api/admin/devices:
+//
+//	go run ./cmd/devicesim -device 3 -secret <hex key> -lat 18.47 -lng -69.93 -batches 10
+//
+// With -commands it also acts as a fake unit for the remote command channel: it long-polls
+// GET /device/commands, applies each command to its simulated lock state and acknowledges it.
+// -fail-rate makes a share of the acknowledgements fail and -ack-delay slows them down, which is
+// enough to exercise the unlock flow and the acknowledgement timeout end to end:
+//
+//	go run ./cmd/devicesim -device 3 -secret <hex key> -commands -batches 0 -fail-rate 0.1
+package main
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	middlewares "go-auth-api/src/middleware"
+	"go-auth-api/src/models"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// simulator keeps the state of the simulated vehicle and signs its requests
+type simulator struct {
+	baseURL string
+	device  int
+	secret  string
+	client  *http.Client
+
+	lat, lng float64
+	odometer float64
+	fuel     float64
+	battery  float64
+	heading  float64
+
+	locked      bool
+	immobilized bool
+}
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:3000", "API base URL")
+	device := flag.Int("device", 0, "device id")
+	secret := flag.String("secret", "", "device HMAC key (hex)")
+	lat := flag.Float64("lat", 18.4861, "starting latitude")
+	lng := flag.Float64("lng", -69.9312, "starting longitude")
+	batches := flag.Int("batches", 5, "number of batches to send (0 = forever)")
+	points := flag.Int("points", 10, "points per batch")
+	interval := flag.Duration("interval", 5*time.Second, "time between batches")
+	step := flag.Duration("step", time.Second, "time between the points of a batch")
+	commands := flag.Bool("commands", false, "also serve remote commands as a fake unit")
+	failRate := flag.Float64("fail-rate", 0, "share of commands acknowledged as failed (0-1)")
+	ackDelay := flag.Duration("ack-delay", 0, "delay before acknowledging each command")
+	flag.Parse()
+
+	if *device == 0 || *secret == "" {
+		log.Fatal("-device y -secret son requeridos")
+	}
+
+	sim := &simulator{
+		baseURL:  *baseURL,
+		device:   *device,
+		secret:   *secret,
+		client:   &http.Client{Timeout: commandPollWait + 10*time.Second},
+		lat:      *lat,
+		lng:      *lng,
+		odometer: 10000 + rand.Float64()*5000,
+		fuel:     80,
+		battery:  95,
+		heading:  rand.Float64() * 2 * math.Pi,
+		locked:   true,
+	}
+
+	if *commands {
+		go sim.serveCommands(*failRate, *ackDelay)
+	}
+	for i := 0; *batches == 0 || i < *batches; i++ {
+		if err := sim.sendBatch(*points, *step); err != nil {
+			log.Printf("lote %d: %v", i+1, err)
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// commandPollWait is the long-poll wait requested to the API
+const commandPollWait = 25 * time.Second
+
+// serveCommands long-polls the command queue and acknowledges every command received
+func (s *simulator) serveCommands(failRate float64, ackDelay time.Duration) {
+	path := fmt.Sprintf("/device/commands?wait=%d", int(commandPollWait.Seconds()))
+	for {
+		_, response, err := s.do(http.MethodGet, path, nil)
+		if err != nil {
+			log.Printf("comandos: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		var pending []models.VehicleCommand
+		if err := json.Unmarshal([]byte(response), &pending); err != nil {
+			log.Printf("comandos: respuesta inválida: %v", err)
+			continue
+		}
+		for _, cmd := range pending {
+			time.Sleep(ackDelay)
+			s.ackCommand(cmd, rand.Float64() >= failRate)
+		}
+	}
+}
+
+// ackCommand applies the command to the simulated state and reports the result
+func (s *simulator) ackCommand(cmd models.VehicleCommand, succeed bool) {
+	result := "simulado: fallo del actuador"
+	if succeed {
+		switch cmd.Command {
+		case models.CommandUnlock:
+			s.locked = false
+		case models.CommandLock:
+			s.locked = true
+		case models.CommandImmobilize:
+			s.immobilized = true
+		}
+		result = fmt.Sprintf("locked=%t immobilized=%t", s.locked, s.immobilized)
+	}
+	status := models.CommandFailed
+	if succeed {
+		status = models.CommandSucceeded
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"status": status, "result": result})
+	code, response, err := s.do(http.MethodPost, fmt.Sprintf("/device/commands/%d/ack", cmd.ID), body)
+	if err != nil {
+		log.Printf("comando %d (%s): %v %s", cmd.ID, cmd.Command, err, response)
+		return
+	}
+	log.Printf("comando %d (%s) -> %s [%d]", cmd.ID, cmd.Command, status, code)
+}
+
+// advance moves the vehicle by roughly 30 km/h during dt
+func (s *simulator) advance(dt time.Duration) models.TelemetryPoint {
+	s.heading += (rand.Float64() - 0.5) * 0.6
+	km := 30 * dt.Hours()
+	s.lat += km / 111.0 * math.Cos(s.heading)
+	s.lng += km / (111.0 * math.Cos(s.lat*math.Pi/180)) * math.Sin(s.heading)
+	s.odometer += km
+	s.fuel = math.Max(0, s.fuel-km*0.08)
+	s.battery = math.Max(0, s.battery-0.01)
+
+	lat, lng, odometer, fuel, battery := s.lat, s.lng, s.odometer, s.fuel, s.battery
+	charging := false
+	return models.TelemetryPoint{
+		Latitude:        &lat,
+		Longitude:       &lng,
+		OdometerKm:      &odometer,
+		FuelLevel:       &fuel,
+		BatteryLevel:    &battery,
+		BatteryCharging: &charging,
+	}
+}
+
+func (s *simulator) sendBatch(n int, step time.Duration) error {
+	now := time.Now()
+	batch := make([]models.TelemetryPoint, n)
+	for i := range batch {
+		batch[i] = s.advance(step)
+		batch[i].RecordedAt = now.Add(-time.Duration(n-1-i) * step).UTC()
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"points": batch})
+	if err != nil {
+		return err
+	}
+	status, response, err := s.do(http.MethodPost, "/device/telemetry", body)
+	if err != nil {
+		return err
+	}
+	log.Printf("%d %s", status, response)
+	return nil
+}
+
+// do sends a request signed as middlewares.DeviceAuthMiddleware expects
+func (s *simulator) do(method, path string, body []byte) (int, string, error) {
+	u, err := url.Parse(s.baseURL + path)
+	if err != nil {
+		return 0, "", err
+	}
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	// A fresh nonce per request; the server rejects repeated ones
+	random := make([]byte, 16)
+	if _, err := crand.Read(random); err != nil {
+		return 0, "", err
+	}
+	nonce := hex.EncodeToString(random)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middlewares.DeviceIDHeader, strconv.Itoa(s.device))
+	req.Header.Set(middlewares.DeviceTimestampHeader, timestamp)
+	req.Header.Set(middlewares.DeviceNonceHeader, nonce)
+	req.Header.Set(middlewares.DeviceSignatureHeader,
+		middlewares.SignDeviceRequest(s.secret, method, u.RequestURI(), timestamp, nonce, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, string(data), fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return resp.StatusCode, string(data), nil
+}
@@ -1,34 +1,80 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"go-auth-api/src/config"
+	"go-auth-api/src/events"
+	"go-auth-api/src/jobs"
+	"go-auth-api/src/mail"
+	"go-auth-api/src/models"
+	"go-auth-api/src/realtime"
 	routes "go-auth-api/src/router"
+	"go-auth-api/src/services"
+	"go-auth-api/src/storage"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
+	_ "time/tzdata" // Quiet hours use the users' timezones even where the host has no zoneinfo
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Load the environment variables from the .env file
-	err := godotenv.Load()
+	// Load the configuration from the environment, CONFIG_FILE or .env
+	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Fatalf("Invalid configuration:\n%v", err)
 	}
 
 	// Connect to the database
-	config.ConnectDB()
+	if err := config.ConnectDB(cfg.Database); err != nil {
+		log.Fatal("Error connecting to the database: ", err)
+	}
+
+	// Apply pending schema migrations
+	if err := config.Migrate(config.DB); err != nil {
+		log.Fatal("Error applying migrations: ", err)
+	}
+
+	// Configure the blob storage used for uploaded files
+	if err := storage.Init(cfg.Storage); err != nil {
+		log.Fatal("Error configuring storage: ", err)
+	}
+
+	// Wake the units' command long-polls on this replica when a command is queued on any of them
+	if err := realtime.Commands.Listen(context.Background(), cfg.Database.URL, models.VehicleCommandsChannel); err != nil {
+		log.Fatal("Error listening for vehicle commands: ", err)
+	}
+
+	// Sign the session tokens and the unsubscribe links of the notification emails
+	models.ConfigureJWT([]byte(cfg.JWT.Secret))
+	configureUnsubscribe(cfg)
+
+	// Periodic jobs run on one replica at a time, the one holding the scheduler lock
+	startScheduler(context.Background(), cfg.Reminders)
+
+	// React to the domain events written by the controllers
+	startEventBus(context.Background())
+
+	// Deliver the queued notifications in the background
+	services.NewDeliveryWorker(config.DB, notifiers(cfg)).Start(context.Background())
+
+	// Deliver the organizations' webhooks in the background
+	services.NewWebhookWorker(config.DB).Start(context.Background())
 
 	// Initialize the Gin router
 	r := gin.Default()
 
 	// Set up CORS middleware to allow cross-origin requests
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},                                       // Allow all origins for simplicity
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},            // HTTP methods allowed
+		AllowOrigins:     cfg.CORSOrigins,                                     // CORS_ALLOWED_ORIGINS, all by default
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},   // HTTP methods allowed
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"}, // Allowed headers
 		AllowCredentials: true,                                                // Allow credentials like cookies or auth headers
 		MaxAge:           12 * time.Hour,                                      // Cache the preflight response for 12 hours
@@ -37,12 +83,137 @@ func main() {
 	// Register the homepage route using Gin
 	r.GET("/", homePage) // Use Gin's method to handle requests to the root path
 
+	// Serve uploaded files when they are stored on the local filesystem
+	if storage.IsLocal() {
+		r.Static("/uploads", cfg.Storage.LocalDir)
+	}
+
 	// Set up all the other routes from the router package
 	routes.SetupRoutes(r)
 
-	// Start the server on port 3000
-	log.Println("Starting HTTPS server at http://192.168.0.104:3000")
-	r.Run(":3000")
+	// Serve TLS when a certificate is configured; in-car units may then authenticate with client certificates
+	addr := ":" + strconv.Itoa(cfg.Port)
+	if cfg.TLS.Enabled() {
+		tlsConfig, err := deviceTLSConfig(cfg.TLS.DeviceCAFile)
+		if err != nil {
+			log.Fatal("Error configuring TLS: ", err)
+		}
+		server := &http.Server{Addr: addr, Handler: r, TLSConfig: tlsConfig}
+		log.Printf("Starting HTTPS server on port %d", cfg.Port)
+		log.Fatal(server.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile))
+	}
+
+	// Start the server on the configured port
+	log.Printf("Starting HTTP server on port %d", cfg.Port)
+	log.Fatal(r.Run(addr))
+}
+
+// startScheduler registers the periodic jobs: maintenance reminders for the staff, the pickup,
+// return and overdue reminders of the reservations and the cleanup of expired device nonces
+func startScheduler(ctx context.Context, leads config.Reminders) {
+	reminders := jobs.NewReservationReminders(config.DB)
+	overrides := map[*time.Duration]*time.Duration{
+		&reminders.PickupLead:   leads.PickupLead,
+		&reminders.ReturnLead:   leads.ReturnLead,
+		&reminders.OverdueAfter: leads.OverdueAfter,
+	}
+	for dst, d := range overrides {
+		if d != nil {
+			*dst = *d
+		}
+	}
+
+	scheduler := jobs.NewScheduler(config.DB, jobs.SystemClock)
+	scheduler.Every("maintenance-reminders", jobs.MaintenanceReminderInterval, func(ctx context.Context, now time.Time) error {
+		_, err := jobs.RunMaintenanceReminders(config.DB)
+		return err
+	})
+	scheduler.Every("reservation-reminders", jobs.ReservationReminderInterval, reminders.Run)
+	scheduler.Every("device-nonces", jobs.DeviceNonceCleanupInterval, func(ctx context.Context, now time.Time) error {
+		return jobs.PurgeDeviceNonces(config.DB, now)
+	})
+	scheduler.Start(ctx)
+}
+
+// startEventBus registers the subscribers of the domain events and starts dispatching them. Subscriber
+// names are stored with their progress, so renaming one makes it start over with the new events.
+func startEventBus(ctx context.Context) {
+	events.Subscribe("notifications", events.NotifyCustomer, events.TypeReservationCreated, events.TypePaymentCaptured)
+	events.Subscribe("favorites", events.NotifyFavoriteAvailable, events.TypeVehicleStatusChanged, events.TypeTripFinished)
+	events.Subscribe("webhooks", events.ForwardToWebhooks, events.TypeReservationCreated,
+		events.TypeReservationCancelled, events.TypeTripStarted, events.TypeTripFinished, events.TypePaymentCaptured)
+	events.Subscribe("audit", events.RecordAudit)
+	events.NewDispatcher(config.DB).Start(ctx)
+}
+
+// notifiers builds the delivery channels. Email is only captured in memory when MAIL_TRANSPORT=capture;
+// SMS and push fall back to in-memory fakes that only log the messages when their provider
+// is not configured. SMTP certificates are verified unless SMTP_INSECURE_SKIP_VERIFY is set, which is
+// only meant for local test servers.
+func notifiers(cfg *config.Config) map[string]services.Notifier {
+	channels := map[string]services.Notifier{}
+
+	if cfg.MailTransport == config.MailCapture {
+		log.Println("MAIL_TRANSPORT=capture, emails are only kept in memory")
+		channels[models.ChannelEmail] = services.NewFakeNotifier(models.ChannelEmail)
+	} else {
+		mailer, err := mail.New(cfg.Mail)
+		if err != nil {
+			log.Fatal("Error configuring email: ", err)
+		}
+		channels[models.ChannelEmail] = services.EmailNotifier{Mail: mailer}
+	}
+
+	if cfg.SMS.GatewayURL != "" {
+		channels[models.ChannelSMS] = services.NewSMSNotifier(cfg.SMS.GatewayURL, cfg.SMS.Token, cfg.SMS.From)
+	} else {
+		log.Println("SMS_GATEWAY_URL not set, SMS notifications are only logged")
+		channels[models.ChannelSMS] = services.NewFakeNotifier(models.ChannelSMS)
+	}
+
+	if cfg.Push.FCMServerKey != "" {
+		push := services.NewPushNotifier(cfg.Push.FCMServerKey)
+		push.OnInvalidToken = func(token string) {
+			if err := models.ForgetPushToken(config.DB, token); err != nil {
+				log.Printf("Error removing push token: %v", err)
+			}
+		}
+		channels[models.ChannelPush] = push
+	} else {
+		log.Println("FCM_SERVER_KEY not set, push notifications are only logged")
+		channels[models.ChannelPush] = services.NewFakeNotifier(models.ChannelPush)
+	}
+	return channels
+}
+
+// configureUnsubscribe enables the unsubscribe links in the email footers. Links need the public URL
+// of the API; they are signed with UNSUBSCRIBE_SECRET, or JWT_SECRET when it is not set.
+func configureUnsubscribe(cfg *config.Config) {
+	if cfg.PublicBaseURL == "" {
+		log.Println("PUBLIC_BASE_URL not set, emails are sent without unsubscribe links")
+		return
+	}
+	models.ConfigureUnsubscribe(cfg.PublicBaseURL, []byte(cfg.UnsubscribeSecret))
+}
+
+// deviceTLSConfig requests client certificates signed by the devices CA, when one is configured.
+// Clients without a certificate (browsers, apps) are still accepted.
+func deviceTLSConfig(caFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile == "" {
+		return cfg, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	return cfg, nil
 }
 
 // homePage is a simple route handler for the root path that returns an HTML welcome message.
@@ -0,0 +1,315 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-auth-api/src/mail"
+	"go-auth-api/src/storage"
+	"io/fs"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config is the application configuration. It is loaded once at startup by Load and handed to the
+// components that need it.
+type Config struct {
+	Port          int
+	PublicBaseURL string   // Public URL of the API, used in the links of the emails; optional
+	CORSOrigins   []string // "*" allows any origin
+
+	Database Database
+	JWT      JWT
+	// UnsubscribeSecret signs the unsubscribe links of the emails. When UNSUBSCRIBE_SECRET is not set
+	// it is derived from the JWT secret, so the two keys are never the same.
+	UnsubscribeSecret string
+
+	MailTransport string      // MailSMTP or MailCapture
+	Mail          mail.Config // SMTP server; required with MailSMTP
+	SMS           SMS
+	Push          Push
+	Storage       storage.Config
+	TLS           TLS
+
+	Reminders Reminders
+}
+
+// Email transports. Capture keeps the emails in memory instead of sending them; it is only used when
+// MAIL_TRANSPORT asks for it, so a missing SMTP server is a configuration error rather than emails
+// silently not going out.
+const (
+	MailSMTP    = "smtp"
+	MailCapture = "capture"
+)
+
+// Database holds the PostgreSQL connection settings
+type Database struct {
+	URL string
+}
+
+// JWT holds the key of the session tokens
+type JWT struct {
+	Secret string
+}
+
+// SMS holds the SMS gateway settings; SMS are only logged when GatewayURL is empty
+type SMS struct {
+	GatewayURL string
+	Token      string
+	From       string
+}
+
+// Push holds the push provider settings; push notifications are only logged when FCMServerKey is empty
+type Push struct {
+	FCMServerKey string
+}
+
+// TLS enables HTTPS when CertFile and KeyFile are set. DeviceCAFile, when set, is the CA of the
+// client certificates of the in-car units.
+type TLS struct {
+	CertFile     string
+	KeyFile      string
+	DeviceCAFile string
+}
+
+// Enabled reports whether the server should serve HTTPS
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// Reminders overrides the lead times of the reservation reminders; nil keeps the job's default
+type Reminders struct {
+	PickupLead   *time.Duration
+	ReturnLead   *time.Duration
+	OverdueAfter *time.Duration
+}
+
+// Load reads the configuration from the environment. Variables that are not set are taken from the
+// file named by CONFIG_FILE, if any, and then from a .env file in the working directory, if there is
+// one. Every missing or invalid setting is reported in the returned error.
+func Load() (*Config, error) {
+	var files []map[string]string
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		values, err := godotenv.Read(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading CONFIG_FILE: %w", err)
+		}
+		files = append(files, values)
+	}
+	values, err := godotenv.Read(".env")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+	if err == nil {
+		files = append(files, values)
+	}
+
+	return Parse(func(key string) (string, bool) {
+		if v, ok := os.LookupEnv(key); ok {
+			return v, true
+		}
+		for _, values := range files {
+			if v, ok := values[key]; ok {
+				return v, true
+			}
+		}
+		return "", false
+	})
+}
+
+// Parse builds and validates the configuration from lookup, which returns the value of a variable
+// and whether it is set
+func Parse(lookup func(key string) (string, bool)) (*Config, error) {
+	r := &reader{lookup: lookup}
+	jwtSecret := r.string("JWT_SECRET", "")
+	cfg := &Config{
+		Port:              r.int("PORT", 3000),
+		PublicBaseURL:     r.string("PUBLIC_BASE_URL", ""),
+		CORSOrigins:       r.list("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		Database:          Database{URL: r.string("CONNECTIONSTRING", "")},
+		JWT:               JWT{Secret: jwtSecret},
+		UnsubscribeSecret: r.string("UNSUBSCRIBE_SECRET", deriveSecret(jwtSecret, "unsubscribe-links")),
+		SMS: SMS{
+			GatewayURL: r.string("SMS_GATEWAY_URL", ""),
+			Token:      r.string("SMS_GATEWAY_TOKEN", ""),
+			From:       r.string("SMS_FROM", ""),
+		},
+		MailTransport: r.string("MAIL_TRANSPORT", MailSMTP),
+		Push:          Push{FCMServerKey: r.string("FCM_SERVER_KEY", "")},
+		Storage: storage.Config{
+			Driver:      r.string("STORAGE_DRIVER", "local"),
+			LocalDir:    r.string("STORAGE_LOCAL_DIR", "./uploads"),
+			LocalURL:    r.string("STORAGE_PUBLIC_URL", "/uploads"),
+			S3Endpoint:  r.string("S3_ENDPOINT", ""),
+			S3Region:    r.string("S3_REGION", "us-east-1"),
+			S3Bucket:    r.string("S3_BUCKET", ""),
+			S3AccessKey: r.string("S3_ACCESS_KEY_ID", ""),
+			S3SecretKey: r.string("S3_SECRET_ACCESS_KEY", ""),
+			S3PublicURL: r.string("S3_PUBLIC_URL", ""),
+			S3PathStyle: r.bool("S3_PATH_STYLE"),
+		},
+		TLS: TLS{
+			CertFile:     r.string("TLS_CERT_FILE", ""),
+			KeyFile:      r.string("TLS_KEY_FILE", ""),
+			DeviceCAFile: r.string("DEVICE_CA_FILE", ""),
+		},
+		Reminders: Reminders{
+			PickupLead:   r.duration("PICKUP_REMINDER_LEAD"),
+			ReturnLead:   r.duration("RETURN_REMINDER_LEAD"),
+			OverdueAfter: r.duration("OVERDUE_ALERT_AFTER"),
+		},
+	}
+	// The SMTP settings are only read when emails are sent over SMTP
+	if cfg.MailTransport == MailSMTP {
+		cfg.Mail = mail.Config{
+			Host:               r.string("MAILTRAP_HOST", ""),
+			Port:               r.int("MAILTRAP_PORT", 587),
+			Username:           r.string("MAILTRAP_USERNAME", ""),
+			Password:           r.string("MAILTRAP_PASSWORD", ""),
+			From:               r.string("MAILTRAP_FROM", ""),
+			ReplyTo:            r.string("MAIL_REPLY_TO", ""),
+			ImplicitTLS:        r.bool("SMTP_IMPLICIT_TLS"),
+			InsecureSkipVerify: r.bool("SMTP_INSECURE_SKIP_VERIFY"),
+		}
+		if idle := r.duration("SMTP_IDLE_TIMEOUT"); idle != nil {
+			cfg.Mail.IdleTimeout = *idle
+		}
+	}
+
+	if err := errors.Join(append(r.errs, cfg.Validate())...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the settings that the application cannot start without, and that the optional
+// ones are consistent
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Port <= 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be between 1 and 65535, got %d", c.Port))
+	}
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("CONNECTIONSTRING is required"))
+	}
+	if c.JWT.Secret == "" {
+		errs = append(errs, errors.New("JWT_SECRET is required"))
+	}
+	if c.UnsubscribeSecret != "" && c.UnsubscribeSecret == c.JWT.Secret {
+		errs = append(errs, errors.New("UNSUBSCRIBE_SECRET must differ from JWT_SECRET; leave it unset to derive one"))
+	}
+	if c.PublicBaseURL != "" {
+		if u, err := url.Parse(c.PublicBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("PUBLIC_BASE_URL must be an absolute http(s) URL, got %q", c.PublicBaseURL))
+		}
+	}
+	if len(c.CORSOrigins) == 0 {
+		errs = append(errs, errors.New("CORS_ALLOWED_ORIGINS must list at least one origin"))
+	}
+	switch c.MailTransport {
+	case MailSMTP:
+		if c.Mail.Host == "" {
+			errs = append(errs, errors.New("MAILTRAP_HOST is required; set MAIL_TRANSPORT=capture to keep emails in memory instead"))
+		} else if err := c.Mail.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("SMTP: %w", err))
+		}
+	case MailCapture:
+	default:
+		errs = append(errs, fmt.Errorf("MAIL_TRANSPORT must be %s or %s, got %q", MailSMTP, MailCapture, c.MailTransport))
+	}
+	if err := c.Storage.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("storage: %w", err))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+	if c.TLS.DeviceCAFile != "" && !c.TLS.Enabled() {
+		errs = append(errs, errors.New("DEVICE_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE"))
+	}
+	return errors.Join(errs...)
+}
+
+// deriveSecret derives a key for one purpose from a master secret, so that a key handed to one
+// component cannot be used to sign anything for another. An empty master derives nothing.
+func deriveSecret(master, purpose string) string {
+	if master == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(master))
+	mac.Write([]byte(purpose))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// reader parses variables and collects the errors so they are all reported at once
+type reader struct {
+	lookup func(key string) (string, bool)
+	errs   []error
+}
+
+func (r *reader) string(key, fallback string) string {
+	if v, ok := r.lookup(key); ok && strings.TrimSpace(v) != "" {
+		return strings.TrimSpace(v)
+	}
+	return fallback
+}
+
+func (r *reader) int(key string, fallback int) int {
+	raw := r.string(key, "")
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be an integer, got %q", key, raw))
+		return fallback
+	}
+	return n
+}
+
+func (r *reader) bool(key string) bool {
+	raw := r.string(key, "")
+	if raw == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be true or false, got %q", key, raw))
+	}
+	return b
+}
+
+// duration returns nil when the variable is not set
+func (r *reader) duration(key string) *time.Duration {
+	raw := r.string(key, "")
+	if raw == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		r.errs = append(r.errs, fmt.Errorf("%s must be a non-negative duration such as 30m or 2h, got %q", key, raw))
+		return nil
+	}
+	return &d
+}
+
+// list splits a comma-separated variable
+func (r *reader) list(key string, fallback []string) []string {
+	raw := r.string(key, "")
+	if raw == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestParseMailTransport(t *testing.T) {
+	base := map[string]string{"CONNECTIONSTRING": "postgres://localhost/golan", "JWT_SECRET": "secret"}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string // MailTransport
+		wantErr string
+	}{
+		{"smtp by default", map[string]string{"MAILTRAP_HOST": "smtp.example.com", "MAILTRAP_FROM": "no-reply@example.com"},
+			MailSMTP, ""},
+		{"missing host is an error", nil, "", "MAILTRAP_HOST is required"},
+		{"explicit smtp still needs a host", map[string]string{"MAIL_TRANSPORT": "smtp"}, "", "MAILTRAP_HOST is required"},
+		{"invalid smtp settings", map[string]string{"MAILTRAP_HOST": "smtp.example.com", "MAILTRAP_FROM": "nobody"},
+			"", "SMTP:"},
+		{"capture needs no server", map[string]string{"MAIL_TRANSPORT": "capture"}, MailCapture, ""},
+		{"capture ignores the SMTP settings", map[string]string{"MAIL_TRANSPORT": "capture", "MAILTRAP_PORT": "x"},
+			MailCapture, ""},
+		{"unknown transport", map[string]string{"MAIL_TRANSPORT": "sendmail"}, "", "MAIL_TRANSPORT must be smtp or capture"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{}
+			for k, v := range base {
+				env[k] = v
+			}
+			for k, v := range tt.env {
+				env[k] = v
+			}
+			cfg, err := Parse(lookup(env))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if cfg.MailTransport != tt.want {
+				t.Errorf("MailTransport = %q, want %q", cfg.MailTransport, tt.want)
+			}
+		})
+	}
+}
+
+// unsetenv removes key from the environment for the rest of the test
+func unsetenv(t *testing.T, key string) {
+	t.Helper()
+	t.Setenv(key, "") // Restores the previous value when the test ends
+	os.Unsetenv(key)
+}
+
+// chdir moves the test into dir, where Load looks for .env
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	for _, key := range []string{"PORT", "JWT_SECRET", "CONNECTIONSTRING", "PUBLIC_BASE_URL", "MAIL_TRANSPORT",
+		"UNSUBSCRIBE_SECRET", "CONFIG_FILE"} {
+		unsetenv(t, key)
+	}
+	dir := t.TempDir()
+	chdir(t, dir)
+	writeFile(t, ".env", "PORT=4000\nJWT_SECRET=from-dotenv\nCONNECTIONSTRING=postgres://localhost/dotenv\n"+
+		"PUBLIC_BASE_URL=https://dotenv.example.com\nMAIL_TRANSPORT=capture\n")
+	writeFile(t, filepath.Join(dir, "app.env"), "PORT=5000\nJWT_SECRET=from-config-file\n")
+	t.Setenv("CONFIG_FILE", filepath.Join(dir, "app.env"))
+	t.Setenv("PORT", "6000")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct{ name, got, want string }{
+		{"PORT from the environment", strconv.Itoa(cfg.Port), "6000"},
+		{"JWT_SECRET from CONFIG_FILE", cfg.JWT.Secret, "from-config-file"},
+		{"CONNECTIONSTRING from .env", cfg.Database.URL, "postgres://localhost/dotenv"},
+		{"PUBLIC_BASE_URL from .env", cfg.PublicBaseURL, "https://dotenv.example.com"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, c.got, c.want)
+		}
+	}
+
+	t.Setenv("CONFIG_FILE", filepath.Join(dir, "missing.env"))
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "reading CONFIG_FILE") {
+		t.Errorf("Load() with a missing CONFIG_FILE: error = %v", err)
+	}
+}
+
+func TestParseReportsEveryError(t *testing.T) {
+	_, err := Parse(lookup(map[string]string{"PORT": "abc", "MAIL_TRANSPORT": "sendmail",
+		"PUBLIC_BASE_URL": "golancar.com"}))
+	if err == nil {
+		t.Fatal("Parse() succeeded with an invalid configuration")
+	}
+	for _, want := range []string{
+		`PORT must be an integer, got "abc"`,
+		"CONNECTIONSTRING is required",
+		"JWT_SECRET is required",
+		"PUBLIC_BASE_URL must be an absolute http(s) URL",
+		"MAIL_TRANSPORT must be smtp or capture",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse() error does not mention %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestParsePort(t *testing.T) {
+	tests := []struct {
+		port    string // Empty leaves PORT unset
+		want    int
+		wantErr string
+	}{
+		{"", 3000, ""},
+		{"8080", 8080, ""},
+		{" 8080 ", 8080, ""},
+		{"abc", 0, "PORT must be an integer"},
+		{"80.5", 0, "PORT must be an integer"},
+		{"0", 0, "PORT must be between 1 and 65535"},
+		{"-1", 0, "PORT must be between 1 and 65535"},
+		{"65536", 0, "PORT must be between 1 and 65535"},
+	}
+	for _, tt := range tests {
+		env := map[string]string{"CONNECTIONSTRING": "postgres://localhost/golan", "JWT_SECRET": "secret",
+			"MAIL_TRANSPORT": "capture"}
+		if tt.port != "" {
+			env["PORT"] = tt.port
+		}
+		cfg, err := Parse(lookup(env))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("PORT=%q: error = %v, want it to mention %q", tt.port, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("PORT=%q: error = %v", tt.port, err)
+		} else if cfg.Port != tt.want {
+			t.Errorf("PORT=%q: Port = %d, want %d", tt.port, cfg.Port, tt.want)
+		}
+	}
+}
+
+func TestParseUnsubscribeSecret(t *testing.T) {
+	base := func(extra map[string]string) map[string]string {
+		env := map[string]string{"CONNECTIONSTRING": "postgres://localhost/golan", "JWT_SECRET": "secret",
+			"MAIL_TRANSPORT": "capture"}
+		for k, v := range extra {
+			env[k] = v
+		}
+		return env
+	}
+
+	derived, err := Parse(lookup(base(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if derived.UnsubscribeSecret == "" || derived.UnsubscribeSecret == derived.JWT.Secret {
+		t.Errorf("derived UnsubscribeSecret = %q, want a key other than the JWT secret", derived.UnsubscribeSecret)
+	}
+	again, _ := Parse(lookup(base(nil)))
+	if again.UnsubscribeSecret != derived.UnsubscribeSecret {
+		t.Error("the derived secret changes between loads, so earlier unsubscribe links would stop working")
+	}
+	other, _ := Parse(lookup(base(map[string]string{"JWT_SECRET": "another"})))
+	if other.UnsubscribeSecret == derived.UnsubscribeSecret {
+		t.Error("the derived secret does not depend on the JWT secret")
+	}
+
+	explicit, err := Parse(lookup(base(map[string]string{"UNSUBSCRIBE_SECRET": "unsubscribe"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explicit.UnsubscribeSecret != "unsubscribe" {
+		t.Errorf("UnsubscribeSecret = %q, want the UNSUBSCRIBE_SECRET value", explicit.UnsubscribeSecret)
+	}
+
+	_, err = Parse(lookup(base(map[string]string{"UNSUBSCRIBE_SECRET": "secret"})))
+	if err == nil || !strings.Contains(err.Error(), "UNSUBSCRIBE_SECRET must differ from JWT_SECRET") {
+		t.Errorf("UNSUBSCRIBE_SECRET equal to JWT_SECRET: error = %v", err)
+	}
+}
@@ -4,35 +4,27 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
 
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 var DB *sql.DB
 
-func ConnectDB() {
-	// Cargar el archivo .env
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
-	// Obtener la cadena de conexión desde las variables de entorno
-	connStr := os.Getenv("CONNECTIONSTRING")
-
+// ConnectDB abre la conexión con la base de datos y comprueba que responde
+func ConnectDB(cfg Database) error {
 	// Conectar a la base de datos
-	DB, err = sql.Open("postgres", connStr)
+	db, err := sql.Open("postgres", cfg.URL)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Verificar la conexión
-	err = DB.Ping()
-	if err != nil {
-		log.Fatal("Cannot connect to DB", err)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return fmt.Errorf("cannot connect to the database: %w", err)
 	}
 
-	fmt.Println("Connected to database!")
+	DB = db
+	log.Println("Connected to database!")
+	return nil
 }
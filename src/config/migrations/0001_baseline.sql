@@ -0,0 +1,97 @@
+-- Baseline schema: the tables the API already relied on before migrations were tracked.
+-- Every statement is idempotent so it can run against an existing database.
+
+CREATE TABLE IF NOT EXISTS users (
+    id       SERIAL PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    email    TEXT UNIQUE,
+    phone    TEXT
+);
+
+CREATE TABLE IF NOT EXISTS brand (
+    id   SERIAL PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS model (
+    id   SERIAL PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS fuel_type (
+    id   SERIAL PRIMARY KEY,
+    type TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS vehicles (
+    id               SERIAL PRIMARY KEY,
+    brand_id         INTEGER NOT NULL REFERENCES brand (id),
+    model_id         INTEGER NOT NULL REFERENCES model (id),
+    fuel_type_id     INTEGER NOT NULL REFERENCES fuel_type (id),
+    license_plate    TEXT NOT NULL,
+    latitude         DOUBLE PRECISION NOT NULL DEFAULT 0,
+    longitude        DOUBLE PRECISION NOT NULL DEFAULT 0,
+    type             TEXT,
+    distance         DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fuel_efficiency  DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fuel_consumption DOUBLE PRECISION NOT NULL DEFAULT 0,
+    status           TEXT,
+    rating           DOUBLE PRECISION NOT NULL DEFAULT 0,
+    is_booked        BOOLEAN NOT NULL DEFAULT FALSE,
+    is_reserved      BOOLEAN NOT NULL DEFAULT FALSE,
+    is_available     BOOLEAN NOT NULL DEFAULT TRUE,
+    is_rented        BOOLEAN NOT NULL DEFAULT FALSE,
+    is_favorited     BOOLEAN NOT NULL DEFAULT FALSE,
+    is_economic      BOOLEAN NOT NULL DEFAULT FALSE,
+    is_luxury        BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS pricing (
+    id               SERIAL PRIMARY KEY,
+    vehicle_id       INTEGER NOT NULL REFERENCES vehicles (id),
+    price_per_minute NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    price_per_mile   NUMERIC(10, 2) NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS vehicle_images (
+    id         SERIAL PRIMARY KEY,
+    vehicle_id INTEGER NOT NULL REFERENCES vehicles (id),
+    image_url  TEXT NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_images_vehicle_id_idx ON vehicle_images (vehicle_id);
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id),
+    vehicle_id INTEGER NOT NULL REFERENCES vehicles (id),
+    start_time TIMESTAMPTZ NOT NULL,
+    end_time   TIMESTAMPTZ NOT NULL,
+    status     TEXT NOT NULL DEFAULT 'activa'
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id      SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id),
+    message TEXT NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS payments (
+    id             SERIAL PRIMARY KEY,
+    reservation_id INTEGER NOT NULL REFERENCES reservations (id),
+    amount         NUMERIC(10, 2) NOT NULL,
+    status         TEXT NOT NULL,
+    payment_date   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS characters (
+    id       INTEGER PRIMARY KEY,
+    name     TEXT NOT NULL,
+    imageurl TEXT,
+    status   TEXT,
+    gender   TEXT,
+    species  TEXT
+);
@@ -0,0 +1,15 @@
+-- Roles for the admin endpoints and the constraints fleet management relies on
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'customer';
+
+ALTER TABLE model ADD COLUMN IF NOT EXISTS brand_id INTEGER REFERENCES brand (id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS brand_name_key ON brand (LOWER(name));
+CREATE UNIQUE INDEX IF NOT EXISTS fuel_type_type_key ON fuel_type (LOWER(type));
+CREATE UNIQUE INDEX IF NOT EXISTS vehicles_license_plate_key ON vehicles (UPPER(license_plate));
+CREATE UNIQUE INDEX IF NOT EXISTS pricing_vehicle_id_key ON pricing (vehicle_id);
+
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS retired_at TIMESTAMPTZ;
+
+ALTER TABLE pricing ADD CONSTRAINT pricing_non_negative
+    CHECK (price_per_minute >= 0 AND price_per_mile >= 0) NOT VALID;
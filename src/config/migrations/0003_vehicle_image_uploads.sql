@@ -0,0 +1,20 @@
+-- Uploaded vehicle images: storage keys, thumbnails and display order
+
+ALTER TABLE vehicle_images ADD COLUMN IF NOT EXISTS position      INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE vehicle_images ADD COLUMN IF NOT EXISTS thumbnail_url TEXT;
+ALTER TABLE vehicle_images ADD COLUMN IF NOT EXISTS storage_key   TEXT;
+ALTER TABLE vehicle_images ADD COLUMN IF NOT EXISTS thumbnail_key TEXT;
+ALTER TABLE vehicle_images ADD COLUMN IF NOT EXISTS content_type  TEXT;
+ALTER TABLE vehicle_images ADD COLUMN IF NOT EXISTS created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- Keep the existing order stable for images inserted by hand
+UPDATE vehicle_images vi SET position = o.rn
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY vehicle_id ORDER BY is_primary DESC, id) AS rn
+      FROM vehicle_images) o
+WHERE vi.id = o.id AND vi.position = 0;
+
+-- At most one primary image per vehicle
+UPDATE vehicle_images vi SET is_primary = FALSE
+WHERE is_primary AND EXISTS (SELECT 1 FROM vehicle_images o
+                             WHERE o.vehicle_id = vi.vehicle_id AND o.is_primary AND o.id < vi.id);
+CREATE UNIQUE INDEX IF NOT EXISTS vehicle_images_one_primary ON vehicle_images (vehicle_id) WHERE is_primary;
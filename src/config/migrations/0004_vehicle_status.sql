@@ -0,0 +1,6 @@
+-- Vehicle status is an enumerated set; rows written before the check are left untouched
+
+ALTER TABLE vehicles ALTER COLUMN status SET DEFAULT 'available';
+
+ALTER TABLE vehicles ADD CONSTRAINT vehicles_status_check
+    CHECK (status IN ('available', 'reserved', 'rented', 'maintenance', 'out_of_service', 'retired')) NOT VALID;
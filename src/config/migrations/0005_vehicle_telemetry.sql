@@ -0,0 +1,23 @@
+-- Time series of the points reported by the in-car units and the latest readings per vehicle
+
+CREATE TABLE IF NOT EXISTS vehicle_telemetry (
+    id               BIGSERIAL PRIMARY KEY,
+    vehicle_id       INTEGER NOT NULL REFERENCES vehicles (id),
+    reservation_id   INTEGER REFERENCES reservations (id),
+    recorded_at      TIMESTAMPTZ NOT NULL,
+    received_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    latitude         DOUBLE PRECISION NOT NULL,
+    longitude        DOUBLE PRECISION NOT NULL,
+    odometer_km      DOUBLE PRECISION,
+    fuel_level       DOUBLE PRECISION,
+    battery_level    DOUBLE PRECISION,
+    battery_charging BOOLEAN
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_telemetry_vehicle_time_idx ON vehicle_telemetry (vehicle_id, recorded_at);
+CREATE INDEX IF NOT EXISTS vehicle_telemetry_reservation_idx ON vehicle_telemetry (reservation_id) WHERE reservation_id IS NOT NULL;
+
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS odometer_km       DOUBLE PRECISION;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS fuel_level        DOUBLE PRECISION;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS battery_level     DOUBLE PRECISION;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS last_telemetry_at TIMESTAMPTZ;
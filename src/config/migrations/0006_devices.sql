@@ -0,0 +1,19 @@
+-- Registry of in-car telematics units and their credentials
+
+CREATE TABLE IF NOT EXISTS devices (
+    id               SERIAL PRIMARY KEY,
+    vehicle_id       INTEGER NOT NULL REFERENCES vehicles (id),
+    name             TEXT NOT NULL DEFAULT '',
+    auth_type        TEXT NOT NULL CHECK (auth_type IN ('hmac', 'certificate')),
+    secret           TEXT,             -- HMAC key (hex); only for auth_type = 'hmac'
+    cert_fingerprint TEXT,             -- SHA-256 of the client certificate (hex); only for 'certificate'
+    status           TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'revoked')),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    rotated_at       TIMESTAMPTZ,
+    revoked_at       TIMESTAMPTZ,
+    last_seen_at     TIMESTAMPTZ
+);
+
+-- One active unit per vehicle, and a certificate identifies a single active unit
+CREATE UNIQUE INDEX IF NOT EXISTS devices_active_vehicle_key ON devices (vehicle_id) WHERE status = 'active';
+CREATE UNIQUE INDEX IF NOT EXISTS devices_active_fingerprint_key ON devices (LOWER(cert_fingerprint)) WHERE status = 'active';
@@ -0,0 +1,25 @@
+-- Service areas and no-go zones, and the alerts raised when a rented vehicle breaks them
+
+CREATE TABLE IF NOT EXISTS zones (
+    id         SERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    kind       TEXT NOT NULL CHECK (kind IN ('service_area', 'no_go')),
+    polygon    JSONB NOT NULL, -- [{"lat": .., "lng": ..}, ...]
+    active     BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS geofence_alerts (
+    id             SERIAL PRIMARY KEY,
+    vehicle_id     INTEGER NOT NULL REFERENCES vehicles (id),
+    reservation_id INTEGER REFERENCES reservations (id),
+    zone_id        INTEGER REFERENCES zones (id) ON DELETE SET NULL,
+    kind           TEXT NOT NULL CHECK (kind IN ('left_service_area', 'entered_no_go')),
+    latitude       DOUBLE PRECISION NOT NULL,
+    longitude      DOUBLE PRECISION NOT NULL,
+    recorded_at    TIMESTAMPTZ NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS geofence_alerts_vehicle_idx ON geofence_alerts (vehicle_id, created_at DESC);
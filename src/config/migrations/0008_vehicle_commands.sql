@@ -0,0 +1,19 @@
+-- Remote commands queued for the in-car units
+
+CREATE TABLE IF NOT EXISTS vehicle_commands (
+    id             SERIAL PRIMARY KEY,
+    vehicle_id     INTEGER NOT NULL REFERENCES vehicles (id),
+    reservation_id INTEGER REFERENCES reservations (id),
+    requested_by   INTEGER REFERENCES users (id),
+    command        TEXT NOT NULL CHECK (command IN ('unlock', 'lock', 'honk', 'immobilize')),
+    status         TEXT NOT NULL DEFAULT 'pending'
+                   CHECK (status IN ('pending', 'delivered', 'succeeded', 'failed', 'expired')),
+    result         TEXT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at   TIMESTAMPTZ,
+    completed_at   TIMESTAMPTZ,
+    expires_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_commands_pending_idx ON vehicle_commands (vehicle_id, created_at)
+    WHERE status IN ('pending', 'delivered');
@@ -0,0 +1,45 @@
+-- Maintenance history, downtime windows and service schedules
+
+CREATE TABLE IF NOT EXISTS maintenance_records (
+    id           SERIAL PRIMARY KEY,
+    vehicle_id   INTEGER NOT NULL REFERENCES vehicles (id),
+    type         TEXT NOT NULL,
+    performed_at TIMESTAMPTZ NOT NULL,
+    cost         NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (cost >= 0),
+    odometer_km  DOUBLE PRECISION CHECK (odometer_km >= 0),
+    notes        TEXT,
+    created_by   INTEGER REFERENCES users (id),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS maintenance_records_vehicle_idx ON maintenance_records (vehicle_id, performed_at DESC);
+
+CREATE TABLE IF NOT EXISTS vehicle_downtime (
+    id         SERIAL PRIMARY KEY,
+    vehicle_id INTEGER NOT NULL REFERENCES vehicles (id),
+    start_time TIMESTAMPTZ NOT NULL,
+    end_time   TIMESTAMPTZ NOT NULL,
+    reason     TEXT,
+    created_by INTEGER REFERENCES users (id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (end_time > start_time)
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_downtime_vehicle_idx ON vehicle_downtime (vehicle_id, start_time, end_time);
+
+CREATE TABLE IF NOT EXISTS maintenance_schedules (
+    id               SERIAL PRIMARY KEY,
+    vehicle_id       INTEGER NOT NULL REFERENCES vehicles (id),
+    type             TEXT NOT NULL,
+    interval_days    INTEGER CHECK (interval_days > 0),
+    interval_km      DOUBLE PRECISION CHECK (interval_km > 0),
+    last_done_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_done_km     DOUBLE PRECISION,
+    last_notified_at TIMESTAMPTZ,
+    active           BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (interval_days IS NOT NULL OR interval_km IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS maintenance_schedules_vehicle_type_idx
+    ON maintenance_schedules (vehicle_id, LOWER(type));
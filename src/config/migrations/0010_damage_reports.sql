@@ -0,0 +1,48 @@
+-- Condition checklists at trip start/end and damage reports with photo evidence
+
+CREATE TABLE IF NOT EXISTS condition_checklists (
+    id             SERIAL PRIMARY KEY,
+    reservation_id INTEGER NOT NULL REFERENCES reservations (id),
+    stage          TEXT NOT NULL CHECK (stage IN ('start', 'end')),
+    items          JSONB NOT NULL,
+    fuel_level     DOUBLE PRECISION CHECK (fuel_level BETWEEN 0 AND 100),
+    odometer_km    DOUBLE PRECISION CHECK (odometer_km >= 0),
+    notes          TEXT,
+    submitted_by   INTEGER NOT NULL REFERENCES users (id),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (reservation_id, stage)
+);
+
+-- Payments now also record damage charges, not only rentals
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'rental';
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS description TEXT;
+
+CREATE TABLE IF NOT EXISTS damage_reports (
+    id                SERIAL PRIMARY KEY,
+    reservation_id    INTEGER NOT NULL REFERENCES reservations (id),
+    vehicle_id        INTEGER NOT NULL REFERENCES vehicles (id),
+    reported_by       INTEGER NOT NULL REFERENCES users (id),
+    location_on_car   TEXT NOT NULL,
+    severity          TEXT NOT NULL CHECK (severity IN ('minor', 'moderate', 'severe')),
+    description       TEXT,
+    occurred_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    status            TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'charged', 'dismissed')),
+    charge_payment_id INTEGER REFERENCES payments (id),
+    resolved_by       INTEGER REFERENCES users (id),
+    resolved_at       TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS damage_reports_reservation_idx ON damage_reports (reservation_id);
+CREATE INDEX IF NOT EXISTS damage_reports_open_idx ON damage_reports (created_at) WHERE status = 'open';
+
+CREATE TABLE IF NOT EXISTS damage_photos (
+    id            SERIAL PRIMARY KEY,
+    report_id     INTEGER NOT NULL REFERENCES damage_reports (id) ON DELETE CASCADE,
+    image_url     TEXT NOT NULL,
+    thumbnail_url TEXT,
+    storage_key   TEXT,
+    thumbnail_key TEXT,
+    content_type  TEXT,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,19 @@
+-- Reviews left by customers after a completed reservation; vehicles.rating becomes their aggregate
+
+CREATE TABLE IF NOT EXISTS vehicle_reviews (
+    id                SERIAL PRIMARY KEY,
+    reservation_id    INTEGER NOT NULL UNIQUE REFERENCES reservations (id),
+    vehicle_id        INTEGER NOT NULL REFERENCES vehicles (id),
+    user_id           INTEGER NOT NULL REFERENCES users (id),
+    rating            SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment           TEXT,
+    status            TEXT NOT NULL DEFAULT 'published' CHECK (status IN ('published', 'hidden')),
+    moderation_reason TEXT,
+    moderated_by      INTEGER REFERENCES users (id),
+    moderated_at      TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_reviews_vehicle_idx ON vehicle_reviews (vehicle_id, id DESC) WHERE status = 'published';
+
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS rating_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,18 @@
+-- Per-user favorites, replacing the global vehicles.is_favorited flag
+
+CREATE TABLE IF NOT EXISTS user_favorites (
+    user_id               INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    vehicle_id            INTEGER NOT NULL REFERENCES vehicles (id) ON DELETE CASCADE,
+    notify_when_available BOOLEAN NOT NULL DEFAULT FALSE,
+    notify_latitude       DOUBLE PRECISION,
+    notify_longitude      DOUBLE PRECISION,
+    notify_radius_km      DOUBLE PRECISION CHECK (notify_radius_km > 0),
+    last_notified_at      TIMESTAMPTZ,
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, vehicle_id)
+);
+
+CREATE INDEX IF NOT EXISTS user_favorites_notify_idx ON user_favorites (vehicle_id) WHERE notify_when_available;
+
+-- The shared flag cannot be attributed to any user, so it is dropped rather than migrated
+ALTER TABLE vehicles DROP COLUMN IF EXISTS is_favorited;
@@ -0,0 +1,9 @@
+-- Availability is derived from status, reservations and downtime at read time; the stored flags
+-- were never kept in sync and are dropped
+
+ALTER TABLE vehicles DROP COLUMN IF EXISTS is_booked;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS is_reserved;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS is_available;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS is_rented;
+
+CREATE INDEX IF NOT EXISTS reservations_vehicle_active_idx ON reservations (vehicle_id, end_time) WHERE status = 'activa';
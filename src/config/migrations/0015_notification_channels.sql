@@ -0,0 +1,37 @@
+-- Notifications are delivered over email, SMS and push. The email outbox becomes the delivery
+-- queue of every channel: one row per notification, channel and recipient, with its own status.
+
+ALTER TABLE email_outbox RENAME TO notification_deliveries;
+ALTER TABLE notification_deliveries RENAME COLUMN to_address TO recipient;
+ALTER INDEX email_outbox_due_idx RENAME TO notification_deliveries_due_idx;
+
+ALTER TABLE notification_deliveries
+    ADD COLUMN channel         TEXT NOT NULL DEFAULT 'email' CHECK (channel IN ('email', 'sms', 'push')),
+    ADD COLUMN notification_id INTEGER REFERENCES notifications (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS notification_deliveries_notification_idx
+    ON notification_deliveries (notification_id) WHERE notification_id IS NOT NULL;
+
+-- Title used by email subjects and push notifications
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS subject TEXT NOT NULL DEFAULT '';
+
+-- Channels each user wants to be reached on; missing rows fall back to the defaults in the code
+CREATE TABLE IF NOT EXISTS notification_channel_preferences (
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    channel    TEXT NOT NULL CHECK (channel IN ('email', 'sms', 'push')),
+    enabled    BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, channel)
+);
+
+-- Devices registered for push notifications; a token belongs to the last user that registered it
+CREATE TABLE IF NOT EXISTS push_tokens (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token        TEXT NOT NULL UNIQUE,
+    platform     TEXT NOT NULL CHECK (platform IN ('android', 'ios', 'web')),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS push_tokens_user_idx ON push_tokens (user_id);
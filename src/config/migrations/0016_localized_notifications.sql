@@ -0,0 +1,6 @@
+-- Notifications are rendered from templates in the user's language, and emails carry a plain-text
+-- alternative next to the HTML body
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'es' CHECK (language IN ('es', 'en'));
+
+ALTER TABLE notification_deliveries ADD COLUMN IF NOT EXISTS text_body TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+-- Reminders already sent for each reservation, so the scheduler never sends the same one twice
+
+CREATE TABLE IF NOT EXISTS reservation_reminders (
+    reservation_id INTEGER NOT NULL REFERENCES reservations (id) ON DELETE CASCADE,
+    kind           TEXT NOT NULL CHECK (kind IN ('pickup', 'return', 'overdue')),
+    sent_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (reservation_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS reservations_active_start_idx ON reservations (start_time) WHERE status = 'activa';
+CREATE INDEX IF NOT EXISTS reservations_active_end_idx ON reservations (end_time) WHERE status = 'activa';
@@ -0,0 +1,9 @@
+-- Inbox state of the notifications: read and deleted by the user. Deleted notifications are kept so
+-- their delivery history survives.
+
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS read_at    TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS notifications_inbox_idx ON notifications (user_id, id DESC) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL AND deleted_at IS NULL;
@@ -0,0 +1,22 @@
+-- Per-user notification settings: quiet hours in the user's timezone, marketing opt-in and, per
+-- notification type, overrides of the channel preferences
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'message';
+
+CREATE TABLE IF NOT EXISTS notification_settings (
+    user_id          INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    timezone         TEXT NOT NULL DEFAULT 'UTC',
+    quiet_start      TIME,
+    quiet_end        TIME,
+    marketing_opt_in BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((quiet_start IS NULL) = (quiet_end IS NULL))
+);
+
+CREATE TABLE IF NOT EXISTS notification_type_preferences (
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    type    TEXT NOT NULL,
+    channel TEXT NOT NULL CHECK (channel IN ('email', 'sms', 'push')),
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, type, channel)
+);
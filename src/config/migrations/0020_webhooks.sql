@@ -0,0 +1,75 @@
+-- Corporate clients: organizations group the accounts of their employees and receive webhooks about
+-- their trips.
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id         SERIAL PRIMARY KEY,
+    name       TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Members of an organization; its admins manage the webhook subscriptions
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS organization_id   INTEGER REFERENCES organizations (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS organization_role TEXT CHECK (organization_role IN ('member', 'admin'));
+
+CREATE INDEX IF NOT EXISTS users_organization_idx ON users (organization_id) WHERE organization_id IS NOT NULL;
+
+-- Endpoints of an organization; an empty event_types receives every event
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id              SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    url             TEXT NOT NULL,
+    description     TEXT NOT NULL DEFAULT '',
+    event_types     TEXT[] NOT NULL DEFAULT '{}',
+    secret          TEXT NOT NULL,
+    active          BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_subscriptions_organization_idx ON webhook_subscriptions (organization_id);
+
+-- Events emitted by the reservation and payment flows, written in the transaction of the change.
+-- user_id is the member the event is about.
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id              BIGSERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id         INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    type            TEXT NOT NULL,
+    payload         JSONB NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_events_organization_idx ON webhook_events (organization_id, id);
+
+-- One delivery per event and subscription, retried with backoff like the notification deliveries
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id        BIGINT NOT NULL REFERENCES webhook_events (id) ON DELETE CASCADE,
+    status          TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sending', 'delivered', 'dead')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    max_attempts    INTEGER NOT NULL DEFAULT 10,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until    TIMESTAMPTZ,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMPTZ,
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON webhook_deliveries (next_attempt_at) WHERE status IN ('pending', 'sending');
+
+-- Log of every attempt, shown to the subscriber
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id            BIGSERIAL PRIMARY KEY,
+    delivery_id   BIGINT NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    attempted_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    status_code   INTEGER,
+    response_body TEXT NOT NULL DEFAULT '',
+    error         TEXT,
+    duration_ms   INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_idx ON webhook_attempts (delivery_id, id);
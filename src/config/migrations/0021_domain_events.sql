@@ -0,0 +1,49 @@
+-- Domain events: the outbox of the in-process event bus. Events are written in the transaction of the
+-- change; the dispatcher later creates one handler row per registered subscriber and runs them.
+-- user_id is the customer the event is about, actor_id who caused it (NULL for devices and jobs).
+
+CREATE TABLE IF NOT EXISTS domain_events (
+    id            BIGSERIAL PRIMARY KEY,
+    type          TEXT NOT NULL,
+    user_id       INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    actor_id      INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    payload       JSONB NOT NULL,
+    occurred_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    fanned_out_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS domain_events_pending_idx ON domain_events (id) WHERE fanned_out_at IS NULL;
+CREATE INDEX IF NOT EXISTS domain_events_type_idx ON domain_events (type, id);
+
+-- Progress of each subscriber on each event, retried with backoff like the notification deliveries
+CREATE TABLE IF NOT EXISTS domain_event_handlers (
+    event_id        BIGINT NOT NULL REFERENCES domain_events (id) ON DELETE CASCADE,
+    subscriber      TEXT NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'done', 'dead')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    max_attempts    INTEGER NOT NULL DEFAULT 10,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error      TEXT,
+    handled_at      TIMESTAMPTZ,
+    PRIMARY KEY (event_id, subscriber)
+);
+
+CREATE INDEX IF NOT EXISTS domain_event_handlers_due_idx
+    ON domain_event_handlers (next_attempt_at) WHERE status = 'pending';
+
+-- Audit log of the business changes, written by the audit subscriber
+CREATE TABLE IF NOT EXISTS audit_log (
+    id          BIGSERIAL PRIMARY KEY,
+    event_id    BIGINT UNIQUE REFERENCES domain_events (id) ON DELETE SET NULL,
+    type        TEXT NOT NULL,
+    entity      TEXT NOT NULL,
+    entity_id   BIGINT NOT NULL,
+    user_id     INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    actor_id    INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    data        JSONB NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (entity, entity_id, id);
+CREATE INDEX IF NOT EXISTS audit_log_user_idx ON audit_log (user_id, id) WHERE user_id IS NOT NULL;
@@ -0,0 +1,4 @@
+-- The delivery log keeps only the status code of each attempt. Response bodies could hold whatever
+-- the receiver (or anything it redirected to) answered, so the ones already stored are dropped.
+
+ALTER TABLE webhook_attempts DROP COLUMN IF EXISTS response_body;
@@ -0,0 +1,12 @@
+-- Nonces of the signed device requests. They were remembered in memory, so a request replayed against
+-- another replica, or after a restart, was accepted again. A nonce stays until expires_at, which covers
+-- the allowed timestamp skew in both directions.
+
+CREATE TABLE IF NOT EXISTS device_nonces (
+    device_id  INTEGER NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    nonce      TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (device_id, nonce)
+);
+
+CREATE INDEX IF NOT EXISTS device_nonces_expires_at_idx ON device_nonces (expires_at);
@@ -5,15 +5,12 @@ import (
 	"go-auth-api/src/models"
 	"log"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte(os.Getenv("JWT_SECRET")) // Obtener clave JWT desde variables de entorno
-
 // Registrar nuevo usuario
 func Register(c *gin.Context) {
 	var user models.User
@@ -27,7 +24,7 @@ func Register(c *gin.Context) {
 	// Intentar registrar al usuario
 	if err := user.Register(config.DB); err != nil {
 		log.Printf("Error registrando usuario: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar el usuario"})
+		writeModelError(c, err, "No se pudo registrar el usuario")
 		return
 	}
 
@@ -61,14 +58,15 @@ func Login(c *gin.Context) {
 	// Generar token JWT
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &models.Claims{
+		UserID:   user.ID,
 		Username: user.Username,
+		Role:     user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, err := models.SignClaims(claims)
 	if err != nil {
 		log.Printf("Error generando token para usuario %s: %v", credentials.Username, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo generar el token"})
@@ -80,6 +78,7 @@ func Login(c *gin.Context) {
 		"success": true,
 		"id":      user.ID,
 		"email":   credentials.Username,
+		"role":    user.Role,
 		"token":   tokenString,
 	})
 }
@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"errors"
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"go-auth-api/src/realtime"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCommandWait limita la espera de un long-poll de comandos; debe quedar por debajo de
+// models.CommandDeliveryTimeout para que un comando encolado al final de una espera no venza antes
+// de que la unidad vuelva a conectarse
+const maxCommandWait = 30 * time.Second
+
+// writeCommandError traduce los errores de los comandos remotos
+func writeCommandError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, models.ErrCommandNotAllowed), errors.Is(err, models.ErrOutsideReservationWindow):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		writeModelError(c, err, message)
+	}
+}
+
+// queueCommand encola el comando y despierta a la unidad del vehículo si está conectada, en esta
+// réplica directamente y en las demás con NOTIFY
+func queueCommand(c *gin.Context, cmd *models.VehicleCommand) {
+	if err := cmd.Create(config.DB); err != nil {
+		writeCommandError(c, err, "No se pudo enviar el comando")
+		return
+	}
+	realtime.Commands.Notify(cmd.VehicleID)
+	if err := models.NotifyVehicleCommands(config.DB, cmd.VehicleID); err != nil {
+		// El comando ya está encolado; la unidad lo recibe en su siguiente consulta
+		log.Printf("No se pudo avisar del comando %d a las otras réplicas: %v", cmd.ID, err)
+	}
+	c.JSON(http.StatusAccepted, cmd)
+}
+
+// CreateReservationCommand permite al cliente abrir, cerrar o hacer sonar el vehículo de su reserva
+// mientras ésta está en curso
+func CreateReservationCommand(c *gin.Context) {
+	reservationID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var input struct {
+		Command string `json:"command" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	var reservation models.Reservation
+	if err := reservation.GetByID(config.DB, reservationID); err != nil {
+		writeModelError(c, err, "No se pudo obtener la reserva")
+		return
+	}
+	userID, _ := currentUser(c)
+	if err := models.CheckCustomerCommand(reservation, userID, input.Command, time.Now()); err != nil {
+		writeCommandError(c, err, "No se pudo enviar el comando")
+		return
+	}
+	// El viaje empieza con la apertura: antes debe registrarse el estado del vehículo
+	if input.Command == models.CommandUnlock {
+		hasChecklist, err := models.HasChecklist(config.DB, reservation.ID, models.ChecklistStart)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo verificar la lista de condición"})
+			return
+		}
+		if !hasChecklist {
+			writeCommandError(c, models.ErrChecklistRequired, "No se pudo enviar el comando")
+			return
+		}
+	}
+
+	queueCommand(c, &models.VehicleCommand{
+		VehicleID:     reservation.VehicleID,
+		ReservationID: &reservation.ID,
+		RequestedBy:   &userID,
+		Command:       input.Command,
+	})
+}
+
+// CreateVehicleCommand permite al personal enviar cualquier comando, incluida la inmovilización
+func CreateVehicleCommand(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var input struct {
+		Command string `json:"command" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	userID, _ := currentUser(c)
+	queueCommand(c, &models.VehicleCommand{VehicleID: vehicleID, RequestedBy: &userID, Command: input.Command})
+}
+
+// GetVehicleCommand devuelve el estado de un comando a quien lo pidió o al personal
+func GetVehicleCommand(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	cmd, err := models.GetVehicleCommand(config.DB, id)
+	if err != nil {
+		writeModelError(c, err, "No se pudo obtener el comando")
+		return
+	}
+	userID, role := currentUser(c)
+	if !isStaff(role) && (cmd.RequestedBy == nil || *cmd.RequestedBy != userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recurso no encontrado"})
+		return
+	}
+	c.JSON(http.StatusOK, cmd)
+}
+
+// DeviceFetchCommands entrega a la unidad sus comandos pendientes. Con wait=<segundos> la petición
+// queda abierta hasta que llegue un comando o venza la espera (long-poll).
+func DeviceFetchCommands(c *gin.Context) {
+	vehicleID := deviceVehicleID(c)
+
+	var wait time.Duration
+	if raw := c.Query("wait"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "wait inválido"})
+			return
+		}
+		wait = time.Duration(seconds) * time.Second
+		if wait > maxCommandWait {
+			wait = maxCommandWait
+		}
+	}
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+
+	for {
+		// Suscribirse antes de consultar para no perder un aviso entre ambas operaciones
+		signal := realtime.Commands.Wait(vehicleID)
+		commands, err := models.ClaimVehicleCommands(config.DB, vehicleID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los comandos"})
+			return
+		}
+		if len(commands) > 0 || wait == 0 {
+			c.JSON(http.StatusOK, commands)
+			return
+		}
+		select {
+		case <-signal:
+		case <-deadline.C:
+			c.JSON(http.StatusOK, commands)
+			return
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// DeviceStreamCommands mantiene una conexión Server-Sent Events por la que la unidad recibe sus
+// comandos en cuanto se encolan
+func DeviceStreamCommands(c *gin.Context) {
+	vehicleID := deviceVehicleID(c)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.SSEvent("ready", gin.H{"heartbeat_seconds": int(streamHeartbeat.Seconds())})
+	c.Stream(func(w io.Writer) bool {
+		signal := realtime.Commands.Wait(vehicleID)
+		commands, err := models.ClaimVehicleCommands(config.DB, vehicleID)
+		if err != nil {
+			c.SSEvent("error", gin.H{"error": "No se pudieron obtener los comandos"})
+			return false
+		}
+		for _, cmd := range commands {
+			c.SSEvent("command", cmd)
+		}
+		if len(commands) > 0 {
+			return true
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-signal:
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("heartbeat", gin.H{"at": time.Now()})
+			return true
+		}
+	})
+}
+
+// DeviceAckCommand registra el resultado de un comando ejecutado por la unidad
+func DeviceAckCommand(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var input struct {
+		Status string  `json:"status" binding:"required"`
+		Result *string `json:"result"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	if input.Status != models.CommandSucceeded && input.Status != models.CommandFailed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status debe ser succeeded o failed"})
+		return
+	}
+
+	cmd, err := models.AckVehicleCommand(config.DB, deviceVehicleID(c), id, input.Status == models.CommandSucceeded, input.Result)
+	if errors.Is(err, models.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "El comando ya no admite confirmación", "command": cmd})
+		return
+	}
+	if err != nil {
+		writeModelError(c, err, "No se pudo registrar el resultado")
+		return
+	}
+	c.JSON(http.StatusOK, cmd)
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"fmt"
+	"go-auth-api/src/config"
+	"go-auth-api/src/events"
+	"go-auth-api/src/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubmitChecklist registra la lista de condición del vehículo al inicio (stage=start) o al final
+// (stage=end) del viaje; la envían el cliente de la reserva o el personal
+func SubmitChecklist(c *gin.Context) {
+	reservationID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	reservation, ok := reservationForUser(c, reservationID)
+	if !ok {
+		return
+	}
+	if reservation.Status != "activa" {
+		c.JSON(http.StatusConflict, gin.H{"error": "La reserva no está activa"})
+		return
+	}
+
+	var checklist models.ConditionChecklist
+	if err := c.ShouldBindJSON(&checklist); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	userID, _ := currentUser(c)
+	checklist.ReservationID = reservationID
+	checklist.SubmittedBy = userID
+
+	// La lista de inicio marca el comienzo del viaje
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar la lista de condición"})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := checklist.Create(tx); err != nil {
+		writeModelError(c, err, "No se pudo registrar la lista de condición")
+		return
+	}
+	if checklist.Stage == models.ChecklistStart {
+		if err := events.Publish(tx, userID, events.TripStarted{Reservation: reservation}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar la lista de condición"})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar la lista de condición"})
+		return
+	}
+	c.JSON(http.StatusCreated, checklist)
+}
+
+// CreateDamageReport registra un daño del vehículo de la reserva; las fotos se suben después
+func CreateDamageReport(c *gin.Context) {
+	reservationID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if _, ok := reservationForUser(c, reservationID); !ok {
+		return
+	}
+
+	var report models.DamageReport
+	if err := c.ShouldBindJSON(&report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	userID, _ := currentUser(c)
+	report.ReservationID = reservationID
+	report.ReportedBy = userID
+
+	if err := report.Create(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo registrar el reporte de daños")
+		return
+	}
+	c.JSON(http.StatusCreated, report)
+}
+
+// damageReportForUser carga el reporte si el usuario puede ver su reserva
+func damageReportForUser(c *gin.Context, reportID int) (models.DamageReport, bool) {
+	report, err := models.GetDamageReport(config.DB, reportID)
+	if err != nil {
+		writeModelError(c, err, "No se pudo obtener el reporte de daños")
+		return report, false
+	}
+	if _, ok := reservationForUser(c, report.ReservationID); !ok {
+		return report, false
+	}
+	return report, true
+}
+
+// GetDamageReport devuelve un reporte de daños con sus fotos
+func GetDamageReport(c *gin.Context) {
+	reportID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	report, ok := damageReportForUser(c, reportID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// UploadDamagePhoto añade una foto (campo multipart "image") a un reporte abierto
+func UploadDamagePhoto(c *gin.Context) {
+	reportID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	report, ok := damageReportForUser(c, reportID)
+	if !ok {
+		return
+	}
+	if report.Status != models.DamageOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": models.ErrReportNotOpen.Error()})
+		return
+	}
+
+	stored, ok := receiveImage(c, "image", fmt.Sprintf("damage/%d", reportID))
+	if !ok {
+		return
+	}
+
+	photo := models.DamagePhoto{
+		ReportID:     reportID,
+		ImageURL:     stored.URL,
+		ThumbnailURL: &stored.ThumbnailURL,
+		StorageKey:   &stored.Key,
+		ThumbnailKey: &stored.ThumbnailKey,
+		ContentType:  &stored.ContentType,
+	}
+	if err := models.AddDamagePhoto(config.DB, &photo); err != nil {
+		deleteBlobs(stored.Key, stored.ThumbnailKey)
+		writeModelError(c, err, "No se pudo registrar la foto")
+		return
+	}
+	c.JSON(http.StatusCreated, photo)
+}
+
+// ListDamageReports lista los reportes de daños, filtrables por status (open, charged, dismissed)
+func ListDamageReports(c *gin.Context) {
+	reports, err := models.GetDamageReports(config.DB, nil, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los reportes de daños"})
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// ChargeDamageReport convierte un reporte abierto en un cargo pendiente sobre la reserva
+func ChargeDamageReport(c *gin.Context) {
+	reportID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var input struct {
+		Amount      float64 `json:"amount" binding:"required"`
+		Description *string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	staffID, _ := currentUser(c)
+	payment, err := models.ChargeDamageReport(config.DB, reportID, staffID, input.Amount, input.Description)
+	if err != nil {
+		writeModelError(c, err, "No se pudo registrar el cargo")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Cargo registrado", "payment": payment})
+}
+
+// DismissDamageReport cierra un reporte abierto sin cargo
+func DismissDamageReport(c *gin.Context) {
+	reportID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	staffID, _ := currentUser(c)
+	if err := models.DismissDamageReport(config.DB, reportID, staffID); err != nil {
+		writeModelError(c, err, "No se pudo descartar el reporte")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Reporte descartado"})
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListDeliveries devuelve los envíos del outbox de notificaciones, filtrables por status (pending,
+// sending, sent, dead), channel (email, sms, push) y notification_id, junto con los totales por
+// canal y estado
+func ListDeliveries(c *gin.Context) {
+	filter := models.DeliveryFilter{Status: c.Query("status"), Channel: c.Query("channel"), Limit: 50}
+	if filter.Channel != "" && !models.ValidChannel(filter.Channel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Canal inválido", "allowed": models.Channels})
+		return
+	}
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit debe estar entre 1 y 500"})
+			return
+		}
+		filter.Limit = n
+	}
+	if raw := c.Query("notification_id"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "notification_id inválido"})
+			return
+		}
+		filter.NotificationID = n
+	}
+
+	deliveries, err := models.GetDeliveries(config.DB, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener el outbox de notificaciones"})
+		return
+	}
+	stats, err := models.DeliveryStats(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener el outbox de notificaciones"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stats": stats, "deliveries": deliveries})
+}
+
+// RetryDelivery vuelve a encolar un envío en estado dead
+func RetryDelivery(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := models.RetryDelivery(config.DB, int64(id)); err != nil {
+		writeModelError(c, err, "No se pudo reintentar el envío")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Envío encolado de nuevo"})
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListDevices lista las unidades telemáticas, filtrables por vehicle_id
+func ListDevices(c *gin.Context) {
+	var vehicleID *int
+	if raw := c.Query("vehicle_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "vehicle_id inválido"})
+			return
+		}
+		vehicleID = &id
+	}
+
+	devices, err := models.GetDevices(config.DB, vehicleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los dispositivos"})
+		return
+	}
+	c.JSON(http.StatusOK, devices)
+}
+
+// deviceCredentialResponse incluye el secreto solo en la respuesta de emisión o rotación
+func deviceCredentialResponse(device models.Device) gin.H {
+	response := gin.H{"device": device}
+	if device.Secret != "" {
+		response["secret"] = device.Secret
+		response["message"] = "Guarde la clave ahora, no se volverá a mostrar"
+	}
+	return response
+}
+
+// IssueDevice registra una unidad para un vehículo y emite su credencial
+func IssueDevice(c *gin.Context) {
+	var input struct {
+		VehicleID       int    `json:"vehicle_id" binding:"required"`
+		Name            string `json:"name"`
+		AuthType        string `json:"auth_type" binding:"required"`
+		CertFingerprint string `json:"cert_fingerprint"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	device, err := models.IssueDevice(config.DB, input.VehicleID, input.Name, input.AuthType, input.CertFingerprint)
+	if err != nil {
+		writeModelError(c, err, "No se pudo registrar el dispositivo")
+		return
+	}
+	c.JSON(http.StatusCreated, deviceCredentialResponse(device))
+}
+
+// RotateDeviceCredential genera una clave nueva (o registra un certificado nuevo) para la unidad
+func RotateDeviceCredential(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		CertFingerprint string `json:"cert_fingerprint"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+			return
+		}
+	}
+
+	device, err := models.RotateDeviceCredential(config.DB, id, input.CertFingerprint)
+	if err != nil {
+		writeModelError(c, err, "No se pudo rotar la credencial")
+		return
+	}
+	c.JSON(http.StatusOK, deviceCredentialResponse(device))
+}
+
+// RevokeDevice revoca la credencial de una unidad
+func RevokeDevice(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := models.RevokeDevice(config.DB, id); err != nil {
+		writeModelError(c, err, "No se pudo revocar el dispositivo")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Dispositivo revocado"})
+}
+
+// deviceVehicleID devuelve el vehículo al que está vinculado el dispositivo autenticado
+func deviceVehicleID(c *gin.Context) int {
+	return c.GetInt("device_vehicle_id")
+}
+
+// DeviceIngestTelemetry recibe la telemetría del vehículo de la unidad autenticada
+func DeviceIngestTelemetry(c *gin.Context) {
+	ingestTelemetry(c, deviceVehicleID(c))
+}
+
+// DeviceUpdateLocation actualiza la ubicación del vehículo de la unidad autenticada
+func DeviceUpdateLocation(c *gin.Context) {
+	updateVehicleLocation(c, deviceVehicleID(c))
+}
+
+// DeviceUpdateStatus actualiza el estado del vehículo de la unidad autenticada
+func DeviceUpdateStatus(c *gin.Context) {
+	updateVehicleStatus(c, deviceVehicleID(c))
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListDomainEvents devuelve los últimos eventos de dominio con el progreso de cada suscriptor,
+// filtrables por type y por el status de algún suscriptor (pending, done, dead)
+func ListDomainEvents(c *gin.Context) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit debe estar entre 1 y 500"})
+			return
+		}
+		limit = n
+	}
+
+	events, err := models.GetDomainEvents(config.DB, c.Query("type"), c.Query("status"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los eventos"})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// RetryEventHandler vuelve a entregar un evento a un suscriptor que agotó sus intentos
+func RetryEventHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := models.RetryEventHandler(config.DB, id, c.Param("subscriber")); err != nil {
+		writeModelError(c, err, "No se pudo reintentar el evento")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Evento encolado de nuevo"})
+}
+
+// ListAuditLog devuelve el registro de auditoría, del más reciente al más antiguo, filtrable por
+// entity (reservation, payment, vehicle), entity_id y user_id; se pagina con cursor
+func ListAuditLog(c *gin.Context) {
+	filter := models.AuditFilter{Entity: c.Query("entity"), Cursor: c.Query("cursor")}
+	ints := map[string]*int{"limit": &filter.Limit, "user_id": &filter.UserID}
+	for name, dst := range ints {
+		if raw := c.Query(name); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " inválido"})
+				return
+			}
+			*dst = n
+		}
+	}
+	if raw := c.Query("entity_id"); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "entity_id inválido"})
+			return
+		}
+		filter.EntityID = n
+	}
+
+	page, err := models.GetAuditLog(config.DB, filter)
+	if err != nil {
+		writeModelError(c, err, "No se pudo obtener el registro de auditoría")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListFavorites devuelve los vehículos favoritos del usuario autenticado
+func ListFavorites(c *gin.Context) {
+	userID, _ := currentUser(c)
+	favorites, err := models.GetFavorites(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los favoritos"})
+		return
+	}
+	c.JSON(http.StatusOK, favorites)
+}
+
+// SaveFavorite añade un vehículo a los favoritos del usuario o cambia su aviso de disponibilidad.
+// El cuerpo es opcional: {"notify_when_available": true, "latitude": .., "longitude": .., "radius_km": ..}
+func SaveFavorite(c *gin.Context) {
+	vehicleID, ok := paramID(c, "vehicle_id")
+	if !ok {
+		return
+	}
+	var settings models.FavoriteSettings
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&settings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+			return
+		}
+	}
+
+	userID, _ := currentUser(c)
+	if err := models.SaveFavorite(config.DB, userID, vehicleID, settings); err != nil {
+		writeModelError(c, err, "No se pudo guardar el favorito")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Favorito guardado"})
+}
+
+// RemoveFavorite quita un vehículo de los favoritos del usuario
+func RemoveFavorite(c *gin.Context) {
+	vehicleID, ok := paramID(c, "vehicle_id")
+	if !ok {
+		return
+	}
+
+	userID, _ := currentUser(c)
+	if err := models.RemoveFavorite(config.DB, userID, vehicleID); err != nil {
+		writeModelError(c, err, "No se pudo quitar el favorito")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Favorito eliminado"})
+}
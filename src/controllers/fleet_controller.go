@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"go-auth-api/src/config"
+	"go-auth-api/src/events"
+	"go-auth-api/src/models"
+	"go-auth-api/src/realtime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateVehicle registra un vehículo nuevo con su tarifa
+func CreateVehicle(c *gin.Context) {
+	var input models.VehicleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	id, err := models.CreateVehicle(config.DB, input)
+	if err != nil {
+		writeModelError(c, err, "No se pudo crear el vehículo")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Vehículo creado correctamente", "id": id})
+}
+
+// UpdateVehicle actualiza los datos y la tarifa de un vehículo
+func UpdateVehicle(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input models.VehicleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	if err := models.UpdateVehicle(config.DB, id, input); err != nil {
+		writeModelError(c, err, "No se pudo actualizar el vehículo")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vehículo actualizado correctamente"})
+}
+
+// SetVehiclePricing fija la tarifa de un vehículo
+func SetVehiclePricing(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var pricing models.VehiclePricing
+	if err := c.ShouldBindJSON(&pricing); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	if err := models.SetVehiclePricing(config.DB, id, pricing); err != nil {
+		writeModelError(c, err, "No se pudo actualizar la tarifa")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tarifa actualizada correctamente"})
+}
+
+// RetireVehicle retira un vehículo del servicio
+func RetireVehicle(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo retirar el vehículo"})
+		return
+	}
+	defer tx.Rollback()
+
+	vehicle := models.Vehicle{ID: id}
+	previous, err := vehicle.Retire(tx)
+	if err != nil {
+		writeModelError(c, err, "No se pudo retirar el vehículo")
+		return
+	}
+	actorID, _ := currentUser(c)
+	changed := events.VehicleStatusChanged{VehicleID: id, From: previous, To: models.VehicleStatusRetired,
+		Latitude: vehicle.Latitude, Longitude: vehicle.Longitude}
+	if err := events.Publish(tx, actorID, changed); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo retirar el vehículo"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo retirar el vehículo"})
+		return
+	}
+	realtime.PublishStatus(id, models.VehicleStatusRetired, vehicle.Latitude, vehicle.Longitude)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vehículo retirado del servicio"})
+}
+
+// DeleteVehicle elimina un vehículo retirado sin reservas futuras
+func DeleteVehicle(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	keys, err := models.DeleteVehicle(config.DB, id)
+	if err != nil {
+		writeModelError(c, err, "No se pudo eliminar el vehículo")
+		return
+	}
+	// Los objetos se borran solo cuando las filas ya no existen, como en DeleteVehicleImage
+	deleteBlobs(keys...)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vehículo eliminado correctamente"})
+}
+
+// ListBrands devuelve el catálogo de marcas
+func ListBrands(c *gin.Context) {
+	brands, err := models.GetBrands(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las marcas"})
+		return
+	}
+	c.JSON(http.StatusOK, brands)
+}
+
+func CreateBrand(c *gin.Context) {
+	var brand models.Brand
+	if err := c.ShouldBindJSON(&brand); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	if err := brand.Create(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo crear la marca")
+		return
+	}
+	c.JSON(http.StatusCreated, brand)
+}
+
+func UpdateBrand(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var brand models.Brand
+	if err := c.ShouldBindJSON(&brand); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	brand.ID = id
+	if err := brand.Update(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo actualizar la marca")
+		return
+	}
+	c.JSON(http.StatusOK, brand)
+}
+
+func DeleteBrand(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if err := models.DeleteBrand(config.DB, id); err != nil {
+		writeModelError(c, err, "No se pudo eliminar la marca")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Marca eliminada correctamente"})
+}
+
+// ListCarModels devuelve el catálogo de modelos, filtrable por brand_id
+func ListCarModels(c *gin.Context) {
+	var brandID *int
+	if raw := c.Query("brand_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "brand_id inválido"})
+			return
+		}
+		brandID = &id
+	}
+
+	carModels, err := models.GetCarModels(config.DB, brandID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los modelos"})
+		return
+	}
+	c.JSON(http.StatusOK, carModels)
+}
+
+func CreateCarModel(c *gin.Context) {
+	var carModel models.CarModel
+	if err := c.ShouldBindJSON(&carModel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	if err := carModel.Create(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo crear el modelo")
+		return
+	}
+	c.JSON(http.StatusCreated, carModel)
+}
+
+func UpdateCarModel(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var carModel models.CarModel
+	if err := c.ShouldBindJSON(&carModel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	carModel.ID = id
+	if err := carModel.Update(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo actualizar el modelo")
+		return
+	}
+	c.JSON(http.StatusOK, carModel)
+}
+
+func DeleteCarModel(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if err := models.DeleteCarModel(config.DB, id); err != nil {
+		writeModelError(c, err, "No se pudo eliminar el modelo")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Modelo eliminado correctamente"})
+}
+
+// ListFuelTypes devuelve el catálogo de tipos de combustible
+func ListFuelTypes(c *gin.Context) {
+	fuelTypes, err := models.GetFuelTypes(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los tipos de combustible"})
+		return
+	}
+	c.JSON(http.StatusOK, fuelTypes)
+}
+
+func CreateFuelType(c *gin.Context) {
+	var fuelType models.FuelTypeRecord
+	if err := c.ShouldBindJSON(&fuelType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	if err := fuelType.Create(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo crear el tipo de combustible")
+		return
+	}
+	c.JSON(http.StatusCreated, fuelType)
+}
+
+func UpdateFuelType(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var fuelType models.FuelTypeRecord
+	if err := c.ShouldBindJSON(&fuelType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	fuelType.ID = id
+	if err := fuelType.Update(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo actualizar el tipo de combustible")
+		return
+	}
+	c.JSON(http.StatusOK, fuelType)
+}
+
+func DeleteFuelType(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if err := models.DeleteFuelType(config.DB, id); err != nil {
+		writeModelError(c, err, "No se pudo eliminar el tipo de combustible")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tipo de combustible eliminado correctamente"})
+}
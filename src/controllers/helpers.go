@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// paramID lee un parámetro de ruta numérico; si no es válido responde 400 y devuelve false
+func paramID(c *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return 0, false
+	}
+	return id, true
+}
+
+// writeModelError traduce los errores de la capa de modelos al código HTTP correspondiente
+func writeModelError(c *gin.Context, err error, message string) {
+	var validationErr *models.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Msg})
+	case errors.Is(err, models.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recurso no encontrado"})
+	case errors.Is(err, models.ErrConflict),
+		errors.Is(err, models.ErrDuplicatePlate),
+		errors.Is(err, models.ErrVehicleNotRetired),
+		errors.Is(err, models.ErrVehicleHasFutureReservations),
+		errors.Is(err, models.ErrDowntimeOverlapsReservation),
+		errors.Is(err, models.ErrChecklistRequired),
+		errors.Is(err, models.ErrReportNotOpen),
+		errors.Is(err, models.ErrReviewNotAllowed),
+		errors.Is(err, models.ErrVehicleUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// currentUser devuelve el id y el rol del usuario autenticado por AuthMiddleware
+func currentUser(c *gin.Context) (int, string) {
+	return c.GetInt("user_id"), c.GetString("role")
+}
+
+// reservationForUser carga la reserva si pertenece al usuario autenticado o si éste es personal;
+// en otro caso responde 404 (sin revelar que existe) y devuelve false
+func reservationForUser(c *gin.Context, reservationID int) (models.Reservation, bool) {
+	var reservation models.Reservation
+	if err := reservation.GetByID(config.DB, reservationID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reserva no encontrada"})
+			return reservation, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener la reserva"})
+		return reservation, false
+	}
+	userID, role := currentUser(c)
+	if reservation.UserID != userID && !isStaff(role) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reserva no encontrada"})
+		return reservation, false
+	}
+	return reservation, true
+}
+
+// isStaff indica si el rol corresponde a personal de operaciones
+func isStaff(role string) bool {
+	return role == models.RoleOperator || role == models.RoleAdmin
+}
+
+// queryTimeRange lee los parámetros from/to (RFC3339); si faltan usa las últimas defaultSpan horas
+func queryTimeRange(c *gin.Context, defaultSpan time.Duration) (time.Time, time.Time, bool) {
+	to := time.Now()
+	from := to.Add(-defaultSpan)
+	var err error
+	if raw := c.Query("to"); raw != "" {
+		if to, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro to inválido, use RFC3339"})
+			return from, to, false
+		}
+		if c.Query("from") == "" {
+			from = to.Add(-defaultSpan)
+		}
+	}
+	if raw := c.Query("from"); raw != "" {
+		if from, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro from inválido, use RFC3339"})
+			return from, to, false
+		}
+	}
+	return from, to, true
+}
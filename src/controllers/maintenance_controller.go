@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"go-auth-api/src/config"
+	"go-auth-api/src/jobs"
+	"go-auth-api/src/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListMaintenanceRecords devuelve el historial de mantenimiento de un vehículo
+func ListMaintenanceRecords(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	records, err := models.GetMaintenanceRecords(config.DB, vehicleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener el historial de mantenimiento"})
+		return
+	}
+	c.JSON(http.StatusOK, records)
+}
+
+// CreateMaintenanceRecord registra un servicio realizado y reinicia el plan del mismo tipo
+func CreateMaintenanceRecord(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var record models.MaintenanceRecord
+	if err := c.ShouldBindJSON(&record); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	userID, _ := currentUser(c)
+	record.VehicleID = vehicleID
+	record.CreatedBy = &userID
+
+	if err := record.Create(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo registrar el mantenimiento")
+		return
+	}
+	c.JSON(http.StatusCreated, record)
+}
+
+// ListDowntime devuelve las ventanas de mantenimiento vigentes y futuras de un vehículo
+func ListDowntime(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	windows, err := models.GetDowntime(config.DB, vehicleID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las ventanas de mantenimiento"})
+		return
+	}
+	c.JSON(http.StatusOK, windows)
+}
+
+// CreateDowntime bloquea la disponibilidad del vehículo durante una ventana de mantenimiento
+func CreateDowntime(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var downtime models.Downtime
+	if err := c.ShouldBindJSON(&downtime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	userID, _ := currentUser(c)
+	downtime.VehicleID = vehicleID
+	downtime.CreatedBy = &userID
+
+	if err := downtime.Create(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo crear la ventana de mantenimiento")
+		return
+	}
+	c.JSON(http.StatusCreated, downtime)
+}
+
+// DeleteDowntime libera una ventana de mantenimiento
+func DeleteDowntime(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteDowntime(config.DB, id); err != nil {
+		writeModelError(c, err, "No se pudo eliminar la ventana de mantenimiento")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Ventana de mantenimiento eliminada"})
+}
+
+// ListMaintenanceSchedules devuelve los planes de mantenimiento de un vehículo
+func ListMaintenanceSchedules(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	schedules, err := models.GetMaintenanceSchedules(config.DB, vehicleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los planes de mantenimiento"})
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+// CreateMaintenanceSchedule define un recordatorio por tiempo y/o kilometraje
+func CreateMaintenanceSchedule(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var schedule models.MaintenanceSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	schedule.VehicleID = vehicleID
+
+	if err := schedule.Create(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo crear el plan de mantenimiento")
+		return
+	}
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// UpdateMaintenanceSchedule cambia los intervalos de un plan o lo desactiva
+func UpdateMaintenanceSchedule(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	schedule := models.MaintenanceSchedule{Active: true}
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	schedule.ID = id
+
+	if err := schedule.Update(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo actualizar el plan de mantenimiento")
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteMaintenanceSchedule elimina un plan de mantenimiento
+func DeleteMaintenanceSchedule(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteMaintenanceSchedule(config.DB, id); err != nil {
+		writeModelError(c, err, "No se pudo eliminar el plan de mantenimiento")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Plan de mantenimiento eliminado"})
+}
+
+// ListDueMaintenance devuelve los planes que alcanzaron su umbral, ya recordados o no
+func ListDueMaintenance(c *gin.Context) {
+	due, err := models.GetDueMaintenance(config.DB, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los mantenimientos pendientes"})
+		return
+	}
+	c.JSON(http.StatusOK, due)
+}
+
+// RunMaintenanceReminders envía en el momento los recordatorios pendientes, sin esperar a la revisión periódica
+func RunMaintenanceReminders(c *gin.Context) {
+	sent, err := jobs.RunMaintenanceReminders(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron enviar los recordatorios"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reminded": sent})
+}
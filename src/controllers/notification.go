@@ -3,64 +3,74 @@ package controllers
 import (
 	"go-auth-api/src/config"
 	"go-auth-api/src/models"
-	"go-auth-api/src/utils"
+	"go-auth-api/src/templates"
 	"log"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Función para enviar notificaciones
-func sendNotification(c *gin.Context, notification models.Notification, subject string, body string) {
-	// Registrar notificación en la base de datos
-	if err := notification.Send(config.DB); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar la notificación: " + err.Error()})
-		return
+// queueNotification registra las notificaciones y sus envíos en una transacción; la entrega por cada
+// canal la hace el worker de notificaciones en segundo plano
+func queueNotification(notifications ...models.Notification) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	// Canal para comunicar el resultado del envío del correo
-	emailResult := make(chan error)
+	for _, notification := range notifications {
+		if err := notification.Send(tx); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
 
-	// Enviar correo en segundo plano usando goroutine
-	go func() {
-		recipientEmail := "recipient@example.com" // Obtén el correo del usuario desde el JSON o la base de datos
-		err := utils.SendEmail(recipientEmail, subject, body)
-		emailResult <- err // Enviar el resultado (nil si es exitoso, error si falla)
-	}()
+// returnReminder es el recordatorio de devolución para el usuario de la notificación recibida
+func returnReminder(notification models.Notification) models.Notification {
+	return models.Notification{
+		UserID:   notification.UserID,
+		Template: templates.ReturnReminder,
+		Data:     templates.ReminderData{},
+	}
+}
 
-	// Esperar el resultado del envío de correo por un tiempo limitado
-	select {
-	case err := <-emailResult:
-		if err != nil {
-			// Si hay un error en el envío del correo, lo informamos
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al enviar la notificación por correo: " + err.Error()})
-		} else {
-			// Si el correo fue exitoso
-			c.JSON(http.StatusOK, gin.H{"message": "Notificación enviada exitosamente y correo enviado"})
-		}
-	case <-time.After(5 * time.Second): // Si el envío tarda más de 5 segundos, se considera un timeout
-		log.Println("Advertencia: el envío del correo está tardando demasiado")
-		c.JSON(http.StatusOK, gin.H{"message": "Notificación enviada exitosamente, pero el correo está tardando más de lo esperado"})
+// freeFormType valida el type de una notificación escrita a mano y responde 400 si no sirve. Los tipos
+// transaccionales (reserva, recibo, seguridad) saltan las horas de silencio y la baja del usuario, así
+// que solo los emite el propio sistema.
+func freeFormType(c *gin.Context, notificationType string) bool {
+	if notificationType != "" && !models.ValidNotificationType(notificationType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipo de notificación inválido", "allowed": models.NotificationTypes})
+		return false
 	}
+	if models.IsTransactional(notificationType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Los avisos de tipo " + notificationType + " solo los envía el sistema"})
+		return false
+	}
+	return true
 }
 
-// Enviar notificación
+// Enviar notificación; el mensaje se envía con la plantilla genérica, escapado en el HTML del correo.
+// type (por defecto message) decide qué preferencias del usuario se aplican, p. ej. marketing.
 func SendNotification(c *gin.Context) {
 	var notification models.Notification
 	if err := c.ShouldBindJSON(&notification); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
 		return
 	}
+	if !freeFormType(c, notification.Type) {
+		return
+	}
 
 	log.Println("Notificación recibida:", notification)
 
-	// Crear el cuerpo del correo
-	body := "<p>Tienes una nueva notificación:<br> " + notification.Message + "</p>"
-	subject := "Nueva notificación"
-
-	// Llamar a la función de envío de notificaciones
-	sendNotification(c, notification, subject, body)
+	if err := queueNotification(notification); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar la notificación: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notificación registrada; se enviará en breve"})
 }
 
 // Enviar recordatorio de notificación
@@ -73,47 +83,106 @@ func SendNotificationReminder(c *gin.Context) {
 
 	log.Println("Recordatorio de notificación recibido:", notification)
 
-	// Crear el cuerpo del correo
-	body := "<p>Recuerda devolver el vehículo a tiempo para evitar cargos adicionales.</p>"
-	subject := "Recordatorio de Devolución"
-
-	sendNotification(c, notification, subject, body)
+	if err := queueNotification(returnReminder(notification)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar la notificación: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Recordatorio registrado; se enviará en breve"})
 }
 
-// Enviar ambas notificaciones
+// Enviar ambas notificaciones: el mensaje recibido y el recordatorio de devolución
 func SendBothNotifications(c *gin.Context) {
 	var notification models.Notification
 	if err := c.ShouldBindJSON(&notification); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos: " + err.Error()})
 		return
 	}
+	if !freeFormType(c, notification.Type) {
+		return
+	}
 
 	log.Println("Notificación recibida:", notification)
 
-	// Enviar la primera notificación
-	body1 := "<p>Tienes una nueva notificación:<br> " + notification.Message + "</p>"
-	subject1 := "Nueva notificación"
-	sendNotification(c, notification, subject1, body1)
+	if err := queueNotification(notification, returnReminder(notification)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar las notificaciones: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notificaciones registradas; se enviarán en breve"})
+}
+
+// ListMyNotifications devuelve la bandeja del usuario autenticado, de la más reciente a la más
+// antigua, paginada con cursor; con unread=true solo las no leídas
+func ListMyNotifications(c *gin.Context) {
+	limit, cursor, ok := queryPage(c)
+	if !ok {
+		return
+	}
+	unreadOnly := false
+	if raw := c.Query("unread"); raw != "" {
+		var err error
+		if unreadOnly, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unread inválido"})
+			return
+		}
+	}
+
+	userID, _ := currentUser(c)
+	page, err := models.GetInbox(config.DB, userID, unreadOnly, limit, cursor)
+	if err != nil {
+		writeModelError(c, err, "No se pudieron obtener las notificaciones")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
 
-	// Enviar la segunda notificación (recordatorio)
-	body2 := "<p>Recuerda devolver el vehículo a tiempo para evitar cargos adicionales.</p>"
-	subject2 := "Recordatorio de Devolución"
-	sendNotification(c, notification, subject2, body2)
+// CountMyUnreadNotifications devuelve cuántas notificaciones no leídas tiene el usuario autenticado
+func CountMyUnreadNotifications(c *gin.Context) {
+	userID, _ := currentUser(c)
+	count, err := models.CountUnreadNotifications(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron contar las notificaciones"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread_count": count})
 }
 
-// GetUserNotifications recupera las notificaciones de un usuario
-func GetUserNotifications(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
+// MarkNotificationRead marca como leída una notificación del usuario autenticado
+func MarkNotificationRead(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, _ := currentUser(c)
+	if err := models.MarkNotificationRead(config.DB, userID, id); err != nil {
+		writeModelError(c, err, "No se pudo marcar la notificación")
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notificación marcada como leída"})
+}
 
-	notifications, err := models.GetNotificationsByUserID(config.DB, userID.(int))
+// MarkAllNotificationsRead marca como leídas todas las notificaciones del usuario autenticado
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID, _ := currentUser(c)
+	updated, err := models.MarkAllNotificationsRead(config.DB, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las notificaciones"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron marcar las notificaciones"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notificaciones marcadas como leídas", "updated": updated})
+}
+
+// DeleteNotification quita una notificación de la bandeja del usuario autenticado
+func DeleteNotification(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, notifications)
+	userID, _ := currentUser(c)
+	if err := models.DeleteNotification(config.DB, userID, id); err != nil {
+		writeModelError(c, err, "No se pudo eliminar la notificación")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notificación eliminada"})
 }
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChannelPreferences devuelve por qué canales quiere recibir notificaciones el usuario
+// autenticado, junto con sus dispositivos registrados para push
+func GetChannelPreferences(c *gin.Context) {
+	userID, _ := currentUser(c)
+	prefs, err := models.GetChannelPreferences(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las preferencias"})
+		return
+	}
+	tokens, err := models.GetPushTokens(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las preferencias"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"channels": prefs, "push_tokens": tokens})
+}
+
+// UpdateChannelPreferences activa o desactiva canales, p. ej. {"sms": true, "push": false}
+func UpdateChannelPreferences(c *gin.Context) {
+	var prefs models.ChannelPreferences
+	if err := c.ShouldBindJSON(&prefs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	userID, _ := currentUser(c)
+	if err := models.SetChannelPreferences(config.DB, userID, prefs); err != nil {
+		writeModelError(c, err, "No se pudieron guardar las preferencias")
+		return
+	}
+	current, err := models.GetChannelPreferences(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las preferencias"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"channels": current})
+}
+
+// RegisterPushToken registra el dispositivo del usuario para recibir notificaciones push
+func RegisterPushToken(c *gin.Context) {
+	var input struct {
+		Token    string `json:"token" binding:"required"`
+		Platform string `json:"platform" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	userID, _ := currentUser(c)
+	token := models.PushToken{UserID: userID, Token: input.Token, Platform: input.Platform}
+	if err := token.Register(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo registrar el dispositivo")
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
+
+// RemovePushToken deja de enviar notificaciones push al dispositivo
+func RemovePushToken(c *gin.Context) {
+	userID, _ := currentUser(c)
+	if err := models.RemovePushToken(config.DB, userID, c.Param("token")); err != nil {
+		writeModelError(c, err, "No se pudo eliminar el dispositivo")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Dispositivo eliminado"})
+}
+
+// UpdateLanguage cambia el idioma en que el usuario recibe las notificaciones
+func UpdateLanguage(c *gin.Context) {
+	var input struct {
+		Language string `json:"language" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	userID, _ := currentUser(c)
+	if err := models.SetUserLanguage(config.DB, userID, input.Language); err != nil {
+		writeModelError(c, err, "No se pudo guardar el idioma")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"language": input.Language})
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotificationPreferences devuelve las preferencias por tipo de notificación del usuario
+// autenticado, su horario de silencio y si acepta mensajes promocionales, junto con los canales
+// activos que se aplican cuando un tipo no tiene preferencia propia
+func GetNotificationPreferences(c *gin.Context) {
+	userID, _ := currentUser(c)
+	settings, err := models.GetNotificationSettings(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las preferencias"})
+		return
+	}
+	channels, err := models.GetChannelPreferences(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las preferencias"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"preferences": settings, "channels": channels, "types": models.NotificationTypes})
+}
+
+// UpdateNotificationPreferences reemplaza las preferencias, p. ej. {"timezone": "America/La_Paz",
+// "quiet_start": "22:00", "quiet_end": "07:00", "marketing_opt_in": false,
+// "types": {"reminder": {"sms": true}, "favorite": {"email": false}}}. Los tipos omitidos vuelven a
+// seguir los canales generales.
+func UpdateNotificationPreferences(c *gin.Context) {
+	var settings models.NotificationSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	if settings.Timezone == "" {
+		settings.Timezone = "UTC"
+	}
+
+	userID, _ := currentUser(c)
+	if err := models.SaveNotificationSettings(config.DB, userID, settings); err != nil {
+		writeModelError(c, err, "No se pudieron guardar las preferencias")
+		return
+	}
+	current, err := models.GetNotificationSettings(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las preferencias"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"preferences": current})
+}
+
+// unsubscribePage es la página pública del enlace del pie de los correos. GET solo muestra el
+// botón, para que los antivirus que abren los enlaces no cancelen la suscripción; el botón y los
+// clientes de correo compatibles con el envío en un clic (RFC 8058) hacen el POST.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="es">
+<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>Cancelar suscripción</title></head>
+<body style="font-family: Arial, sans-serif; color: #333; line-height: 1.5; max-width: 480px; margin: 40px auto;">
+{{if .Done}}<p>Listo. Ya no recibirá por correo los mensajes de tipo «{{.Type}}».</p>
+<p>Puede volver a activarlos desde las preferencias de notificación de la aplicación.</p>
+{{else}}<p>¿Desea dejar de recibir por correo los mensajes de tipo «{{.Type}}»?</p>
+<form method="POST" action="/unsubscribe?token={{.Token}}"><button type="submit">Cancelar suscripción</button></form>
+{{end}}</body>
+</html>`))
+
+// ShowUnsubscribe muestra la confirmación para dejar de recibir un tipo de correo
+func ShowUnsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	_, notificationType, err := models.ParseUnsubscribeToken(token)
+	if err != nil {
+		c.String(http.StatusBadRequest, "El enlace no es válido.")
+		return
+	}
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	unsubscribePage.Execute(c.Writer, gin.H{"Type": notificationType, "Token": token})
+}
+
+// Unsubscribe deja de enviar por correo el tipo de notificación del enlace. Responde HTML al
+// formulario de la página y JSON al resto de clientes.
+func Unsubscribe(c *gin.Context) {
+	userID, notificationType, err := models.ParseUnsubscribeToken(c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El enlace no es válido"})
+		return
+	}
+	if err := models.UnsubscribeEmail(config.DB, userID, notificationType); err != nil {
+		writeModelError(c, err, "No se pudo cancelar la suscripción")
+		return
+	}
+
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEHTML {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		unsubscribePage.Execute(c.Writer, gin.H{"Type": notificationType, "Done": true})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Suscripción cancelada", "type": notificationType})
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"go-auth-api/src/templates"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListNotificationTemplates devuelve las plantillas de notificación y los idiomas disponibles
+func ListNotificationTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"templates": templates.Names(), "languages": templates.Languages})
+}
+
+// PreviewNotificationTemplate genera una plantilla con datos de ejemplo, con las fechas en la zona
+// timezone (UTC por defecto). Con format=html o format=text responde el cuerpo del correo tal como lo
+// recibiría el usuario; por defecto devuelve todas las partes en JSON.
+func PreviewNotificationTemplate(c *gin.Context) {
+	name := c.Param("name")
+	data, ok := templates.Sample(name)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plantilla no encontrada", "templates": templates.Names()})
+		return
+	}
+	lang := c.DefaultQuery("lang", templates.DefaultLanguage)
+	if !templates.SupportedLanguage(lang) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Idioma no soportado", "languages": templates.Languages})
+		return
+	}
+
+	loc, err := time.LoadLocation(c.DefaultQuery("timezone", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timezone inválida, use un nombre IANA como America/La_Paz"})
+		return
+	}
+
+	content, err := templates.Render(name, lang, templates.View{Name: c.DefaultQuery("name", "Ana"), Data: data, Location: loc})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo generar la plantilla", "details": err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(content.HTML))
+	case "text":
+		c.String(http.StatusOK, "%s", content.Subject+"\n\n"+content.Text)
+	case "json":
+		c.JSON(http.StatusOK, gin.H{"template": name, "language": lang, "content": content})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format debe ser json, html o text"})
+	}
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListOrganizations devuelve los clientes corporativos con su número de miembros
+func ListOrganizations(c *gin.Context) {
+	organizations, err := models.GetOrganizations(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las organizaciones"})
+		return
+	}
+	c.JSON(http.StatusOK, organizations)
+}
+
+// CreateOrganization registra un cliente corporativo
+func CreateOrganization(c *gin.Context) {
+	var organization models.Organization
+	if err := c.ShouldBindJSON(&organization); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	if err := organization.Create(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo crear la organización")
+		return
+	}
+	c.JSON(http.StatusCreated, organization)
+}
+
+// SetOrganizationMember agrega un usuario a la organización o cambia su rol: member, o admin para
+// que gestione los webhooks
+func SetOrganizationMember(c *gin.Context) {
+	orgID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := paramID(c, "user_id")
+	if !ok {
+		return
+	}
+	var input struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	if input.Role == "" {
+		input.Role = models.OrganizationMember
+	}
+
+	if err := models.SetOrganizationMember(config.DB, orgID, userID, input.Role); err != nil {
+		writeModelError(c, err, "No se pudo guardar el miembro")
+		return
+	}
+	c.JSON(http.StatusOK, models.OrganizationMembership{OrganizationID: orgID, UserID: userID, Role: input.Role})
+}
+
+// RemoveOrganizationMember saca al usuario de la organización
+func RemoveOrganizationMember(c *gin.Context) {
+	orgID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := paramID(c, "user_id")
+	if !ok {
+		return
+	}
+	if err := models.RemoveOrganizationMember(config.DB, orgID, userID); err != nil {
+		writeModelError(c, err, "No se pudo quitar el miembro")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Miembro eliminado"})
+}
@@ -2,60 +2,50 @@ package controllers
 
 import (
 	"go-auth-api/src/config"
+	"go-auth-api/src/events"
 	"go-auth-api/src/models"
 	"net/http"
-	"sync"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ProcessReservationPayment registra un cobro ya recibido por el personal, en mostrador o en la
+// terminal, y genera su factura. No hay pasarela de pago: solo el personal confirma que el dinero
+// llegó, así que la ruta es exclusiva de operadores y administradores. El pago y su evento, del que
+// salen el recibo y los webhooks, se guardan en la misma transacción.
 func ProcessReservationPayment(c *gin.Context) {
 	var payment models.Payment
 	if err := c.ShouldBindJSON(&payment); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
 		return
 	}
+	reservation, ok := reservationForUser(c, payment.ReservationID)
+	if !ok {
+		return
+	}
 
-	// Definimos una WaitGroup para esperar a que ambos procesos terminen
-	var wg sync.WaitGroup
-	wg.Add(2) // Vamos a realizar dos tareas en paralelo
-
-	// Canal para capturar errores
-	errChan := make(chan error, 2)
-	// Canal para capturar la factura generada
-	invoiceChan := make(chan models.Invoice)
-
-	// Procesar el pago en paralelo
-	go func() {
-		defer wg.Done() // Marca la goroutine como terminada
-		if err := payment.ProcessPayment(config.DB); err != nil {
-			errChan <- err
-		}
-	}()
-
-	// Generar la factura en paralelo
-	go func() {
-		defer wg.Done()                            // Marca la goroutine como terminada
-		invoice := models.GenerateInvoice(payment) // Esto debería devolver un models.Invoice
-		invoiceChan <- invoice                     // Enviar el Invoice al canal
-	}()
-
-	// Esperamos a que ambos procesos terminen
-	wg.Wait()
-	close(errChan)
-	close(invoiceChan)
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar el pago"})
+		return
+	}
+	defer tx.Rollback()
 
-	// Comprobar si hubo algún error
-	for err := range errChan {
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar el pago o generar la factura"})
-			return
-		}
+	if err := payment.ProcessPayment(tx); err != nil {
+		writeModelError(c, err, "Error al procesar el pago")
+		return
 	}
+	invoice := models.GenerateInvoice(payment)
 
-	// Recuperar la factura generada
-	invoice := <-invoiceChan
+	userID, _ := currentUser(c)
+	if err := events.Publish(tx, userID, events.PaymentCaptured{Payment: payment, UserID: reservation.UserID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar el pago"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al procesar el pago"})
+		return
+	}
 
-	// Respuesta de éxito
-	c.JSON(http.StatusOK, gin.H{"message": "Pago procesado", "invoice": invoice})
+	c.JSON(http.StatusOK, gin.H{"message": "Pago procesado", "payment": payment, "invoice": invoice})
 }
@@ -1,10 +1,10 @@
 package controllers
 
 import (
+	"errors"
 	"go-auth-api/src/config"
+	"go-auth-api/src/events"
 	"go-auth-api/src/models"
-	"go-auth-api/src/utils"
-	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -34,66 +34,50 @@ func CreateReservation(c *gin.Context) {
 	// Verificar la disponibilidad del vehículo
 	isAvailable, err := reservation.IsVehicleAvailable(config.DB)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al verificar la disponibilidad del vehículo"})
+		writeModelError(c, err, "Error al verificar la disponibilidad del vehículo")
 		return
 	}
 	if !isAvailable {
 		c.JSON(http.StatusConflict, gin.H{"error": "El vehículo no está disponible en el rango de tiempo solicitado"})
 		return
 	}
-	// Create the reservation in the database
-	if err := reservation.Create(config.DB); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	var vehicle models.Vehicle
+	if err := vehicle.GetByID(config.DB, reservation.VehicleID); err != nil {
+		writeModelError(c, err, "No se pudo obtener el vehículo")
 		return
 	}
 
-	// Send reservation confirmation notification
-	notification := models.Notification{
-		UserID:  reservation.UserID,
-		Message: "Su reserva ha sido confirmada.",
-	}
-	if err := notification.Send(config.DB); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo enviar la notificación de confirmación"})
+	// La reserva y su evento se guardan juntos: la confirmación y los webhooks salen del outbox solo si
+	// la reserva se confirmó
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo crear la reserva"})
 		return
 	}
+	defer tx.Rollback()
 
-	// Canal para comunicar el resultado del envío del correo
-	emailResult := make(chan error)
+	// Create the reservation in the database
+	// Create bloquea el vehículo y vuelve a verificar: otra reserva pudo confirmarse mientras tanto
+	if err := reservation.Create(tx); err != nil {
+		writeModelError(c, err, "No se pudo crear la reserva")
+		return
+	}
+	created := events.ReservationCreated{
+		Reservation:  reservation,
+		Vehicle:      vehicle.Brand + " " + vehicle.Model,
+		LicensePlate: vehicle.LicensePlate,
+	}
+	if err := events.Publish(tx, reservation.UserID, created); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo crear la reserva"})
+		return
+	}
 
-	// Fetch the user's email
-	var userEmail string
-	queryUsuario := `SELECT email FROM users WHERE id = $1`
-	if err := config.DB.QueryRow(queryUsuario, reservation.UserID).Scan(&userEmail); err != nil {
-		log.Printf("No se pudo obtener el correo del usuario: %v", err)
-	} else {
-		// Crear el cuerpo del correo
-		emailBody := "Su reserva ha sido confirmada desde " +
-			reservation.StartTime.Format("2006-01-02 15:04:05") + " hasta " +
-			reservation.EndTime.Format("2006-01-02 15:04:05") + ". Detalles de la reserva:" +
-			"<p>Vehículo: XYZ</p>"
-
-		// Enviar el correo en segundo plano usando una goroutine
-		go func() {
-			subject := "Confirmación de reserva de vehículo"
-			err := utils.SendEmail(userEmail, subject, emailBody)
-			emailResult <- err // Enviar el resultado (nil si es exitoso, error si falla)
-		}()
-	}
-
-	// Esperar el resultado del envío de correo por un tiempo limitado
-	select {
-	case err := <-emailResult:
-		if err != nil {
-			// Si hay un error en el envío del correo, lo informamos
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Reserva creada, pero falló el envío del correo de confirmación: " + err.Error()})
-		} else {
-			// Si el correo fue exitoso
-			c.JSON(http.StatusOK, gin.H{"message": "Reserva creada exitosamente y correo enviado", "reservation": reservation})
-		}
-	case <-time.After(5 * time.Second): // Si el envío tarda más de 5 segundos, se considera un timeout
-		log.Println("Advertencia: el envío del correo está tardando demasiado")
-		c.JSON(http.StatusOK, gin.H{"message": "Reserva creada exitosamente, pero el correo está tardando más de lo esperado", "reservation": reservation})
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo crear la reserva"})
+		return
 	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reserva creada exitosamente", "reservation": reservation})
 }
 
 func GetReservation(c *gin.Context) {
@@ -104,17 +88,39 @@ func GetReservation(c *gin.Context) {
 		return
 	}
 
-	var reservation models.Reservation
-	if err := reservation.GetByID(config.DB, reservationID); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Reserva no encontrada"})
+	reservation, ok := reservationForUser(c, reservationID)
+	if !ok {
+		return
+	}
+
+	// El detalle incluye las listas de condición y los reportes de daños con sus fotos
+	checklists, err := models.GetChecklists(config.DB, reservationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las listas de condición"})
+		return
+	}
+	reports, err := models.GetDamageReports(config.DB, &reservationID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los reportes de daños"})
 		return
 	}
 
-	c.JSON(http.StatusOK, reservation)
+	c.JSON(http.StatusOK, struct {
+		models.Reservation
+		Checklists    []models.ConditionChecklist `json:"checklists"`
+		DamageReports []models.DamageReport       `json:"damage_reports"`
+	}{reservation, checklists, reports})
 }
+
+// GetAllReservation lista todas las reservas al personal y solo las propias a los clientes
 func GetAllReservation(c *gin.Context) {
+	userID, role := currentUser(c)
+	owner := &userID
+	if isStaff(role) {
+		owner = nil
+	}
 	var reservation models.Reservation
-	reservations, err := reservation.GetAll(config.DB)
+	reservations, err := reservation.GetAll(config.DB, owner)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las reservas"})
 		return
@@ -158,7 +164,7 @@ func CheckVehicleAvailability(c *gin.Context) {
 	// Verificar la disponibilidad del vehículo
 	isAvailable, err := reservation.IsVehicleAvailable(config.DB)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al verificar la disponibilidad del vehículo"})
+		writeModelError(c, err, "Error al verificar la disponibilidad del vehículo")
 		return
 	}
 
@@ -185,13 +191,108 @@ func UpdateReservation(c *gin.Context) {
 		return
 	}
 
-	var reservation models.Reservation
-	if err := c.ShouldBindJSON(&reservation); err != nil {
+	// Los campos omitidos conservan su valor actual
+	var input struct {
+		StartTime *time.Time `json:"start_time"`
+		EndTime   *time.Time `json:"end_time"`
+		Status    string     `json:"status"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
 		return
 	}
 
-	if err := reservation.Update(config.DB, reservationID); err != nil { // Pass reservationID as int
+	// Solo el titular o el personal pueden cambiar la reserva
+	current, ok := reservationForUser(c, reservationID)
+	if !ok {
+		return
+	}
+	reservation := current
+	if input.Status != "" {
+		reservation.Status = input.Status
+	}
+	if input.StartTime != nil {
+		reservation.StartTime = *input.StartTime
+	}
+	if input.EndTime != nil {
+		reservation.EndTime = *input.EndTime
+	}
+
+	// Completada y cancelada son finales: una reserva cancelada no vuelve a activarse
+	if !models.CanChangeReservationStatus(current.Status, reservation.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": "La reserva " + current.Status + " no puede pasar a " + reservation.Status})
+		return
+	}
+	// Las fechas las usan la disponibilidad y los comandos al vehículo; el cliente que quiera otras
+	// cancela y reserva de nuevo
+	if !reservation.StartTime.Equal(current.StartTime) || !reservation.EndTime.Equal(current.EndTime) {
+		if _, role := currentUser(c); !isStaff(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Solo el personal puede cambiar las fechas; cancele la reserva y cree otra"})
+			return
+		}
+		if current.Status != "activa" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Solo se pueden cambiar las fechas de una reserva activa"})
+			return
+		}
+		if !reservation.EndTime.After(reservation.StartTime) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "La fecha de fin debe ser posterior a la de inicio"})
+			return
+		}
+	}
+
+	// Un viaje solo puede terminar dentro del área de servicio y fuera de zonas restringidas
+	if reservation.Status == "completada" {
+		lat, lng, err := models.GetVehiclePosition(config.DB, current.VehicleID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener la ubicación del vehículo"})
+			return
+		}
+		violations, err := models.CheckPosition(config.DB, lat, lng)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo verificar la zona del vehículo"})
+			return
+		}
+		if len(violations) > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":      "El viaje no puede terminar en la ubicación actual del vehículo",
+				"violations": violations,
+			})
+			return
+		}
+
+		// El estado del vehículo debe quedar registrado al devolverlo
+		hasChecklist, err := models.HasChecklist(config.DB, reservationID, models.ChecklistEnd)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo verificar la lista de condición"})
+			return
+		}
+		if !hasChecklist {
+			c.JSON(http.StatusConflict, gin.H{"error": "Envíe la lista de condición de fin de viaje antes de terminarlo"})
+			return
+		}
+	}
+
+	// El cambio y su evento se guardan juntos
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar la reserva"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Update bloquea el vehículo y verifica que las nuevas fechas sigan libres
+	if err := reservation.Update(tx, reservationID); err != nil {
+		writeModelError(c, err, "No se pudo actualizar la reserva")
+		return
+	}
+	if event := reservationEvent(current.Status, reservation); event != nil {
+		actorID, _ := currentUser(c)
+		if err := events.Publish(tx, actorID, event); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar la reserva"})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar la reserva"})
 		return
 	}
@@ -199,6 +300,20 @@ func UpdateReservation(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Reserva actualizada correctamente"})
 }
 
+// reservationEvent es el evento de un cambio de estado de la reserva, o nil si no lo hay
+func reservationEvent(from string, updated models.Reservation) events.Event {
+	if from == updated.Status {
+		return nil
+	}
+	switch updated.Status {
+	case "cancelada":
+		return events.ReservationCancelled{Reservation: updated}
+	case "completada":
+		return events.TripFinished{Reservation: updated}
+	}
+	return nil
+}
+
 // DeleteReservation deletes a specific reservation by ID
 func DeleteReservation(c *gin.Context) {
 	id := c.Param("id")
@@ -208,7 +323,44 @@ func DeleteReservation(c *gin.Context) {
 		return
 	}
 
-	if err := models.DeleteReservation(config.DB, reservationID); err != nil { // Pass reservationID as int
+	// Solo el titular o el personal pueden eliminar la reserva
+	reservation, ok := reservationForUser(c, reservationID)
+	if !ok {
+		return
+	}
+
+	// Las reservas completadas y canceladas forman parte del historial del vehículo y del cliente
+	if reservation.Status != "activa" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Solo se pueden eliminar reservas activas"})
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar la reserva"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Borrar una reserva activa equivale a cancelarla; el evento se guarda antes del borrado porque la
+	// reserva deja de existir, y se descarta con él si el borrado falla
+	reservation.Status = "cancelada"
+	actorID, _ := currentUser(c)
+	if err := events.Publish(tx, actorID, events.ReservationCancelled{Reservation: reservation}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar la reserva"})
+		return
+	}
+	err = models.DeleteReservation(tx, reservationID)
+	if errors.Is(err, models.ErrConflict) {
+		// Ya empezó: tiene telemetría, comandos o listas de condición que no se pueden perder
+		c.JSON(http.StatusConflict, gin.H{"error": "La reserva ya tiene actividad registrada; cancélela en lugar de eliminarla"})
+		return
+	}
+	if err != nil {
+		writeModelError(c, err, "No se pudo eliminar la reserva")
+		return
+	}
+	if err := tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar la reserva"})
 		return
 	}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryPage lee los parámetros limit y cursor de un listado paginado
+func queryPage(c *gin.Context) (int, string, bool) {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit inválido"})
+			return 0, "", false
+		}
+	}
+	return limit, c.Query("cursor"), true
+}
+
+// CreateReview registra la calificación (1-5) y el comentario del cliente sobre el vehículo de una
+// reserva completada
+func CreateReview(c *gin.Context) {
+	reservationID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var input struct {
+		Rating  int     `json:"rating" binding:"required"`
+		Comment *string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	userID, _ := currentUser(c)
+	review, err := models.CreateReview(config.DB, userID, reservationID, input.Rating, input.Comment)
+	if err != nil {
+		writeModelError(c, err, "No se pudo registrar la reseña")
+		return
+	}
+	c.JSON(http.StatusCreated, review)
+}
+
+// ListVehicleReviews devuelve las reseñas publicadas de un vehículo, paginadas con cursor
+func ListVehicleReviews(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	limit, cursor, ok := queryPage(c)
+	if !ok {
+		return
+	}
+
+	page, err := models.GetVehicleReviews(config.DB, vehicleID, limit, cursor)
+	if err != nil {
+		writeModelError(c, err, "No se pudieron obtener las reseñas")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// ListReviews devuelve todas las reseñas para moderación, filtrables por status (published, hidden)
+func ListReviews(c *gin.Context) {
+	limit, cursor, ok := queryPage(c)
+	if !ok {
+		return
+	}
+
+	page, err := models.GetReviewsByStatus(config.DB, c.Query("status"), limit, cursor)
+	if err != nil {
+		writeModelError(c, err, "No se pudieron obtener las reseñas")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// ModerateReview oculta o vuelve a publicar una reseña
+func ModerateReview(c *gin.Context) {
+	reviewID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var input struct {
+		Status string  `json:"status" binding:"required"`
+		Reason *string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	staffID, _ := currentUser(c)
+	if err := models.ModerateReview(config.DB, reviewID, staffID, input.Status, input.Reason); err != nil {
+		writeModelError(c, err, "No se pudo moderar la reseña")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Reseña actualizada"})
+}
+
+// DeleteReview elimina una reseña
+func DeleteReview(c *gin.Context) {
+	reviewID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteReview(config.DB, reviewID); err != nil {
+		writeModelError(c, err, "No se pudo eliminar la reseña")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Reseña eliminada"})
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"go-auth-api/src/models"
+	"go-auth-api/src/realtime"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const streamHeartbeat = 15 * time.Second
+
+// parseStreamFilter lee ids=1,2,3 y bbox=minLat,minLng,maxLat,maxLng
+func parseStreamFilter(c *gin.Context) (realtime.Filter, bool) {
+	var filter realtime.Filter
+
+	if raw := c.Query("ids"); raw != "" {
+		filter.VehicleIDs = map[int]bool{}
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "ids inválido"})
+				return filter, false
+			}
+			filter.VehicleIDs[id] = true
+		}
+	}
+
+	if raw := c.Query("bbox"); raw != "" {
+		parts := strings.Split(raw, ",")
+		if len(parts) != 4 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bbox debe ser minLat,minLng,maxLat,maxLng"})
+			return filter, false
+		}
+		var v [4]float64
+		for i, part := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "bbox debe ser minLat,minLng,maxLat,maxLng"})
+				return filter, false
+			}
+			v[i] = f
+		}
+		box := realtime.BBox{MinLat: v[0], MinLng: v[1], MaxLat: v[2], MaxLng: v[3]}
+		if !models.ValidCoordinates(box.MinLat, box.MinLng) || !models.ValidCoordinates(box.MaxLat, box.MaxLng) ||
+			box.MinLat > box.MaxLat || box.MinLng > box.MaxLng {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bbox fuera de rango"})
+			return filter, false
+		}
+		filter.BBox = &box
+	}
+	return filter, true
+}
+
+// StreamVehicles envía por Server-Sent Events los cambios de posición y estado de los vehículos
+// que cumplen el filtro (ids y/o bbox). Un cliente demasiado lento pierde los eventos más antiguos
+// y, si no se recupera, se desconecta para que vuelva a conectarse.
+func StreamVehicles(c *gin.Context) {
+	filter, ok := parseStreamFilter(c)
+	if !ok {
+		return
+	}
+
+	sub := realtime.Vehicles.Subscribe(filter)
+	defer realtime.Vehicles.Unsubscribe(sub)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Evitar que un proxy nginx acumule el stream
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.SSEvent("ready", gin.H{"heartbeat_seconds": int(streamHeartbeat.Seconds())})
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, open := <-sub.C:
+			if !open {
+				c.SSEvent("error", gin.H{"error": "Cliente demasiado lento, vuelva a conectarse"})
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("heartbeat", gin.H{"at": time.Now()})
+			return true
+		}
+	})
+}
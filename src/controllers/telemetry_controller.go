@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"go-auth-api/src/realtime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ingestTelemetry registra un lote de puntos {"points": [...]} del vehículo
+func ingestTelemetry(c *gin.Context, vehicleID int) {
+	var input struct {
+		Points []models.TelemetryPoint `json:"points" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	summary, err := models.IngestTelemetry(config.DB, vehicleID, input.Points)
+	if err != nil {
+		writeModelError(c, err, "No se pudo registrar la telemetría")
+		return
+	}
+	if summary.PositionChanged {
+		realtime.PublishPosition(vehicleID, summary.Latitude, summary.Longitude, *summary.LastTelemetryAt)
+		checkGeofences(vehicleID, summary)
+	}
+
+	c.JSON(http.StatusAccepted, summary)
+}
+
+// GetVehicleTrail devuelve el recorrido de un vehículo entre from y to (por defecto las últimas 24 horas)
+func GetVehicleTrail(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	from, to, ok := queryTimeRange(c, 24*time.Hour)
+	if !ok {
+		return
+	}
+
+	trail, err := models.GetLocationTrail(config.DB, vehicleID, from, to)
+	if err != nil {
+		writeModelError(c, err, "No se pudo obtener el recorrido")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"vehicle_id": vehicleID, "from": from, "to": to, "points": trail})
+}
+
+// GetReservationTrail devuelve el recorrido del vehículo durante una reserva.
+// El cliente solo puede consultar sus propias reservas.
+func GetReservationTrail(c *gin.Context) {
+	reservationID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	reservation, ok := reservationForUser(c, reservationID)
+	if !ok {
+		return
+	}
+
+	// Acotar la consulta a la ventana de la reserva
+	from, to := reservation.StartTime, reservation.EndTime
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+	if raw := c.Query("from"); raw != "" {
+		if t, err := time.Parse(time.RFC3339, raw); err == nil && t.After(from) {
+			from = t
+		}
+	}
+	if raw := c.Query("to"); raw != "" {
+		if t, err := time.Parse(time.RFC3339, raw); err == nil && t.Before(to) {
+			to = t
+		}
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusOK, gin.H{"reservation_id": reservationID, "from": from, "to": to, "points": []models.TrailPoint{}})
+		return
+	}
+
+	trail, err := models.GetLocationTrail(config.DB, reservation.VehicleID, from, to)
+	if err != nil {
+		writeModelError(c, err, "No se pudo obtener el recorrido")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reservation_id": reservationID, "vehicle_id": reservation.VehicleID,
+		"from": from, "to": to, "points": trail})
+}
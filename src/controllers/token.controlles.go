@@ -3,16 +3,12 @@ package controllers
 import (
 	"go-auth-api/src/models"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// SecretKey clave secreta para firmar el token (debería ir en las variables de entorno)
-var SecretKey = []byte(os.Getenv("JWT_SECRET"))
-
 // GenerateToken genera un token JWT sin fecha de expiración
 func GenerateToken(c *gin.Context) {
 	// Obtiene el username del body de la solicitud o cualquier otro dato necesario
@@ -26,11 +22,8 @@ func GenerateToken(c *gin.Context) {
 		},
 	}
 
-	// Crear el token con las reclamaciones
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
 	// Firmar el token con la clave secreta
-	tokenString, err := token.SignedString(SecretKey)
+	tokenString, err := models.SignClaims(claims)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al generar el token"})
 		return
@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"go-auth-api/src/config"
+	"go-auth-api/src/events"
 	"go-auth-api/src/models"
+	"go-auth-api/src/realtime"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,45 +16,186 @@ import (
 
 // Actualizar la ubicación del vehículo
 func UpdateVehicleLocation(c *gin.Context) {
-	var vehicle models.Vehicle
-	if err := c.ShouldBindJSON(&vehicle); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	updateVehicleLocation(c, id)
+}
+
+func updateVehicleLocation(c *gin.Context, id int) {
+	var input struct {
+		Latitude  *float64 `json:"latitude" binding:"required"`
+		Longitude *float64 `json:"longitude" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	if !models.ValidCoordinates(*input.Latitude, *input.Longitude) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Coordenadas inválidas: latitud entre -90 y 90, longitud entre -180 y 180"})
 		return
 	}
 
 	// Actualizar ubicación
-	if err := vehicle.UpdateLocation(config.DB, vehicle.Latitude, vehicle.Longitude); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar la ubicación"})
+	vehicle := models.Vehicle{ID: id}
+	if err := vehicle.UpdateLocation(config.DB, *input.Latitude, *input.Longitude); err != nil {
+		writeModelError(c, err, "No se pudo actualizar la ubicación")
 		return
 	}
+	realtime.PublishPosition(id, vehicle.Latitude, vehicle.Longitude, time.Now())
 
 	c.JSON(http.StatusOK, gin.H{"message": "Ubicación actualizada correctamente"})
 }
 
 // Cambiar estado del vehículo
 func UpdateVehicleStatus(c *gin.Context) {
-	var vehicle models.Vehicle
-	if err := c.ShouldBindJSON(&vehicle); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos"})
+	id, ok := paramID(c, "id")
+	if !ok {
 		return
 	}
+	updateVehicleStatus(c, id)
+}
 
-	// Verificar si el estado es un puntero a string y no es nil
-	if vehicle.Status != nil {
-		// Actualizar estado del vehículo, desreferenciando el puntero
-		if err := vehicle.UpdateStatus(config.DB, *vehicle.Status); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar el estado"})
-			return
+// settableVehicleStatuses son los estados que acepta updateVehicleStatus: todos salvo el retiro
+func settableVehicleStatuses() []string {
+	statuses := make([]string, 0, len(models.VehicleStatuses))
+	for _, s := range models.VehicleStatuses {
+		if s != models.VehicleStatusRetired {
+			statuses = append(statuses, s)
 		}
-	} else {
-		// Si el estado es nil, devolver un error o manejar según el caso
-		c.JSON(http.StatusBadRequest, gin.H{"error": "El estado del vehículo no puede ser nulo"})
+	}
+	return statuses
+}
+
+func updateVehicleStatus(c *gin.Context, id int) {
+	var input struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	// El retiro tiene su propio endpoint porque además registra la fecha de baja
+	if input.Status == models.VehicleStatusRetired {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use POST /api/admin/vehicles/:id/retire para retirar un vehículo"})
+		return
+	}
+	if !models.ValidVehicleStatus(input.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Estado inválido", "allowed": settableVehicleStatuses()})
 		return
 	}
 
+	// Actualizar estado del vehículo; el cambio y su evento se guardan juntos
+	tx, err := config.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar el estado"})
+		return
+	}
+	defer tx.Rollback()
+
+	vehicle := models.Vehicle{ID: id}
+	previous, err := vehicle.UpdateStatus(tx, input.Status)
+	if errors.Is(err, models.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "El vehículo está retirado y su estado ya no cambia"})
+		return
+	}
+	if err != nil {
+		writeModelError(c, err, "No se pudo actualizar el estado")
+		return
+	}
+	actorID, _ := currentUser(c) // 0 cuando el cambio lo envía la unidad del vehículo
+	changed := events.VehicleStatusChanged{VehicleID: id, From: previous, To: input.Status,
+		Latitude: vehicle.Latitude, Longitude: vehicle.Longitude}
+	if err := events.Publish(tx, actorID, changed); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar el estado"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar el estado"})
+		return
+	}
+	// Los clientes conectados a esta réplica se enteran al momento; no pasa por el bus porque el
+	// dispatcher puede correr en otra
+	realtime.PublishStatus(id, input.Status, vehicle.Latitude, vehicle.Longitude)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Estado del vehículo actualizado"})
 }
 
+// parseVehicleFilter reads the listing filters, sorting and pagination from the query string
+func parseVehicleFilter(c *gin.Context) (models.VehicleFilter, error) {
+	f := models.VehicleFilter{
+		Brand:    c.Query("brand"),
+		Model:    c.Query("model"),
+		FuelType: c.Query("fuel_type"),
+		SortBy:   c.Query("sort"),
+		Cursor:   c.Query("cursor"),
+	}
+
+	floats := map[string]**float64{
+		"min_price":  &f.MinPrice,
+		"max_price":  &f.MaxPrice,
+		"min_rating": &f.MinRating,
+		"lat":        &f.Latitude,
+		"lng":        &f.Longitude,
+	}
+	for name, dst := range floats {
+		if raw := c.Query(name); raw != "" {
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return f, fmt.Errorf("%s inválido", name)
+			}
+			*dst = &v
+		}
+	}
+
+	bools := map[string]**bool{
+		"economic": &f.IsEconomic,
+		"luxury":   &f.IsLuxury,
+	}
+	for name, dst := range bools {
+		if raw := c.Query(name); raw != "" {
+			v, err := strconv.ParseBool(raw)
+			if err != nil {
+				return f, fmt.Errorf("%s inválido", name)
+			}
+			*dst = &v
+		}
+	}
+
+	times := map[string]**time.Time{
+		"available_from": &f.AvailableFrom,
+		"available_to":   &f.AvailableTo,
+	}
+	for name, dst := range times {
+		if raw := c.Query(name); raw != "" {
+			v, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return f, fmt.Errorf("%s inválido, use RFC3339", name)
+			}
+			*dst = &v
+		}
+	}
+
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		f.Descending = true
+	default:
+		return f, fmt.Errorf("order debe ser asc o desc")
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return f, fmt.Errorf("limit inválido")
+		}
+		f.Limit = limit
+	}
+	return f, nil
+}
+
 func GetAvailableVehicles(c *gin.Context) {
 	// Parse the start_time and end_time from the request query parameters
 	startTimeStr := c.Query("start_time")
@@ -65,30 +210,54 @@ func GetAvailableVehicles(c *gin.Context) {
 
 	endTime, err := time.Parse(time.RFC3339, endTimeStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Fecha de finalización inválida", "err": err, "endTime": endTime})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fecha de finalización inválida", "err": err.Error()})
+		return
+	}
+
+	filter, err := parseVehicleFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Filtros inválidos", "err": err.Error()})
 		return
 	}
 
 	// Lógica para obtener los vehículos disponibles
-	vehicles, err := models.GetAllAvailableVehicles(config.DB, startTime, endTime)
+	filter.UserID, _ = currentUser(c)
+	page, err := models.GetAllAvailableVehicles(config.DB, startTime, endTime, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los vehículos disponibles", "err": err})
+		writeVehicleListError(c, err, "No se pudieron obtener los vehículos disponibles")
 		return
 	}
 
 	// Retornar la lista de vehículos disponibles
-	c.JSON(http.StatusOK, vehicles)
+	c.JSON(http.StatusOK, page)
 }
 
-// ListVehicles retrieves all vehicles
+// ListVehicles retrieves vehicles matching the query filters, one page at a time
 func ListVehicles(c *gin.Context) {
-	vehicles, err := models.GetAllVehicles(config.DB)
+	filter, err := parseVehicleFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los vehículos", "err": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Filtros inválidos", "err": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, vehicles)
+	filter.UserID, _ = currentUser(c)
+	page, err := models.SearchVehicles(config.DB, filter)
+	if err != nil {
+		writeVehicleListError(c, err, "No se pudieron obtener los vehículos")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// writeVehicleListError maps listing errors: a bad cursor or filter combination is the client's fault
+func writeVehicleListError(c *gin.Context, err error, message string) {
+	var filterErr *models.FilterError
+	if errors.Is(err, models.ErrInvalidCursor) || errors.As(err, &filterErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Filtros inválidos", "err": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message, "err": err.Error()})
 }
 
 // GetVehicle retrieves a specific vehicle by ID
@@ -106,5 +275,14 @@ func GetVehicle(c *gin.Context) {
 		return
 	}
 
+	// is_favorited depende del usuario que consulta
+	userID, _ := currentUser(c)
+	vehicles := []models.Vehicle{vehicle}
+	if err := models.MarkFavorites(config.DB, userID, vehicles); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener el vehículo"})
+		return
+	}
+	vehicle = vehicles[0]
+
 	c.JSON(http.StatusOK, vehicle)
 }
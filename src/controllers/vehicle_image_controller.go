@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"go-auth-api/src/services"
+	"go-auth-api/src/storage"
+	"image"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxImageUploadBytes = 5 << 20 // 5 MB
+	maxImagePixels      = 40e6    // Evita imágenes gigantes que agoten la memoria al decodificar
+	thumbnailSide       = 320
+)
+
+// Tipos aceptados, detectados a partir del contenido y no de la cabecera del cliente
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// UploadVehicleImage recibe una imagen multipart (campo "image"), la guarda junto con su miniatura
+// y la añade al final de la galería del vehículo
+func UploadVehicleImage(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	exists, err := models.VehicleExists(config.DB, vehicleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo verificar el vehículo"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vehículo no encontrado"})
+		return
+	}
+
+	stored, ok := receiveImage(c, "image", fmt.Sprintf("vehicles/%d", vehicleID))
+	if !ok {
+		return
+	}
+
+	vehicleImage := models.VehicleImage{
+		VehicleID:    vehicleID,
+		ImageURL:     stored.URL,
+		ThumbnailURL: &stored.ThumbnailURL,
+		StorageKey:   &stored.Key,
+		ThumbnailKey: &stored.ThumbnailKey,
+		ContentType:  &stored.ContentType,
+	}
+	if err := vehicleImage.Create(config.DB); err != nil {
+		deleteBlobs(stored.Key, stored.ThumbnailKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar la imagen"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, vehicleImage)
+}
+
+// storedImage describe una imagen subida y guardada junto con su miniatura
+type storedImage struct {
+	URL          string
+	ThumbnailURL string
+	Key          string
+	ThumbnailKey string
+	ContentType  string
+}
+
+// receiveImage lee la imagen multipart del campo indicado, valida su formato y dimensiones, genera
+// la miniatura y guarda ambas bajo prefix. Si algo falla responde al cliente y devuelve false.
+func receiveImage(c *gin.Context, field, prefix string) (storedImage, bool) {
+	var stored storedImage
+
+	// Limitar el cuerpo completo (archivo + cabeceras multipart)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageUploadBytes+1<<20)
+	fileHeader, err := c.FormFile(field)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere el archivo '" + field + "'", "details": err.Error()})
+		return stored, false
+	}
+	if fileHeader.Size > maxImageUploadBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "La imagen supera el tamaño máximo de 5 MB"})
+		return stored, false
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No se pudo leer la imagen"})
+		return stored, false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxImageUploadBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No se pudo leer la imagen"})
+		return stored, false
+	}
+	if len(data) > maxImageUploadBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "La imagen supera el tamaño máximo de 5 MB"})
+		return stored, false
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, allowed := allowedImageTypes[contentType]
+	if !allowed {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Formato no soportado, use JPEG, PNG o GIF"})
+		return stored, false
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	// Se limita el área y no cada lado: una imagen de 8000x1 no pesa lo mismo que una de 8000x8000
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > maxImagePixels {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Imagen inválida o demasiado grande"})
+		return stored, false
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Imagen inválida"})
+		return stored, false
+	}
+	thumbnail, err := services.GenerateThumbnail(img, thumbnailSide)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo generar la miniatura"})
+		return stored, false
+	}
+
+	name, err := randomName()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo guardar la imagen"})
+		return stored, false
+	}
+	key := fmt.Sprintf("%s/%s%s", prefix, name, ext)
+	thumbKey := fmt.Sprintf("%s/%s_thumb.jpg", prefix, name)
+
+	ctx := c.Request.Context()
+	url, err := storage.Store.Put(ctx, key, data, contentType)
+	if err != nil {
+		log.Printf("Error guardando la imagen %s: %v", key, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "No se pudo guardar la imagen"})
+		return stored, false
+	}
+	thumbURL, err := storage.Store.Put(ctx, thumbKey, thumbnail, "image/jpeg")
+	if err != nil {
+		log.Printf("Error guardando la miniatura %s: %v", thumbKey, err)
+		deleteBlobs(key)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "No se pudo guardar la imagen"})
+		return stored, false
+	}
+
+	return storedImage{URL: url, ThumbnailURL: thumbURL, Key: key, ThumbnailKey: thumbKey, ContentType: contentType}, true
+}
+
+// deleteBlobs elimina objetos del almacenamiento; los fallos solo se registran
+func deleteBlobs(keys ...string) {
+	for _, key := range keys {
+		if err := storage.Store.Delete(context.Background(), key); err != nil {
+			log.Printf("No se pudo eliminar el objeto %s: %v", key, err)
+		}
+	}
+}
+
+// SetPrimaryVehicleImage marca una imagen como principal
+func SetPrimaryVehicleImage(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	imageID, ok := paramID(c, "image_id")
+	if !ok {
+		return
+	}
+
+	if err := models.SetPrimaryVehicleImage(config.DB, vehicleID, imageID); err != nil {
+		writeModelError(c, err, "No se pudo actualizar la imagen principal")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Imagen principal actualizada"})
+}
+
+// ReorderVehicleImages recibe {"image_ids": [...]} con el nuevo orden de la galería
+func ReorderVehicleImages(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ImageIDs []int `json:"image_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	if err := models.ReorderVehicleImages(config.DB, vehicleID, input.ImageIDs); err != nil {
+		writeModelError(c, err, "No se pudo reordenar las imágenes")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Imágenes reordenadas"})
+}
+
+// DeleteVehicleImage elimina una imagen y sus archivos
+func DeleteVehicleImage(c *gin.Context) {
+	vehicleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	imageID, ok := paramID(c, "image_id")
+	if !ok {
+		return
+	}
+
+	img, err := models.DeleteVehicleImage(config.DB, vehicleID, imageID)
+	if err != nil {
+		writeModelError(c, err, "No se pudo eliminar la imagen")
+		return
+	}
+
+	// Las imágenes añadidas a mano solo tienen URL y no se borran del almacenamiento
+	for _, key := range []*string{img.StorageKey, img.ThumbnailKey} {
+		if key != nil {
+			deleteBlobs(*key)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Imagen eliminada"})
+}
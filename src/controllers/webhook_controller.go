@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// organizationForAdmin lee el :org_id de la ruta y comprueba que el usuario administra esa
+// organización o es administrador de la plataforma; en otro caso responde 404 y devuelve false
+func organizationForAdmin(c *gin.Context) (int, bool) {
+	orgID, ok := paramID(c, "org_id")
+	if !ok {
+		return 0, false
+	}
+	userID, role := currentUser(c)
+	if role == models.RoleAdmin {
+		return orgID, true
+	}
+	membership, err := models.GetOrganizationMembership(config.DB, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo verificar la organización"})
+		return 0, false
+	}
+	if err != nil || membership.OrganizationID != orgID || membership.Role != models.OrganizationAdmin {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organización no encontrada"})
+		return 0, false
+	}
+	return orgID, true
+}
+
+// webhookInput son los campos editables de una suscripción; active es true si se omite
+type webhookInput struct {
+	URL         string   `json:"url" binding:"required"`
+	Description string   `json:"description"`
+	EventTypes  []string `json:"event_types"`
+	Active      *bool    `json:"active"`
+}
+
+func (in webhookInput) subscription(orgID int) models.WebhookSubscription {
+	active := in.Active == nil || *in.Active
+	return models.WebhookSubscription{OrganizationID: orgID, URL: in.URL, Description: in.Description,
+		EventTypes: in.EventTypes, Active: active}
+}
+
+// ListWebhooks devuelve las suscripciones de la organización y los tipos de evento disponibles
+func ListWebhooks(c *gin.Context) {
+	orgID, ok := organizationForAdmin(c)
+	if !ok {
+		return
+	}
+	subscriptions, err := models.GetWebhookSubscriptions(config.DB, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los webhooks"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": subscriptions, "event_types": models.WebhookEventTypes})
+}
+
+// CreateWebhook registra un endpoint; el secreto de firma solo se devuelve en esta respuesta
+func CreateWebhook(c *gin.Context) {
+	orgID, ok := organizationForAdmin(c)
+	if !ok {
+		return
+	}
+	var input webhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	subscription := input.subscription(orgID)
+	if err := subscription.Create(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo crear el webhook")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"webhook": subscription, "secret": subscription.Secret})
+}
+
+// UpdateWebhook cambia la URL, la descripción, el filtro de eventos o lo desactiva
+func UpdateWebhook(c *gin.Context) {
+	orgID, ok := organizationForAdmin(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var input webhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+
+	subscription := input.subscription(orgID)
+	subscription.ID = id
+	if err := subscription.Update(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo actualizar el webhook")
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+// DeleteWebhook elimina la suscripción y su registro de entregas
+func DeleteWebhook(c *gin.Context) {
+	orgID, ok := organizationForAdmin(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if err := models.DeleteWebhookSubscription(config.DB, orgID, id); err != nil {
+		writeModelError(c, err, "No se pudo eliminar el webhook")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook eliminado"})
+}
+
+// RotateWebhookSecret genera un nuevo secreto de firma; el anterior deja de usarse de inmediato
+func RotateWebhookSecret(c *gin.Context) {
+	orgID, ok := organizationForAdmin(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	subscription, err := models.RotateWebhookSecret(config.DB, orgID, id)
+	if err != nil {
+		writeModelError(c, err, "No se pudo rotar el secreto")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhook": subscription, "secret": subscription.Secret})
+}
+
+// ListWebhookDeliveries devuelve las entregas de una suscripción, filtrables por status (pending,
+// sending, delivered, dead)
+func ListWebhookDeliveries(c *gin.Context) {
+	orgID, ok := organizationForAdmin(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit debe estar entre 1 y 500"})
+			return
+		}
+		limit = n
+	}
+	if _, err := models.GetWebhookSubscription(config.DB, orgID, id); err != nil {
+		writeModelError(c, err, "No se pudieron obtener las entregas")
+		return
+	}
+
+	deliveries, err := models.GetWebhookDeliveries(config.DB, orgID, id, c.Query("status"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las entregas"})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// webhookDeliveryID lee el :delivery_id de la ruta
+func webhookDeliveryID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return 0, false
+	}
+	return id, true
+}
+
+// GetWebhookDelivery devuelve una entrega con el registro de cada intento: código de respuesta,
+// cuerpo recibido, error y duración
+func GetWebhookDelivery(c *gin.Context) {
+	orgID, ok := organizationForAdmin(c)
+	if !ok {
+		return
+	}
+	id, ok := webhookDeliveryID(c)
+	if !ok {
+		return
+	}
+	delivery, err := models.GetWebhookDelivery(config.DB, orgID, id)
+	if err != nil {
+		writeModelError(c, err, "No se pudo obtener la entrega")
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// RedeliverWebhook vuelve a enviar el evento de una entrega, aunque ya se hubiera entregado
+func RedeliverWebhook(c *gin.Context) {
+	orgID, ok := organizationForAdmin(c)
+	if !ok {
+		return
+	}
+	id, ok := webhookDeliveryID(c)
+	if !ok {
+		return
+	}
+	if err := models.RedeliverWebhook(config.DB, orgID, id); err != nil {
+		writeModelError(c, err, "No se pudo reenviar el webhook")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Entrega encolada de nuevo"})
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"fmt"
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListZones devuelve las zonas; los clientes solo ven las activas, el personal puede pedir all=true
+func ListZones(c *gin.Context) {
+	_, role := currentUser(c)
+	activeOnly := !(isStaff(role) && c.Query("all") == "true")
+
+	zones, err := models.GetZones(config.DB, activeOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las zonas"})
+		return
+	}
+	c.JSON(http.StatusOK, zones)
+}
+
+func CreateZone(c *gin.Context) {
+	zone := models.Zone{Active: true}
+	if err := c.ShouldBindJSON(&zone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	if err := zone.Create(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo crear la zona")
+		return
+	}
+	c.JSON(http.StatusCreated, zone)
+}
+
+func UpdateZone(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	zone := models.Zone{Active: true}
+	if err := c.ShouldBindJSON(&zone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos inválidos", "details": err.Error()})
+		return
+	}
+	zone.ID = id
+	if err := zone.Update(config.DB); err != nil {
+		writeModelError(c, err, "No se pudo actualizar la zona")
+		return
+	}
+	c.JSON(http.StatusOK, zone)
+}
+
+func DeleteZone(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if err := models.DeleteZone(config.DB, id); err != nil {
+		writeModelError(c, err, "No se pudo eliminar la zona")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Zona eliminada correctamente"})
+}
+
+// ListGeofenceAlerts devuelve las alertas más recientes, filtrables por vehicle_id
+func ListGeofenceAlerts(c *gin.Context) {
+	var vehicleID *int
+	if raw := c.Query("vehicle_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "vehicle_id inválido"})
+			return
+		}
+		vehicleID = &id
+	}
+
+	alerts, err := models.GetGeofenceAlerts(config.DB, vehicleID, 200)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las alertas"})
+		return
+	}
+	c.JSON(http.StatusOK, alerts)
+}
+
+// geofenceMessage es el aviso que recibe el cliente cuando su vehículo rompe una regla de zona
+func geofenceMessage(alert models.GeofenceAlert) string {
+	switch alert.Kind {
+	case models.AlertEnteredNoGo:
+		return "El vehículo de su reserva ha entrado en una zona restringida. Por favor, salga de ella."
+	default:
+		return fmt.Sprintf("El vehículo de su reserva ha salido del área de servicio (%.5f, %.5f). "+
+			"Recuerde que el viaje debe terminar dentro del área.", alert.Latitude, alert.Longitude)
+	}
+}
+
+// checkGeofences evalúa el recorrido recién recibido y avisa al cliente de cada alerta nueva.
+// Los fallos se registran sin afectar a la ingesta de telemetría.
+func checkGeofences(vehicleID int, summary models.TelemetrySummary) {
+	alerts, err := models.EvaluateGeofences(config.DB, vehicleID, summary.Previous, summary.Path)
+	if err != nil {
+		log.Printf("Error evaluando geocercas del vehículo %d: %v", vehicleID, err)
+	}
+	for _, alert := range alerts {
+		if alert.UserID == nil {
+			continue
+		}
+		notification := models.Notification{UserID: *alert.UserID, Type: models.TypeGeofence, Message: geofenceMessage(alert)}
+		if err := notification.Send(config.DB); err != nil {
+			log.Printf("No se pudo notificar la alerta de geocerca %d: %v", alert.ID, err)
+		}
+	}
+}
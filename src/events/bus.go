@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"go-auth-api/src/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Envelope es un evento entregado a un suscriptor, con los datos de su publicación
+type Envelope struct {
+	ID         int64
+	ActorID    *int // Quién causó el cambio; nil para dispositivos y procesos en segundo plano
+	OccurredAt time.Time
+	Event      Event
+}
+
+// Handler reacciona a un evento. Corre en la transacción que marca el evento como atendido, así que
+// sus escrituras en la base de datos se hacen exactamente una vez; si devuelve un error se deshacen y
+// el evento se reintenta más tarde.
+type Handler func(ctx context.Context, tx models.DBTX, e Envelope) error
+
+type subscriber struct {
+	handler Handler
+	types   map[string]bool // Vacío: todos los tipos
+}
+
+var (
+	mu          sync.RWMutex
+	subscribers = map[string]subscriber{}
+)
+
+// Subscribe registra un suscriptor con nombre para los tipos de evento dados, o para todos si no se
+// indica ninguno. Se llama al arrancar, antes de iniciar el Dispatcher. El nombre identifica el
+// progreso del suscriptor en la base de datos, así que no debe cambiar entre versiones.
+func Subscribe(name string, handler Handler, types ...string) {
+	s := subscriber{handler: handler, types: map[string]bool{}}
+	for _, t := range types {
+		s.types[t] = true
+	}
+	mu.Lock()
+	subscribers[name] = s
+	mu.Unlock()
+}
+
+// subscribersFor devuelve los nombres de los suscriptores de un tipo de evento
+func subscribersFor(eventType string) []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	var names []string
+	for name, s := range subscribers {
+		if len(s.types) == 0 || s.types[eventType] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// subscriberNames devuelve los nombres de todos los suscriptores registrados
+func subscriberNames() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(subscribers))
+	for name := range subscribers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func handlerOf(name string) (Handler, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := subscribers[name]
+	return s.handler, ok
+}
+
+// Publish guarda el evento en el outbox. db debe ser la transacción del cambio que lo produce, para
+// que el evento exista solo si el cambio se confirmó. actorID es el usuario que causó el cambio, o 0
+// para dispositivos y procesos en segundo plano.
+func Publish(db models.DBTX, actorID int, e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	record := models.DomainEvent{Type: e.EventType(), Payload: payload}
+	if subject := e.Subject(); subject != 0 {
+		record.UserID = &subject
+	}
+	if actorID != 0 {
+		record.ActorID = &actorID
+	}
+	return models.InsertDomainEvent(db, &record)
+}
@@ -0,0 +1,117 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go-auth-api/src/models"
+	"log"
+	"time"
+)
+
+// Dispatcher entrega los eventos del outbox a los suscriptores. Puede correr en todas las réplicas:
+// cada evento y cada par evento-suscriptor lo toma una sola a la vez.
+type Dispatcher struct {
+	DB           *sql.DB
+	BatchSize    int           // Eventos repartidos y handlers ejecutados por ronda
+	PollInterval time.Duration // Espera entre rondas cuando no hay trabajo
+}
+
+// NewDispatcher crea un dispatcher con los valores por defecto
+func NewDispatcher(db *sql.DB) *Dispatcher {
+	return &Dispatcher{DB: db, BatchSize: 50, PollInterval: 2 * time.Second}
+}
+
+// Start entrega los eventos en segundo plano hasta que se cancele ctx
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		for {
+			processed, err := d.RunOnce(ctx)
+			if err != nil {
+				log.Printf("Error entregando eventos de dominio: %v", err)
+			}
+			if err == nil && processed == d.BatchSize {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(d.PollInterval):
+			}
+		}
+	}()
+}
+
+// RunOnce reparte los eventos nuevos entre sus suscriptores y ejecuta una ronda de handlers pendientes;
+// devuelve cuántos handlers ejecutó
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	if _, err := models.FanOutDomainEvents(d.DB, d.BatchSize, subscribersFor); err != nil {
+		return 0, err
+	}
+	names := subscriberNames()
+	if len(names) == 0 {
+		return 0, nil
+	}
+
+	processed := 0
+	for processed < d.BatchSize && ctx.Err() == nil {
+		ran, err := d.handleNext(ctx, names)
+		if err != nil {
+			return processed, err
+		}
+		if !ran {
+			break
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// handleNext ejecuta el handler pendiente más antiguo en una transacción que lo marca como atendido;
+// devuelve false si no había ninguno. Un fallo del handler se registra y no detiene la ronda.
+func (d *Dispatcher) handleNext(ctx context.Context, names []string) (bool, error) {
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	job, err := models.ClaimEventHandler(tx, names)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if handlerErr := d.run(ctx, tx, job); handlerErr != nil {
+		// Las escrituras del handler se descartan; el fallo se registra fuera de su transacción
+		tx.Rollback()
+		log.Printf("El suscriptor %s falló con el evento %d (%s), intento %d: %v",
+			job.Subscriber, job.ID, job.Type, job.Attempts+1, handlerErr)
+		return true, models.FailEventHandler(d.DB, job, handlerErr)
+	}
+	if err := models.CompleteEventHandler(tx, job); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// run decodifica el evento y llama al handler del suscriptor
+func (d *Dispatcher) run(ctx context.Context, tx *sql.Tx, job models.EventHandlerJob) (err error) {
+	handler, ok := handlerOf(job.Subscriber)
+	if !ok {
+		return fmt.Errorf("suscriptor %q no registrado", job.Subscriber)
+	}
+	event, err := decode(job.Type, job.Payload)
+	if err != nil {
+		return err
+	}
+	// Un handler que entra en pánico cuenta como un intento fallido en lugar de tumbar el dispatcher
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("pánico en el handler: %v", r)
+		}
+	}()
+	return handler(ctx, tx, Envelope{ID: job.ID, ActorID: job.ActorID, OccurredAt: job.OccurredAt, Event: event})
+}
@@ -0,0 +1,122 @@
+// Package events es el bus de eventos de dominio. Los controladores publican un evento en la misma
+// transacción que el cambio que lo produce; el evento queda en la tabla domain_events (el outbox) y un
+// Dispatcher lo entrega después a cada suscriptor registrado al arrancar. Así las notificaciones, los
+// webhooks o el registro de auditoría reaccionan a los cambios sin que los controladores los conozcan.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-auth-api/src/models"
+)
+
+// Tipos de evento, tal como se guardan en domain_events.type
+const (
+	TypeReservationCreated   = "reservation.created"
+	TypeReservationCancelled = "reservation.cancelled"
+	TypeTripStarted          = "trip.started"  // Se envió la lista de condición de inicio
+	TypeTripFinished         = "trip.finished" // La reserva pasó a completada
+	TypePaymentCaptured      = "payment.captured"
+	TypeVehicleStatusChanged = "vehicle.status_changed"
+)
+
+// Event es un hecho del dominio. Subject es el cliente al que se refiere, o 0 si no hay ninguno
+type Event interface {
+	EventType() string
+	Subject() int
+}
+
+// ReservationCreated se publica al confirmar una reserva
+type ReservationCreated struct {
+	Reservation  models.Reservation `json:"reservation"`
+	Vehicle      string             `json:"vehicle"` // Marca y modelo
+	LicensePlate string             `json:"license_plate"`
+}
+
+func (e ReservationCreated) EventType() string { return TypeReservationCreated }
+func (e ReservationCreated) Subject() int      { return e.Reservation.UserID }
+
+// ReservationCancelled se publica al cancelar una reserva activa o al borrarla
+type ReservationCancelled struct {
+	Reservation models.Reservation `json:"reservation"`
+}
+
+func (e ReservationCancelled) EventType() string { return TypeReservationCancelled }
+func (e ReservationCancelled) Subject() int      { return e.Reservation.UserID }
+
+// TripStarted se publica cuando se registra la lista de condición de inicio del viaje
+type TripStarted struct {
+	Reservation models.Reservation `json:"reservation"`
+}
+
+func (e TripStarted) EventType() string { return TypeTripStarted }
+func (e TripStarted) Subject() int      { return e.Reservation.UserID }
+
+// TripFinished se publica cuando la reserva pasa a completada
+type TripFinished struct {
+	Reservation models.Reservation `json:"reservation"`
+}
+
+func (e TripFinished) EventType() string { return TypeTripFinished }
+func (e TripFinished) Subject() int      { return e.Reservation.UserID }
+
+// PaymentCaptured se publica al registrar un pago
+type PaymentCaptured struct {
+	Payment models.Payment `json:"payment"`
+	UserID  int            `json:"user_id"` // Cliente de la reserva pagada
+}
+
+func (e PaymentCaptured) EventType() string { return TypePaymentCaptured }
+func (e PaymentCaptured) Subject() int      { return e.UserID }
+
+// VehicleStatusChanged se publica cuando cambia el estado operativo de un vehículo
+type VehicleStatusChanged struct {
+	VehicleID int     `json:"vehicle_id"`
+	From      string  `json:"from"` // Vacío si el vehículo no tenía estado
+	To        string  `json:"to"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+func (e VehicleStatusChanged) EventType() string { return TypeVehicleStatusChanged }
+func (e VehicleStatusChanged) Subject() int      { return 0 }
+
+// decode reconstruye el evento guardado en el outbox
+func decode(eventType string, payload []byte) (Event, error) {
+	var (
+		e   Event
+		err error
+	)
+	switch eventType {
+	case TypeReservationCreated:
+		var v ReservationCreated
+		err = json.Unmarshal(payload, &v)
+		e = v
+	case TypeReservationCancelled:
+		var v ReservationCancelled
+		err = json.Unmarshal(payload, &v)
+		e = v
+	case TypeTripStarted:
+		var v TripStarted
+		err = json.Unmarshal(payload, &v)
+		e = v
+	case TypeTripFinished:
+		var v TripFinished
+		err = json.Unmarshal(payload, &v)
+		e = v
+	case TypePaymentCaptured:
+		var v PaymentCaptured
+		err = json.Unmarshal(payload, &v)
+		e = v
+	case TypeVehicleStatusChanged:
+		var v VehicleStatusChanged
+		err = json.Unmarshal(payload, &v)
+		e = v
+	default:
+		return nil, fmt.Errorf("tipo de evento desconocido: %q", eventType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("evento %s ilegible: %v", eventType, err)
+	}
+	return e, nil
+}
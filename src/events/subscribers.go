@@ -0,0 +1,153 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-auth-api/src/models"
+	"go-auth-api/src/templates"
+)
+
+// Suscriptores incluidos en la aplicación; main los registra al arrancar con Subscribe
+
+// NotifyCustomer envía al cliente la confirmación de sus reservas y el recibo de sus pagos. Las
+// notificaciones se encolan en el outbox de entregas dentro de la transacción del handler.
+func NotifyCustomer(ctx context.Context, tx models.DBTX, env Envelope) error {
+	var notification models.Notification
+	switch e := env.Event.(type) {
+	case ReservationCreated:
+		notification = models.Notification{
+			UserID:   e.Reservation.UserID,
+			Template: templates.ReservationConfirmed,
+			Data: templates.ReservationData{
+				ReservationID: e.Reservation.ID,
+				Vehicle:       e.Vehicle,
+				LicensePlate:  e.LicensePlate,
+				Start:         e.Reservation.StartTime,
+				End:           e.Reservation.EndTime,
+			},
+		}
+	case PaymentCaptured:
+		notification = models.Notification{
+			UserID:   e.UserID,
+			Template: templates.PaymentReceipt,
+			Data: templates.ReceiptData{
+				PaymentID:     e.Payment.ID,
+				ReservationID: e.Payment.ReservationID,
+				Amount:        e.Payment.Amount,
+				PaidAt:        e.Payment.PaymentDate,
+			},
+		}
+	default:
+		return nil
+	}
+	return notification.Send(tx)
+}
+
+// NotifyFavoriteAvailable avisa a quienes pidieron saber cuándo un vehículo de sus favoritos vuelve a
+// estar disponible cerca de ellos: cuando pasa a available y cuando termina un viaje con él
+func NotifyFavoriteAvailable(ctx context.Context, tx models.DBTX, env Envelope) error {
+	var (
+		vehicleID int
+		lat, lng  float64
+	)
+	switch e := env.Event.(type) {
+	case VehicleStatusChanged:
+		if e.To != models.VehicleStatusAvailable || e.From == models.VehicleStatusAvailable {
+			return nil
+		}
+		vehicleID, lat, lng = e.VehicleID, e.Latitude, e.Longitude
+	case TripFinished:
+		// El vehículo queda libre donde lo dejó el cliente, así que se usa su posición actual y no la
+		// del momento de la reserva
+		status, currentLat, currentLng, err := models.GetVehicleStatusAndPosition(tx, e.Reservation.VehicleID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if status != models.VehicleStatusAvailable {
+			return nil
+		}
+		vehicleID, lat, lng = e.Reservation.VehicleID, currentLat, currentLng
+	default:
+		return nil
+	}
+	users, err := models.ClaimAvailabilityNotices(tx, vehicleID, lat, lng)
+	if err != nil {
+		return err
+	}
+	for _, userID := range users {
+		notification := models.Notification{
+			UserID:  userID,
+			Type:    models.TypeFavorite,
+			Message: fmt.Sprintf("Un vehículo de sus favoritos (#%d) está disponible cerca de usted.", vehicleID),
+		}
+		if err := notification.Send(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForwardToWebhooks reenvía los eventos de reservas, viajes y pagos a los webhooks de la organización
+// del cliente
+func ForwardToWebhooks(ctx context.Context, tx models.DBTX, env Envelope) error {
+	var (
+		webhookType string
+		data        interface{}
+	)
+	switch e := env.Event.(type) {
+	case ReservationCreated:
+		webhookType, data = models.WebhookReservationCreated, e.Reservation
+	case ReservationCancelled:
+		webhookType, data = models.WebhookReservationCancelled, e.Reservation
+	case TripStarted:
+		webhookType, data = models.WebhookTripStarted, e.Reservation
+	case TripFinished:
+		webhookType, data = models.WebhookTripFinished, e.Reservation
+	case PaymentCaptured:
+		webhookType, data = models.WebhookPaymentCaptured, e.Payment
+	default:
+		return nil
+	}
+	return models.EmitWebhookEvent(tx, env.Event.Subject(), webhookType, data)
+}
+
+// RecordAudit guarda cada evento en el registro de auditoría
+func RecordAudit(ctx context.Context, tx models.DBTX, env Envelope) error {
+	var (
+		entity   string
+		entityID int
+	)
+	switch e := env.Event.(type) {
+	case ReservationCreated:
+		entity, entityID = models.AuditReservation, e.Reservation.ID
+	case ReservationCancelled:
+		entity, entityID = models.AuditReservation, e.Reservation.ID
+	case TripStarted:
+		entity, entityID = models.AuditReservation, e.Reservation.ID
+	case TripFinished:
+		entity, entityID = models.AuditReservation, e.Reservation.ID
+	case PaymentCaptured:
+		entity, entityID = models.AuditPayment, e.Payment.ID
+	case VehicleStatusChanged:
+		entity, entityID = models.AuditVehicle, e.VehicleID
+	default:
+		return fmt.Errorf("evento sin entidad de auditoría: %s", env.Event.EventType())
+	}
+
+	data, err := json.Marshal(env.Event)
+	if err != nil {
+		return err
+	}
+	entry := models.AuditEntry{EventID: &env.ID, Type: env.Event.EventType(), Entity: entity,
+		EntityID: int64(entityID), ActorID: env.ActorID, Data: data, OccurredAt: env.OccurredAt}
+	if subject := env.Event.Subject(); subject != 0 {
+		entry.UserID = &subject
+	}
+	return models.RecordAudit(tx, &entry)
+}
@@ -0,0 +1,83 @@
+package geo
+
+import (
+	"errors"
+	"math"
+)
+
+const earthRadiusKm = 6371.0
+
+// Point es una posición WGS84 en grados
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// HaversineKm devuelve la distancia de círculo máximo entre a y b en kilómetros
+func HaversineKm(a, b Point) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := (b.Lat - a.Lat) * math.Pi / 180
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Polygon es un anillo de vértices; el último vértice se une implícitamente con el primero.
+// También se acepta el anillo cerrado de GeoJSON, que repite el primer vértice al final.
+type Polygon []Point
+
+// boundaryTolerance es la distancia en grados (~0,1 mm) a la que un punto se considera sobre un borde
+const boundaryTolerance = 1e-9
+
+// ring devuelve los vértices sin el de cierre repetido
+func (p Polygon) ring() Polygon {
+	if len(p) > 1 && p[0] == p[len(p)-1] {
+		return p[:len(p)-1]
+	}
+	return p
+}
+
+// Validate comprueba que el polígono tenga al menos tres vértices válidos
+func (p Polygon) Validate() error {
+	if len(p.ring()) < 3 {
+		return errors.New("el polígono requiere al menos 3 vértices")
+	}
+	for _, v := range p {
+		if v.Lat < -90 || v.Lat > 90 || v.Lng < -180 || v.Lng > 180 {
+			return errors.New("vértice con coordenadas inválidas")
+		}
+	}
+	return nil
+}
+
+// Contains indica si pt está dentro del polígono (regla par-impar por trazado de rayos).
+// Los puntos sobre un borde o un vértice cuentan como dentro.
+func (p Polygon) Contains(pt Point) bool {
+	ring := p.ring()
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if onSegment(pt, a, b) {
+			return true
+		}
+		if (a.Lat > pt.Lat) != (b.Lat > pt.Lat) &&
+			pt.Lng < (b.Lng-a.Lng)*(pt.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// onSegment indica si pt está sobre el segmento ab
+func onSegment(pt, a, b Point) bool {
+	if pt.Lat < math.Min(a.Lat, b.Lat)-boundaryTolerance || pt.Lat > math.Max(a.Lat, b.Lat)+boundaryTolerance ||
+		pt.Lng < math.Min(a.Lng, b.Lng)-boundaryTolerance || pt.Lng > math.Max(a.Lng, b.Lng)+boundaryTolerance {
+		return false
+	}
+	// Distancia de pt a la recta ab: |ab x ap| / |ab|
+	cross := (b.Lat-a.Lat)*(pt.Lng-a.Lng) - (b.Lng-a.Lng)*(pt.Lat-a.Lat)
+	return math.Abs(cross) <= boundaryTolerance*math.Hypot(b.Lat-a.Lat, b.Lng-a.Lng)
+}
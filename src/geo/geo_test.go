@@ -0,0 +1,78 @@
+package geo
+
+import "testing"
+
+// square es el cuadrado [0,10] x [0,10]
+var square = Polygon{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 10}, {Lat: 10, Lng: 10}, {Lat: 10, Lng: 0}}
+
+// concave tiene forma de U: la muesca de latitud 3 a 10 y longitud 3 a 7 queda fuera
+var concave = Polygon{
+	{Lat: 0, Lng: 0}, {Lat: 0, Lng: 10}, {Lat: 10, Lng: 10}, {Lat: 10, Lng: 7},
+	{Lat: 3, Lng: 7}, {Lat: 3, Lng: 3}, {Lat: 10, Lng: 3}, {Lat: 10, Lng: 0},
+}
+
+func closed(p Polygon) Polygon {
+	return append(append(Polygon{}, p...), p[0])
+}
+
+func TestPolygonContains(t *testing.T) {
+	tests := []struct {
+		name    string
+		polygon Polygon
+		point   Point
+		want    bool
+	}{
+		{"interior", square, Point{Lat: 5, Lng: 5}, true},
+		{"exterior", square, Point{Lat: 15, Lng: 5}, false},
+		{"exterior alineado con un borde", square, Point{Lat: 0, Lng: 15}, false},
+		{"sobre el borde inferior", square, Point{Lat: 0, Lng: 5}, true},
+		{"sobre el borde superior", square, Point{Lat: 10, Lng: 5}, true},
+		{"sobre el borde izquierdo", square, Point{Lat: 5, Lng: 0}, true},
+		{"sobre el borde derecho", square, Point{Lat: 5, Lng: 10}, true},
+		{"sobre un vértice", square, Point{Lat: 0, Lng: 0}, true},
+		{"sobre el vértice opuesto", square, Point{Lat: 10, Lng: 10}, true},
+		{"rayo a la altura de un vértice", square, Point{Lat: 10, Lng: -5}, false},
+		{"justo fuera del borde", square, Point{Lat: -1e-6, Lng: 5}, false},
+		{"borde diagonal", Polygon{{Lat: 0, Lng: 0}, {Lat: 10, Lng: 10}, {Lat: 0, Lng: 10}}, Point{Lat: 4, Lng: 4}, true},
+		{"cóncavo, brazo izquierdo", concave, Point{Lat: 8, Lng: 1}, true},
+		{"cóncavo, brazo derecho", concave, Point{Lat: 8, Lng: 9}, true},
+		{"cóncavo, base", concave, Point{Lat: 1, Lng: 5}, true},
+		{"cóncavo, dentro de la muesca", concave, Point{Lat: 8, Lng: 5}, false},
+		{"cóncavo, borde de la muesca", concave, Point{Lat: 5, Lng: 3}, true},
+		{"cóncavo, vértice reflejo", concave, Point{Lat: 3, Lng: 7}, true},
+		{"cóncavo, rayo que cruza la muesca", concave, Point{Lat: 8, Lng: -1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.polygon.Contains(tt.point); got != tt.want {
+				t.Errorf("Contains(%v) = %t, want %t", tt.point, got, tt.want)
+			}
+			// El anillo cerrado, con el primer vértice repetido al final, se comporta igual
+			if got := closed(tt.polygon).Contains(tt.point); got != tt.want {
+				t.Errorf("closed ring: Contains(%v) = %t, want %t", tt.point, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolygonValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		polygon Polygon
+		wantErr bool
+	}{
+		{"abierto", square, false},
+		{"cerrado", closed(square), false},
+		{"dos vértices", Polygon{{Lat: 0, Lng: 0}, {Lat: 1, Lng: 1}}, true},
+		{"dos vértices cerrado", Polygon{{Lat: 0, Lng: 0}, {Lat: 1, Lng: 1}, {Lat: 0, Lng: 0}}, true},
+		{"latitud inválida", Polygon{{Lat: 0, Lng: 0}, {Lat: 91, Lng: 1}, {Lat: 0, Lng: 1}}, true},
+		{"longitud inválida", Polygon{{Lat: 0, Lng: 0}, {Lat: 1, Lng: 181}, {Lat: 0, Lng: 1}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.polygon.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
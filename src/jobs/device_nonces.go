@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"database/sql"
+	"go-auth-api/src/models"
+	"log"
+	"time"
+)
+
+// DeviceNonceCleanupInterval es cada cuánto se borran los nonces de dispositivo vencidos
+const DeviceNonceCleanupInterval = 10 * time.Minute
+
+// PurgeDeviceNonces borra los nonces que ya no se pueden repetir; cada petición firmada de una unidad
+// añade uno, así que sin esta limpieza la tabla solo crece
+func PurgeDeviceNonces(db *sql.DB, now time.Time) error {
+	deleted, err := models.DeleteExpiredDeviceNonces(db, now)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("Nonces de dispositivo vencidos borrados: %d", deleted)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"database/sql"
+	"fmt"
+	"go-auth-api/src/models"
+	"time"
+)
+
+// MaintenanceReminderInterval es cada cuánto se revisan los planes de mantenimiento
+const MaintenanceReminderInterval = time.Hour
+
+// RunMaintenanceReminders avisa al personal de cada plan que alcanzó su umbral de tiempo o kilometraje
+// y aún no fue recordado desde el último servicio. Devuelve el número de planes recordados.
+func RunMaintenanceReminders(db *sql.DB) (int, error) {
+	due, err := models.GetDueMaintenance(db, true)
+	if err != nil || len(due) == 0 {
+		return 0, err
+	}
+	staff, err := models.GetStaffUserIDs(db)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, d := range due {
+		message := maintenanceMessage(d)
+		for _, userID := range staff {
+			notification := models.Notification{UserID: userID, Type: models.TypeMaintenance, Message: message}
+			if err := notification.Send(db); err != nil {
+				return sent, err
+			}
+		}
+		if err := models.MarkMaintenanceNotified(db, d.ID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func maintenanceMessage(d models.MaintenanceDue) string {
+	var reason string
+	switch {
+	case d.KmOverdue != nil && d.IntervalKm != nil:
+		reason = fmt.Sprintf("superó los %.0f km desde el último servicio", *d.IntervalKm)
+	case d.IntervalDays != nil:
+		reason = fmt.Sprintf("pasaron %d días desde el último servicio", *d.IntervalDays)
+	}
+	return fmt.Sprintf("Mantenimiento pendiente (%s) del vehículo %s: %s.", d.Type, d.LicensePlate, reason)
+}
@@ -0,0 +1,138 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-auth-api/src/models"
+	"go-auth-api/src/templates"
+	"log"
+	"time"
+)
+
+// ReservationReminderInterval es cada cuánto se buscan reservas que necesitan un recordatorio
+const ReservationReminderInterval = time.Minute
+
+// reminderBatch limita cuántos recordatorios de un tipo se envían por ejecución
+const reminderBatch = 200
+
+// ReservationReminders envía los recordatorios de las reservas activas:
+//   - recogida, PickupLead antes del inicio;
+//   - devolución, ReturnLead antes del fin;
+//   - retraso, OverdueAfter después del fin si la reserva sigue activa, al cliente y al personal.
+//
+// Cada recordatorio se registra en reservation_reminders en la misma transacción que la notificación,
+// así que se envía una sola vez aunque dos ejecuciones se solapen.
+type ReservationReminders struct {
+	DB           *sql.DB
+	PickupLead   time.Duration
+	ReturnLead   time.Duration
+	OverdueAfter time.Duration
+	// Window limita cuánto hacia atrás se buscan retrasos, para no avisar de reservas antiguas que
+	// quedaron activas antes de existir el scheduler
+	Window time.Duration
+}
+
+// NewReservationReminders crea el trabajo con los tiempos por defecto
+func NewReservationReminders(db *sql.DB) *ReservationReminders {
+	return &ReservationReminders{DB: db, PickupLead: 2 * time.Hour, ReturnLead: 30 * time.Minute,
+		OverdueAfter: 15 * time.Minute, Window: 24 * time.Hour}
+}
+
+// Run envía los recordatorios que vencieron hasta now; es la tarea del scheduler. Un recordatorio que
+// falla se registra y no detiene los demás: queda sin reclamar y se reintenta en la siguiente ejecución.
+func (r *ReservationReminders) Run(ctx context.Context, now time.Time) error {
+	kinds := []struct {
+		kind     string
+		from, to time.Time
+	}{
+		// Recogida: reservas que empiezan dentro de PickupLead
+		{models.ReminderPickup, now, now.Add(r.PickupLead)},
+		// Devolución: reservas que terminan dentro de ReturnLead
+		{models.ReminderReturn, now, now.Add(r.ReturnLead)},
+		// Retraso: reservas que terminaron hace más de OverdueAfter y siguen activas
+		{models.ReminderOverdue, now.Add(-r.Window), now.Add(-r.OverdueAfter)},
+	}
+
+	var errs []error
+	var staff []int
+	for _, k := range kinds {
+		due, err := models.GetDueReminders(r.DB, k.kind, k.from, k.to, reminderBatch)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recordatorios %s: %w", k.kind, err))
+			continue
+		}
+		if k.kind == models.ReminderOverdue && len(due) > 0 && staff == nil {
+			if staff, err = models.GetStaffUserIDs(r.DB); err != nil {
+				errs = append(errs, fmt.Errorf("recordatorios %s: %w", k.kind, err))
+				continue
+			}
+		}
+		failed := 0
+		for _, d := range due {
+			if err := r.send(k.kind, d, staff); err != nil {
+				log.Printf("Error en el recordatorio %s de la reserva %d: %v", k.kind, d.ReservationID, err)
+				failed++
+			}
+		}
+		if failed > 0 {
+			errs = append(errs, fmt.Errorf("%d de %d recordatorios %s no se enviaron", failed, len(due), k.kind))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send registra el recordatorio y sus notificaciones en una transacción
+func (r *ReservationReminders) send(kind string, d models.DueReminder, staff []int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	claimed, err := models.ClaimReminder(tx, d.ReservationID, kind)
+	if err != nil || !claimed {
+		return err
+	}
+
+	for _, notification := range reminderNotifications(kind, d, staff) {
+		if err := notification.Send(tx); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("Recordatorio %s enviado para la reserva %d", kind, d.ReservationID)
+	return nil
+}
+
+// reminderNotifications arma las notificaciones de un recordatorio
+func reminderNotifications(kind string, d models.DueReminder, staff []int) []models.Notification {
+	reservation := templates.ReservationData{
+		ReservationID: d.ReservationID,
+		Vehicle:       d.Vehicle,
+		LicensePlate:  d.LicensePlate,
+		Start:         d.StartTime,
+		End:           d.EndTime,
+	}
+
+	switch kind {
+	case models.ReminderPickup:
+		return []models.Notification{{UserID: d.UserID, Template: templates.PickupReminder, Data: reservation}}
+	case models.ReminderReturn:
+		end := d.EndTime
+		return []models.Notification{{UserID: d.UserID, Template: templates.ReturnReminder,
+			Data: templates.ReminderData{Vehicle: d.Vehicle, End: &end}}}
+	}
+
+	notifications := []models.Notification{{UserID: d.UserID, Template: templates.ReservationOverdue, Data: reservation}}
+	message := fmt.Sprintf("La reserva #%d del vehículo %s no fue devuelta; terminó el %s.",
+		d.ReservationID, d.LicensePlate, d.EndTime.Format("2006-01-02 15:04"))
+	for _, userID := range staff {
+		notifications = append(notifications, models.Notification{UserID: userID, Type: models.TypeOverdue,
+			Subject: "Reserva vencida", Message: message})
+	}
+	return notifications
+}
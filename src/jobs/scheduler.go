@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// SchedulerLockKey identifica el advisory lock de Postgres que elige la réplica líder
+const SchedulerLockKey int64 = 0x676f6c616e // "golan"
+
+// Locker decide si esta réplica puede ejecutar los trabajos programados
+type Locker interface {
+	Acquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
+
+// LeaderLock es un Locker basado en pg_try_advisory_lock. El lock es de sesión, así que se mantiene
+// una conexión dedicada mientras se es líder; si esa conexión se pierde, Postgres libera el lock y
+// otra réplica puede tomarlo.
+type LeaderLock struct {
+	DB   *sql.DB
+	Key  int64
+	conn *sql.Conn
+}
+
+// NewLeaderLock crea el lock de líder sobre db
+func NewLeaderLock(db *sql.DB, key int64) *LeaderLock {
+	return &LeaderLock{DB: db, Key: key}
+}
+
+// Acquire devuelve true si esta réplica tiene el lock, tomándolo si está libre
+func (l *LeaderLock) Acquire(ctx context.Context) (bool, error) {
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err == nil {
+			return true, nil
+		}
+		// La sesión que tenía el lock murió: el lock ya no es nuestro
+		l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.DB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.Key).Scan(&acquired); err != nil {
+		conn.Close()
+		return false, err
+	}
+	if !acquired {
+		conn.Close()
+		return false, nil
+	}
+	l.conn = conn
+	return true, nil
+}
+
+// Release suelta el lock si se tenía
+func (l *LeaderLock) Release(ctx context.Context) error {
+	if l.conn == nil {
+		return nil
+	}
+	defer func() {
+		l.conn.Close()
+		l.conn = nil
+	}()
+	_, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.Key)
+	return err
+}
+
+// NoLock es un Locker que siempre es líder: sirve con una sola réplica y en pruebas
+type NoLock struct{}
+
+func (NoLock) Acquire(ctx context.Context) (bool, error) { return true, nil }
+func (NoLock) Release(ctx context.Context) error         { return nil }
+
+// Task es un trabajo que se ejecuta cada Interval
+type Task struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context, now time.Time) error
+	next     time.Time
+}
+
+// Scheduler ejecuta tareas periódicas en la réplica que tiene el Locker. Cada Tick revisa qué tareas
+// vencieron; las réplicas que no son líderes solo vuelven a intentar tomar el lock.
+type Scheduler struct {
+	Clock Clock
+	Lock  Locker
+	Tick  time.Duration
+	tasks []*Task
+}
+
+// NewScheduler crea un scheduler con el lock de líder sobre db
+func NewScheduler(db *sql.DB, clock Clock) *Scheduler {
+	return &Scheduler{Clock: clock, Lock: NewLeaderLock(db, SchedulerLockKey), Tick: 15 * time.Second}
+}
+
+// Every registra una tarea; la primera ejecución ocurre en la primera revisión
+func (s *Scheduler) Every(name string, interval time.Duration, run func(ctx context.Context, now time.Time) error) {
+	s.tasks = append(s.tasks, &Task{Name: name, Interval: interval, Run: run})
+}
+
+// Start ejecuta el scheduler en segundo plano hasta que se cancele ctx
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		for {
+			if _, err := s.RunDue(ctx); err != nil {
+				log.Printf("Error en el scheduler: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				if err := s.Lock.Release(context.Background()); err != nil {
+					log.Printf("Error liberando el lock del scheduler: %v", err)
+				}
+				return
+			case <-s.Clock.After(s.Tick):
+			}
+		}
+	}()
+}
+
+// RunDue ejecuta las tareas vencidas si esta réplica es la líder y devuelve cuántas ejecutó. El error
+// de una tarea se registra sin detener las demás; la tarea se reintenta en su siguiente intervalo.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	leader, err := s.Lock.Acquire(ctx)
+	if err != nil || !leader {
+		return 0, err
+	}
+
+	now := s.Clock.Now()
+	ran := 0
+	for _, t := range s.tasks {
+		if now.Before(t.next) {
+			continue
+		}
+		if err := t.Run(ctx, now); err != nil {
+			log.Printf("Error en la tarea %s: %v", t.Name, err)
+		}
+		t.next = now.Add(t.Interval)
+		ran++
+	}
+	return ran, nil
+}
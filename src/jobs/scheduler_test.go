@@ -0,0 +1,345 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-auth-api/src/models"
+	"go-auth-api/src/testdb"
+	"sync"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+
+// recorder cuenta las ejecuciones de una tarea y la hora que recibió cada una
+type recorder struct {
+	mu   sync.Mutex
+	runs []time.Time
+	ran  chan time.Time
+}
+
+func newRecorder() *recorder {
+	return &recorder{ran: make(chan time.Time, 16)}
+}
+
+func (r *recorder) run(ctx context.Context, now time.Time) error {
+	r.mu.Lock()
+	r.runs = append(r.runs, now)
+	r.mu.Unlock()
+	select {
+	case r.ran <- now:
+	default: // Nadie espera las ejecuciones de esta prueba
+	}
+	return nil
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.runs)
+}
+
+// fakeLock es un Locker cuyo resultado fija la prueba
+type fakeLock struct {
+	mu       sync.Mutex
+	leader   bool
+	err      error
+	released bool
+}
+
+func (l *fakeLock) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.leader, l.err
+}
+
+func (l *fakeLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.released = true
+	return nil
+}
+
+func (l *fakeLock) set(leader bool, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.leader, l.err = leader, err
+}
+
+func TestSchedulerRunsTasksOncePerInterval(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	s := &Scheduler{Clock: clock, Lock: NoLock{}, Tick: 15 * time.Second}
+	minutely, hourly := newRecorder(), newRecorder()
+	s.Every("minutely", time.Minute, minutely.run)
+	s.Every("hourly", time.Hour, hourly.run)
+
+	// Dos horas revisando cada Tick: cada tarea corre en la primera revisión y luego una vez por intervalo
+	ctx := context.Background()
+	for elapsed := time.Duration(0); elapsed < 2*time.Hour; elapsed += s.Tick {
+		if _, err := s.RunDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(s.Tick)
+	}
+	if got := minutely.count(); got != 120 {
+		t.Errorf("la tarea de cada minuto corrió %d veces en dos horas, se esperaban 120", got)
+	}
+	if got := hourly.count(); got != 2 {
+		t.Errorf("la tarea de cada hora corrió %d veces en dos horas, se esperaban 2", got)
+	}
+	for i, now := range minutely.runs {
+		if want := epoch.Add(time.Duration(i) * time.Minute); !now.Equal(want) {
+			t.Fatalf("ejecución %d con hora %s, se esperaba %s", i, now, want)
+		}
+	}
+}
+
+func TestSchedulerRunDueSkipsTasksNotDue(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	s := &Scheduler{Clock: clock, Lock: NoLock{}, Tick: 15 * time.Second}
+	task := newRecorder()
+	s.Every("tarea", time.Minute, task.run)
+
+	steps := []struct {
+		advance time.Duration
+		want    int
+	}{
+		{0, 1},                // Primera revisión
+		{0, 0},                // Misma hora: ya corrió
+		{59 * time.Second, 0}, // Aún no vence
+		{time.Second, 1},      // Vence justo al cumplirse el intervalo
+		{10 * time.Minute, 1}, // Tras una pausa larga corre una sola vez, no una por intervalo perdido
+		{time.Minute, 1},
+	}
+	for i, step := range steps {
+		clock.Advance(step.advance)
+		ran, err := s.RunDue(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ran != step.want {
+			t.Errorf("paso %d: RunDue ejecutó %d tareas, se esperaban %d", i, ran, step.want)
+		}
+	}
+}
+
+func TestSchedulerNonLeaderDoesNotRun(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	lock := &fakeLock{}
+	s := &Scheduler{Clock: clock, Lock: lock, Tick: 15 * time.Second}
+	task := newRecorder()
+	s.Every("tarea", time.Minute, task.run)
+
+	for i := 0; i < 10; i++ {
+		if ran, err := s.RunDue(context.Background()); err != nil || ran != 0 {
+			t.Fatalf("réplica no líder: RunDue = %d, %v; se esperaba 0, nil", ran, err)
+		}
+		clock.Advance(time.Minute)
+	}
+
+	lock.set(false, errors.New("conexión perdida"))
+	if ran, err := s.RunDue(context.Background()); err == nil || ran != 0 {
+		t.Errorf("error del lock: RunDue = %d, %v; se esperaba 0 y el error", ran, err)
+	}
+	if got := task.count(); got != 0 {
+		t.Fatalf("la tarea corrió %d veces sin ser líder", got)
+	}
+
+	// Al tomar el liderazgo la tarea corre en la siguiente revisión
+	lock.set(true, nil)
+	if ran, err := s.RunDue(context.Background()); err != nil || ran != 1 {
+		t.Errorf("réplica líder: RunDue = %d, %v; se esperaba 1, nil", ran, err)
+	}
+}
+
+// waitForTimer espera a que alguien quede esperando en clock.After, para avanzar el reloj sin carreras
+func waitForTimer(t *testing.T, clock *FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		clock.mu.Lock()
+		waiting := len(clock.timers) > 0
+		clock.mu.Unlock()
+		if waiting {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("el scheduler no quedó esperando al reloj")
+}
+
+func TestSchedulerStart(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	lock := &fakeLock{leader: true}
+	s := &Scheduler{Clock: clock, Lock: lock, Tick: 15 * time.Second}
+	task := newRecorder()
+	s.Every("tarea", time.Minute, task.run)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+
+	if now := <-task.ran; !now.Equal(epoch) {
+		t.Fatalf("primera ejecución con hora %s, se esperaba %s", now, epoch)
+	}
+	// Tres ticks no completan el intervalo; el cuarto sí
+	for i := 0; i < 4; i++ {
+		waitForTimer(t, clock)
+		clock.Advance(s.Tick)
+	}
+	select {
+	case now := <-task.ran:
+		if want := epoch.Add(time.Minute); !now.Equal(want) {
+			t.Errorf("segunda ejecución con hora %s, se esperaba %s", now, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("la tarea no volvió a correr al cumplirse el intervalo")
+	}
+	if got := task.count(); got != 2 {
+		t.Errorf("la tarea corrió %d veces en un minuto, se esperaban 2", got)
+	}
+
+	waitForTimer(t, clock)
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for {
+		lock.mu.Lock()
+		released := lock.released
+		lock.mu.Unlock()
+		if released {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("el scheduler no liberó el lock al cancelarse")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// El recordatorio queda registrado con ClaimReminder en la transacción que lo envía, así que las
+// siguientes ejecuciones del scheduler no lo repiten
+func TestReservationRemindersNotResent(t *testing.T) {
+	db := testdb.Open(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	userID, reservationID := seedReservation(t, db, now.Add(time.Hour), now.Add(3*time.Hour))
+
+	clock := NewFakeClock(now)
+	s := &Scheduler{Clock: clock, Lock: NoLock{}, Tick: 15 * time.Second}
+	s.Every("recordatorios", ReservationReminderInterval, NewReservationReminders(db).Run)
+
+	for i := 0; i < 5; i++ {
+		if _, err := s.RunDue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(ReservationReminderInterval)
+	}
+	if got := countNotifications(t, db, userID); got != 1 {
+		t.Fatalf("%d notificaciones tras cinco ejecuciones, se esperaba un solo recordatorio de recogida", got)
+	}
+
+	// Un recordatorio que otra réplica ya reclamó tampoco se envía
+	if claimed, err := models.ClaimReminder(db, reservationID, models.ReminderReturn); err != nil || !claimed {
+		t.Fatalf("ClaimReminder = %t, %v", claimed, err)
+	}
+	clock.Set(now.Add(3*time.Hour - 10*time.Minute)) // Dentro del aviso de devolución
+	if _, err := s.RunDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := countNotifications(t, db, userID); got != 1 {
+		t.Errorf("%d notificaciones, el recordatorio de devolución ya reclamado no debía enviarse", got)
+	}
+}
+
+// Un recordatorio que falla no impide enviar los demás, y se reintenta en la siguiente ejecución
+func TestReservationRemindersContinueAfterFailure(t *testing.T) {
+	db := testdb.Open(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	failingID, reservationID := seedReservation(t, db, now.Add(time.Hour), now.Add(3*time.Hour))
+
+	// Otro cliente con otro vehículo cuya recogida también vence
+	other := models.User{Username: "luis", Password: "secreto123", Email: "luis@example.com"}
+	if err := other.Register(db); err != nil {
+		t.Fatal(err)
+	}
+	_, err := db.Exec(`WITH v AS (INSERT INTO vehicles (brand_id, model_id, license_plate, latitude, longitude, status)
+                                  SELECT brand_id, model_id, 'B654321', 18.47, -69.9, 'available'
+                                  FROM vehicles WHERE id = (SELECT vehicle_id FROM reservations WHERE id = $1)
+                                  RETURNING id)
+                       INSERT INTO reservations (user_id, vehicle_id, start_time, end_time)
+                       SELECT $2, v.id, $3, $4 FROM v`,
+		reservationID, other.ID, now.Add(time.Hour), now.Add(3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Las notificaciones del primer cliente fallan hasta que se quite el trigger
+	_, err = db.Exec(fmt.Sprintf(`CREATE FUNCTION fail_notification() RETURNS trigger AS $$
+                                  BEGIN
+                                      IF NEW.user_id = %d THEN RAISE EXCEPTION 'fallo de prueba'; END IF;
+                                      RETURN NEW;
+                                  END $$ LANGUAGE plpgsql;
+                                  CREATE TRIGGER fail_notification BEFORE INSERT ON notifications
+                                  FOR EACH ROW EXECUTE FUNCTION fail_notification()`, failingID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reminders := NewReservationReminders(db)
+	if err := reminders.Run(context.Background(), now); err == nil {
+		t.Error("Run no devolvió el fallo del recordatorio")
+	}
+	if got := countNotifications(t, db, other.ID); got != 1 {
+		t.Errorf("%d notificaciones para el segundo cliente, se esperaba su recordatorio", got)
+	}
+	if got := countNotifications(t, db, failingID); got != 0 {
+		t.Fatalf("%d notificaciones para el cliente cuyo recordatorio falló", got)
+	}
+
+	if _, err := db.Exec(`DROP TRIGGER fail_notification ON notifications`); err != nil {
+		t.Fatal(err)
+	}
+	if err := reminders.Run(context.Background(), now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if got := countNotifications(t, db, failingID); got != 1 {
+		t.Errorf("%d notificaciones tras el reintento, se esperaba el recordatorio pendiente", got)
+	}
+	if got := countNotifications(t, db, other.ID); got != 1 {
+		t.Errorf("%d notificaciones para el segundo cliente, su recordatorio no debía repetirse", got)
+	}
+}
+
+// seedReservation crea un cliente con una reserva activa y devuelve sus ids
+func seedReservation(t *testing.T, db *sql.DB, start, end time.Time) (userID, reservationID int) {
+	t.Helper()
+	user := models.User{Username: "ana", Password: "secreto123", Email: "ana@example.com"}
+	if err := user.Register(db); err != nil {
+		t.Fatal(err)
+	}
+	var vehicleID int
+	err := db.QueryRow(`WITH b AS (INSERT INTO brand (name) VALUES ('Toyota') RETURNING id),
+                             m AS (INSERT INTO model (name) VALUES ('Corolla') RETURNING id)
+                        INSERT INTO vehicles (brand_id, model_id, license_plate, latitude, longitude, status)
+                        SELECT b.id, m.id, 'A123456', 18.47, -69.9, 'available' FROM b, m
+                        RETURNING id`).Scan(&vehicleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.QueryRow(`INSERT INTO reservations (user_id, vehicle_id, start_time, end_time)
+                       VALUES ($1, $2, $3, $4) RETURNING id`, user.ID, vehicleID, start, end).Scan(&reservationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.ID, reservationID
+}
+
+func countNotifications(t *testing.T, db *sql.DB, userID int) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = $1`, userID).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// Capture es un Transport que guarda los mensajes en memoria en lugar de enviarlos, para que las
+// pruebas comprueben qué se envió
+type Capture struct {
+	Err error // Si no es nil, Send lo devuelve sin guardar el mensaje
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+// NewCapture crea un Capture vacío
+func NewCapture() *Capture {
+	return &Capture{}
+}
+
+// Send guarda el mensaje
+func (c *Capture) Send(ctx context.Context, msg Message) error {
+	if c.Err != nil {
+		return c.Err
+	}
+	c.mu.Lock()
+	c.sent = append(c.sent, msg)
+	c.mu.Unlock()
+	return nil
+}
+
+// Close no hace nada
+func (c *Capture) Close() error { return nil }
+
+// Sent devuelve una copia de los mensajes guardados
+func (c *Capture) Sent() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message(nil), c.sent...)
+}
+
+// Last devuelve el último mensaje guardado
+func (c *Capture) Last() (Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.sent) == 0 {
+		return Message{}, false
+	}
+	return c.sent[len(c.sent)-1], true
+}
+
+// Reset olvida los mensajes guardados
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	c.sent = nil
+	c.mu.Unlock()
+}
+
+// Render devuelve el mensaje tal como se enviaría por SMTP, con sus partes MIME
+func Render(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := buildMessage(msg).WriteTo(&buf)
+	return buf.Bytes(), err
+}
@@ -0,0 +1,156 @@
+// Package mail envía correo electrónico. Un Service se construye a partir de la configuración y
+// entrega los mensajes por un Transport: SMTP con una conexión persistente en producción, o Capture,
+// que los guarda en memoria, en pruebas y entornos locales.
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	netmail "net/mail"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAddress indica un remitente o destinatario mal escrito; reintentar no lo corrige
+var ErrInvalidAddress = errors.New("dirección de correo inválida")
+
+// Config es la configuración del servidor SMTP y del remitente
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string // Remitente por defecto, p. ej. "Golan Car <no-reply@golancar.com>"
+	ReplyTo  string // Dirección de respuesta por defecto; opcional
+
+	// ImplicitTLS abre la conexión ya cifrada (SMTPS, puerto 465). Sin ella se usa STARTTLS cuando
+	// el servidor lo ofrece.
+	ImplicitTLS bool
+	// InsecureSkipVerify acepta certificados no válidos. Solo para servidores de prueba locales.
+	InsecureSkipVerify bool
+	// IdleTimeout es cuánto se mantiene abierta la conexión sin enviar nada
+	IdleTimeout time.Duration
+}
+
+// Validate comprueba que la configuración permite enviar correos
+func (c Config) Validate() error {
+	if c.Host == "" {
+		return errors.New("falta el servidor SMTP")
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("puerto SMTP inválido: %d", c.Port)
+	}
+	if _, err := netmail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("remitente inválido %q: %v", c.From, err)
+	}
+	if c.ReplyTo != "" {
+		if _, err := netmail.ParseAddress(c.ReplyTo); err != nil {
+			return fmt.Errorf("dirección de respuesta inválida %q: %v", c.ReplyTo, err)
+		}
+	}
+	return nil
+}
+
+// Attachment es un archivo adjunto
+type Attachment struct {
+	Filename    string
+	ContentType string // Si falta se deduce de la extensión
+	Data        []byte
+}
+
+// Message es un correo. Con HTML y Text se envía como multipart/alternative; From y ReplyTo toman
+// los valores del Service si se omiten.
+type Message struct {
+	From        string
+	To          []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     string
+	Subject     string
+	HTML        string
+	Text        string
+	Headers     map[string]string // Cabeceras adicionales, p. ej. List-Unsubscribe
+	Attachments []Attachment
+}
+
+// Recipients devuelve los destinatarios del sobre: To, Cc y Bcc
+func (m Message) Recipients() []string {
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	return append(recipients, m.Bcc...)
+}
+
+// validate comprueba las direcciones y que el mensaje tenga contenido
+func (m Message) validate() error {
+	if len(m.To)+len(m.Cc)+len(m.Bcc) == 0 {
+		return fmt.Errorf("%w: el correo no tiene destinatarios", ErrInvalidAddress)
+	}
+	addresses := append([]string{m.From}, m.Recipients()...)
+	if m.ReplyTo != "" {
+		addresses = append(addresses, m.ReplyTo)
+	}
+	for _, address := range addresses {
+		if _, err := netmail.ParseAddress(address); err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidAddress, address)
+		}
+	}
+	if strings.TrimSpace(m.HTML) == "" && strings.TrimSpace(m.Text) == "" {
+		return errors.New("el correo no tiene contenido")
+	}
+	for _, a := range m.Attachments {
+		if strings.TrimSpace(a.Filename) == "" {
+			return errors.New("el adjunto no tiene nombre")
+		}
+	}
+	return nil
+}
+
+// Transport entrega un mensaje ya completo y validado
+type Transport interface {
+	Send(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// Service envía correos con el remitente y la dirección de respuesta configurados
+type Service struct {
+	from      string
+	replyTo   string
+	transport Transport
+}
+
+// New crea un Service que envía por SMTP
+func New(cfg Config) (*Service, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return NewService(cfg.From, cfg.ReplyTo, newSMTPTransport(cfg)), nil
+}
+
+// NewService crea un Service sobre cualquier Transport, p. ej. Capture
+func NewService(from, replyTo string, transport Transport) *Service {
+	return &Service{from: from, replyTo: replyTo, transport: transport}
+}
+
+// Send completa el remitente y la dirección de respuesta, valida el mensaje y lo entrega
+func (s *Service) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = s.from
+	}
+	if msg.ReplyTo == "" {
+		msg.ReplyTo = s.replyTo
+	}
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.transport.Send(ctx, msg)
+}
+
+// Close cierra la conexión del transporte, si la hay
+func (s *Service) Close() error {
+	return s.transport.Close()
+}
@@ -0,0 +1,244 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"strings"
+	"testing"
+)
+
+// part es una hoja del árbol MIME de un correo renderizado
+type part struct {
+	mediaType string
+	params    map[string]string
+	header    map[string][]string
+	body      []byte
+}
+
+// parse renderiza msg y devuelve sus cabeceras, el tipo de la raíz y las hojas en orden
+func parse(t *testing.T, msg Message) (netmail.Header, string, []part) {
+	t.Helper()
+	raw, err := Render(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := netmail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("el correo renderizado no es válido: %v\n%s", err, raw)
+	}
+	mediaType, _, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.Header, mediaType, leaves(t, m.Header, m.Body)
+}
+
+func leaves(t *testing.T, header map[string][]string, body io.Reader) []part {
+	t.Helper()
+	h := netmail.Header(header)
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		var parts []part
+		r := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := r.NextRawPart()
+			if err == io.EOF {
+				return parts
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts = append(parts, leaves(t, p.Header, p)...)
+		}
+	}
+	switch strings.ToLower(h.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []part{{mediaType: mediaType, params: params, header: header, body: data}}
+}
+
+func TestRenderAlternative(t *testing.T) {
+	_, root, parts := parse(t, Message{
+		From: "no-reply@golancar.com", To: []string{"ana@example.com"}, Subject: "Reserva",
+		Text: "Tu reserva está confirmada", HTML: "<p>Tu reserva está confirmada</p>",
+	})
+	if root != "multipart/alternative" {
+		t.Fatalf("Content-Type = %s, se esperaba multipart/alternative", root)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("%d partes, se esperaban 2", len(parts))
+	}
+	// La última alternativa es la preferida, así que el HTML va después del texto
+	want := []struct{ mediaType, body string }{
+		{"text/plain", "Tu reserva está confirmada"},
+		{"text/html", "<p>Tu reserva está confirmada</p>"},
+	}
+	for i, w := range want {
+		if parts[i].mediaType != w.mediaType || string(parts[i].body) != w.body {
+			t.Errorf("parte %d = %s %q, se esperaba %s %q", i, parts[i].mediaType, parts[i].body, w.mediaType, w.body)
+		}
+		if parts[i].params["charset"] != "UTF-8" {
+			t.Errorf("parte %d: charset %q, se esperaba UTF-8", i, parts[i].params["charset"])
+		}
+	}
+}
+
+func TestRenderSingleBody(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		want string
+	}{
+		{"solo HTML", Message{HTML: "<p>Hola</p>"}, "text/html"},
+		{"solo texto", Message{Text: "Hola"}, "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.msg.From, tt.msg.To = "no-reply@golancar.com", []string{"ana@example.com"}
+			_, root, parts := parse(t, tt.msg)
+			if root != tt.want || len(parts) != 1 {
+				t.Errorf("Content-Type = %s con %d partes, se esperaba %s sin multipart", root, len(parts), tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderAttachments(t *testing.T) {
+	csv := []byte("fecha,importe\n2026-10-01,25.00\n")
+	png := []byte("\x89PNG\r\n\x1a\n")
+	_, root, parts := parse(t, Message{
+		From: "no-reply@golancar.com", To: []string{"ana@example.com"}, Subject: "Factura",
+		Text: "Adjuntamos tu factura", HTML: "<p>Adjuntamos tu factura</p>",
+		Attachments: []Attachment{
+			{Filename: "viajes.csv", ContentType: "text/csv", Data: csv},
+			{Filename: "recorrido.png", Data: png},
+		},
+	})
+	if root != "multipart/mixed" {
+		t.Fatalf("Content-Type = %s, se esperaba multipart/mixed", root)
+	}
+	if len(parts) != 4 {
+		t.Fatalf("%d partes, se esperaban 4 (texto, HTML y dos adjuntos)", len(parts))
+	}
+	if parts[0].mediaType != "text/plain" || parts[1].mediaType != "text/html" {
+		t.Errorf("cuerpo = %s, %s; se esperaba text/plain y text/html", parts[0].mediaType, parts[1].mediaType)
+	}
+
+	want := []struct {
+		filename, mediaType string
+		data                []byte
+	}{
+		{"viajes.csv", "text/csv", csv},
+		{"recorrido.png", "image/png", png}, // Sin ContentType se deduce de la extensión
+	}
+	for i, w := range want {
+		p := parts[2+i]
+		disposition, params, err := mime.ParseMediaType(netmail.Header(p.header).Get("Content-Disposition"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if disposition != "attachment" || params["filename"] != w.filename {
+			t.Errorf("adjunto %d: Content-Disposition %s filename=%q, se esperaba attachment filename=%q",
+				i, disposition, params["filename"], w.filename)
+		}
+		if p.mediaType != w.mediaType {
+			t.Errorf("adjunto %d: Content-Type %s, se esperaba %s", i, p.mediaType, w.mediaType)
+		}
+		if !bytes.Equal(p.body, w.data) {
+			t.Errorf("adjunto %d: contenido %q, se esperaba %q", i, p.body, w.data)
+		}
+	}
+}
+
+func TestServiceSendHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		replyTo     string // Del Service
+		msg         Message
+		wantFrom    string
+		wantReplyTo string
+	}{
+		{"valores del servicio", "soporte@golancar.com",
+			Message{}, "no-reply@golancar.com", "soporte@golancar.com"},
+		{"el mensaje manda", "soporte@golancar.com",
+			Message{From: "flota@golancar.com", ReplyTo: "flota@golancar.com"}, "flota@golancar.com", "flota@golancar.com"},
+		{"sin dirección de respuesta", "", Message{}, "no-reply@golancar.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capture := NewCapture()
+			service := NewService("no-reply@golancar.com", tt.replyTo, capture)
+			msg := tt.msg
+			msg.To, msg.Cc, msg.Bcc = []string{"ana@example.com"}, []string{"luis@example.com"}, []string{"auditoria@golancar.com"}
+			msg.Subject, msg.Text = "Reserva", "Hola"
+			msg.Headers = map[string]string{"List-Unsubscribe": "<https://golancar.com/baja>"}
+			if err := service.Send(context.Background(), msg); err != nil {
+				t.Fatal(err)
+			}
+			sent, ok := capture.Last()
+			if !ok {
+				t.Fatal("Capture no guardó el mensaje")
+			}
+			if got := sent.Recipients(); len(got) != 3 {
+				t.Errorf("destinatarios del sobre = %v, se esperaban To, Cc y Bcc", got)
+			}
+
+			header, _, _ := parse(t, sent)
+			checks := map[string]string{
+				"From":             tt.wantFrom,
+				"Reply-To":         tt.wantReplyTo,
+				"To":               "ana@example.com",
+				"Cc":               "luis@example.com",
+				"Bcc":              "", // Solo va en el sobre; en las cabeceras lo verían todos
+				"List-Unsubscribe": "<https://golancar.com/baja>",
+			}
+			for name, want := range checks {
+				if got := header.Get(name); got != want {
+					t.Errorf("%s = %q, se esperaba %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceSendRejectsInvalidMessages(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		want error
+	}{
+		{"destinatario inválido", Message{To: []string{"ana@"}, Text: "Hola"}, ErrInvalidAddress},
+		{"respuesta inválida", Message{To: []string{"ana@example.com"}, ReplyTo: "soporte", Text: "Hola"}, ErrInvalidAddress},
+		{"sin destinatarios", Message{Text: "Hola"}, ErrInvalidAddress},
+		{"sin contenido", Message{To: []string{"ana@example.com"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capture := NewCapture()
+			err := NewService("no-reply@golancar.com", "", capture).Send(context.Background(), tt.msg)
+			if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("Send() = %v, se esperaba %v", err, tt.want)
+			}
+			if len(capture.Sent()) != 0 {
+				t.Error("un mensaje inválido llegó al transporte")
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/textproto"
+	"sync"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// defaultIdleTimeout es cuánto se mantiene abierta una conexión sin uso si la configuración no lo indica
+const defaultIdleTimeout = 30 * time.Second
+
+// smtpTransport mantiene una conexión abierta con el servidor y la reutiliza entre envíos; se
+// cierra tras IdleTimeout sin uso y se vuelve a abrir con el siguiente mensaje
+type smtpTransport struct {
+	dialer *gomail.Dialer
+	idle   time.Duration
+
+	mu    sync.Mutex
+	conn  gomail.SendCloser
+	timer *time.Timer
+}
+
+func newSMTPTransport(cfg Config) *smtpTransport {
+	dialer := gomail.NewDialer(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+	dialer.SSL = cfg.ImplicitTLS || cfg.Port == 465
+	dialer.TLSConfig = &tls.Config{
+		ServerName:         cfg.Host,
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	idle := cfg.IdleTimeout
+	if idle <= 0 {
+		idle = defaultIdleTimeout
+	}
+	return &smtpTransport{dialer: dialer, idle: idle}
+}
+
+// Send entrega el mensaje. Si la conexión reutilizada resultó estar caída (el servidor la cerró por
+// inactividad) se reintenta una vez con una nueva; las respuestas del servidor no se reintentan.
+func (t *smtpTransport) Send(ctx context.Context, msg Message) error {
+	m := buildMessage(msg)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	reused := t.conn != nil
+	err := t.send(msg.From, msg.Recipients(), m)
+	var reply *textproto.Error
+	if err != nil && reused && !errors.As(err, &reply) && ctx.Err() == nil {
+		err = t.send(msg.From, msg.Recipients(), m)
+	}
+	return err
+}
+
+// send usa la conexión abierta o abre una; tras un error la descarta, porque la sesión SMTP puede
+// haber quedado a mitad de una transacción
+func (t *smtpTransport) send(from string, to []string, m *gomail.Message) error {
+	if t.conn == nil {
+		conn, err := t.dialer.Dial()
+		if err != nil {
+			return err
+		}
+		t.conn = conn
+	}
+	if err := t.conn.Send(from, to, m); err != nil {
+		t.conn.Close()
+		t.conn = nil
+		return err
+	}
+
+	if t.timer == nil {
+		t.timer = time.AfterFunc(t.idle, t.closeIdle)
+	} else {
+		t.timer.Reset(t.idle)
+	}
+	return nil
+}
+
+func (t *smtpTransport) closeIdle() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+}
+
+// Close cierra la conexión abierta
+func (t *smtpTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
+
+// buildMessage arma el MIME del mensaje: texto y HTML como alternativas, y los adjuntos. Bcc no se
+// escribe en las cabeceras; solo forma parte del sobre.
+func buildMessage(msg Message) *gomail.Message {
+	m := gomail.NewMessage()
+	m.SetHeader("From", msg.From)
+	if len(msg.To) > 0 {
+		m.SetHeader("To", msg.To...)
+	}
+	if len(msg.Cc) > 0 {
+		m.SetHeader("Cc", msg.Cc...)
+	}
+	if msg.ReplyTo != "" {
+		m.SetHeader("Reply-To", msg.ReplyTo)
+	}
+	m.SetHeader("Subject", msg.Subject)
+	for name, value := range msg.Headers {
+		m.SetHeader(name, value)
+	}
+
+	switch {
+	case msg.Text != "" && msg.HTML != "":
+		m.SetBody("text/plain", msg.Text)
+		m.AddAlternative("text/html", msg.HTML)
+	case msg.HTML != "":
+		m.SetBody("text/html", msg.HTML)
+	default:
+		m.SetBody("text/plain", msg.Text)
+	}
+
+	for _, a := range msg.Attachments {
+		data := a.Data
+		settings := []gomail.FileSetting{
+			gomail.Rename(a.Filename),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			}),
+		}
+		if a.ContentType != "" {
+			settings = append(settings, gomail.SetHeader(map[string][]string{"Content-Type": {a.ContentType}}))
+		}
+		m.Attach(a.Filename, settings...)
+	}
+	return m
+}
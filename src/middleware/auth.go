@@ -2,14 +2,11 @@ package middlewares
 
 import (
 	"go-auth-api/src/models"
-	"os"
 
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
 func AuthMiddleware() gin.HandlerFunc {
@@ -31,23 +28,34 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
-		claims := &models.Claims{}
-
-		// Parsear el token JWT usando la clave secreta
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		// Verificar si el token es válido
-		if err != nil || !token.Valid {
+		// Verificar el token con la clave configurada al arrancar
+		claims, err := models.ParseClaims(parts[1])
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
 			c.Abort()
 			return
 		}
 
-		// Guardar el nombre de usuario en el contexto para que esté disponible en los controladores
+		// Guardar el usuario en el contexto para que esté disponible en los controladores
 		c.Set("username", claims.Username)
+		c.Set("user_id", claims.UserID)
+		c.Set("role", claims.Role)
 		c.Next()
 	}
 }
+
+// RequireRole permite el acceso solo a los usuarios autenticados con alguno de los roles indicados.
+// Debe usarse después de AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "No tiene permisos para esta operación"})
+		c.Abort()
+	}
+}
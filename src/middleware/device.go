@@ -0,0 +1,154 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"go-auth-api/src/config"
+	"go-auth-api/src/models"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cabeceras de autenticación de dispositivos con clave HMAC
+const (
+	DeviceIDHeader        = "X-Device-ID"
+	DeviceTimestampHeader = "X-Device-Timestamp"
+	DeviceNonceHeader     = "X-Device-Nonce"
+	DeviceSignatureHeader = "X-Device-Signature"
+)
+
+const (
+	deviceSignatureWindow = 5 * time.Minute
+	maxDeviceBodyBytes    = 2 << 20
+	minDeviceNonceLength  = 16
+	maxDeviceNonceLength  = 64
+)
+
+// SignDeviceRequest calcula la firma HMAC-SHA256 (hex) de una petición de dispositivo sobre
+//
+//	MÉTODO \n RUTA?QUERY \n TIMESTAMP \n NONCE \n SHA256_HEX(CUERPO)
+//
+// El nonce es un valor aleatorio distinto en cada petición (de 16 a 64 caracteres alfanuméricos,
+// '-' o '_'). El simulador y las unidades reales deben firmar exactamente esta cadena.
+func SignDeviceRequest(secret, method, requestURI, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + requestURI + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(bodyHash[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func validNonce(nonce string) bool {
+	if len(nonce) < minDeviceNonceLength || len(nonce) > maxDeviceNonceLength {
+		return false
+	}
+	for _, c := range nonce {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDeviceTimestamp exige un timestamp Unix dentro de deviceSignatureWindow de now, en ambos sentidos
+func checkDeviceTimestamp(timestamp string, now time.Time) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("falta " + DeviceTimestampHeader)
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > deviceSignatureWindow || skew < -deviceSignatureWindow {
+		return errors.New("timestamp fuera de la ventana permitida")
+	}
+	return nil
+}
+
+// DeviceAuthMiddleware autentica las unidades telemáticas, separado de AuthMiddleware de los clientes.
+// Acepta un certificado de cliente TLS registrado o una petición firmada con la clave HMAC del
+// dispositivo. Deja en el contexto "device_id" y "device_vehicle_id".
+func DeviceAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		device, err := authenticateDevice(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Dispositivo no autenticado", "details": err.Error()})
+			c.Abort()
+			return
+		}
+
+		if err := models.TouchDevice(config.DB, device.ID); err != nil {
+			log.Printf("No se pudo registrar la actividad del dispositivo %d: %v", device.ID, err)
+		}
+		c.Set("device_id", device.ID)
+		c.Set("device_vehicle_id", device.VehicleID)
+		c.Next()
+	}
+}
+
+func authenticateDevice(c *gin.Context) (models.Device, error) {
+	// Certificado de cliente verificado por el servidor TLS
+	if tlsState := c.Request.TLS; tlsState != nil && len(tlsState.PeerCertificates) > 0 {
+		sum := sha256.Sum256(tlsState.PeerCertificates[0].Raw)
+		device, err := models.GetActiveDeviceByFingerprint(config.DB, hex.EncodeToString(sum[:]))
+		if err == nil {
+			return device, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return device, errors.New("error verificando el certificado")
+		}
+		// Un certificado desconocido no bloquea la autenticación por HMAC
+	}
+
+	var device models.Device
+	id, err := strconv.Atoi(c.GetHeader(DeviceIDHeader))
+	if err != nil {
+		return device, errors.New("falta " + DeviceIDHeader)
+	}
+	timestamp := c.GetHeader(DeviceTimestampHeader)
+	now := time.Now()
+	if err := checkDeviceTimestamp(timestamp, now); err != nil {
+		return device, err
+	}
+	nonce := c.GetHeader(DeviceNonceHeader)
+	if !validNonce(nonce) {
+		return device, errors.New("falta " + DeviceNonceHeader + " o no es válido")
+	}
+	signature, err := hex.DecodeString(c.GetHeader(DeviceSignatureHeader))
+	if err != nil || len(signature) == 0 {
+		return device, errors.New("falta " + DeviceSignatureHeader)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxDeviceBodyBytes+1))
+	if err != nil || len(body) > maxDeviceBodyBytes {
+		return device, errors.New("cuerpo inválido")
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	device, err = models.GetDevice(config.DB, id)
+	if err != nil || device.Status != "active" || device.AuthType != models.DeviceAuthHMAC || device.Secret == "" {
+		return device, errors.New("credenciales inválidas")
+	}
+
+	expected, _ := hex.DecodeString(SignDeviceRequest(device.Secret, c.Request.Method, c.Request.URL.RequestURI(), timestamp, nonce, body))
+	if !hmac.Equal(signature, expected) {
+		return device, errors.New("credenciales inválidas")
+	}
+	// El nonce firmado identifica la petición. Se guarda por dispositivo en la base de datos, así que
+	// una petición repetida contra otra réplica o tras un reinicio también se rechaza. Vence cuando
+	// cubre el desfase permitido del timestamp en ambos sentidos.
+	first, err := models.UseDeviceNonce(config.DB, device.ID, nonce, now, now.Add(2*deviceSignatureWindow))
+	if err != nil {
+		log.Printf("No se pudo registrar el nonce del dispositivo %d: %v", device.ID, err)
+		return device, errors.New("error verificando la petición")
+	}
+	if !first {
+		return device, errors.New("petición repetida")
+	}
+	return device, nil
+}
@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignDeviceRequest(t *testing.T) {
+	// Firmas calculadas aparte con HMAC-SHA256 sobre la cadena documentada en SignDeviceRequest
+	tests := []struct {
+		name, method, uri string
+		body              string
+		want              string
+	}{
+		{"con cuerpo y query", "POST", "/api/device/status?x=1", `{"status":"available"}`,
+			"9259f0d33e24b4c5c2e265e9b298752a5dc9a6340b4a1542087dfff329023745"},
+		{"sin cuerpo", "GET", "/api/device/commands", "",
+			"fc6371722b04a7b97ae3218fe573c65c2f608f0b1280eb5eede119749966db65"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SignDeviceRequest("secreto-de-prueba", tt.method, tt.uri, "1760860800", "abcdef0123456789", []byte(tt.body))
+			if got != tt.want {
+				t.Errorf("SignDeviceRequest() = %s, se esperaba %s", got, tt.want)
+			}
+		})
+	}
+
+	// Cualquier cambio en una de las partes firmadas cambia la firma
+	base := SignDeviceRequest("secreto-de-prueba", "GET", "/api/device/commands", "1760860800", "abcdef0123456789", nil)
+	variants := map[string]string{
+		"clave":     SignDeviceRequest("otro-secreto", "GET", "/api/device/commands", "1760860800", "abcdef0123456789", nil),
+		"método":    SignDeviceRequest("secreto-de-prueba", "POST", "/api/device/commands", "1760860800", "abcdef0123456789", nil),
+		"ruta":      SignDeviceRequest("secreto-de-prueba", "GET", "/api/device/commands?wait=30", "1760860800", "abcdef0123456789", nil),
+		"timestamp": SignDeviceRequest("secreto-de-prueba", "GET", "/api/device/commands", "1760860801", "abcdef0123456789", nil),
+		"nonce":     SignDeviceRequest("secreto-de-prueba", "GET", "/api/device/commands", "1760860800", "abcdef0123456780", nil),
+		"cuerpo":    SignDeviceRequest("secreto-de-prueba", "GET", "/api/device/commands", "1760860800", "abcdef0123456789", []byte("{}")),
+	}
+	for part, sig := range variants {
+		if sig == base {
+			t.Errorf("cambiar %s no cambió la firma", part)
+		}
+	}
+}
+
+func TestValidNonce(t *testing.T) {
+	tests := []struct {
+		nonce string
+		want  bool
+	}{
+		{"abcdef0123456789", true},
+		{"ABC-def_0123456789", true},
+		{strings.Repeat("a", 64), true},
+		{"", false},
+		{"abcdef012345678", false}, // 15 caracteres
+		{strings.Repeat("a", 65), false},
+		{"abcdef01234567 9", false},
+		{"abcdef0123456789/", false},
+		{"abcdef0123456789ñ", false},
+		{"abcdef0123456789\n", false},
+	}
+	for _, tt := range tests {
+		if got := validNonce(tt.nonce); got != tt.want {
+			t.Errorf("validNonce(%q) = %t, se esperaba %t", tt.nonce, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDeviceTimestamp(t *testing.T) {
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	unix := func(d time.Duration) string { return strconv.FormatInt(now.Add(d).Unix(), 10) }
+	tests := []struct {
+		name      string
+		timestamp string
+		ok        bool
+	}{
+		{"ahora", unix(0), true},
+		{"en el límite del pasado", unix(-deviceSignatureWindow), true},
+		{"en el límite del futuro", unix(deviceSignatureWindow), true},
+		{"demasiado antiguo", unix(-deviceSignatureWindow - time.Second), false},
+		{"demasiado adelantado", unix(deviceSignatureWindow + time.Second), false},
+		{"vacío", "", false},
+		{"no numérico", "2026-10-19T08:00:00Z", false},
+		{"en milisegundos", strconv.FormatInt(now.UnixMilli(), 10), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkDeviceTimestamp(tt.timestamp, now); (err == nil) != tt.ok {
+				t.Errorf("checkDeviceTimestamp(%q) = %v, se esperaba aceptado=%t", tt.timestamp, err, tt.ok)
+			}
+		})
+	}
+}
@@ -2,37 +2,233 @@ package models
 
 import (
 	"database/sql"
+	"go-auth-api/src/templates"
+	"strings"
 	"time"
 )
 
 type Notification struct {
-	ID      int       `json:"id"`
-	UserID  int       `json:"user_id" binding:"required"`
-	Message string    `json:"message" binding:"required"`
-	SentAt  time.Time `json:"sent_at"`
+	ID         int         `json:"id"`
+	UserID     int         `json:"user_id" binding:"required"`
+	Subject    string      `json:"subject"`
+	Message    string      `json:"message" binding:"required"`
+	Type       string      `json:"type"` // One of NotificationTypes; derived from the template when empty
+	Template   string      `json:"-"`    // Name in the templates package; Send renders it in the user's language
+	Data       interface{} `json:"-"`    // Data of the template
+	SentAt     time.Time   `json:"sent_at"`
+	ReadAt     *time.Time  `json:"read_at"`              // Nil while unread
+	Deliveries []Delivery  `json:"deliveries,omitempty"` // Queued by Send, one per channel and recipient
+
+	emailHTML, emailText string
 }
 
-// Enviar notificación
-func (n *Notification) Send(db *sql.DB) error {
-	query := `INSERT INTO notifications (user_id, message, sent_at) 
-              VALUES ($1, $2, $3) RETURNING id`
-	return db.QueryRow(query, n.UserID, n.Message, time.Now()).Scan(&n.ID)
+// notificationRecipient is what Send needs to know about the user
+type notificationRecipient struct {
+	name, email, phone, language, timezone sql.NullString
 }
-func GetNotificationsByUserID(db *sql.DB, userID int) ([]Notification, error) {
-	query := `SELECT id, user_id, message, created_at FROM notifications WHERE user_id = $1`
-	rows, err := db.Query(query, userID)
+
+// Enviar notificación: genera el contenido en el idioma del usuario, la guarda y encola una entrega
+// por cada canal que el usuario tiene activo para su tipo y para el que tiene destinatario. Sin
+// Template, el mensaje libre se envuelve en la plantilla genérica. db puede ser la transacción del
+// cambio que la origina.
+func (n *Notification) Send(db DBTX) error {
+	if n.Type == "" {
+		n.Type = templateTypes[n.Template]
+	}
+	if n.Type == "" {
+		n.Type = TypeMessage
+	}
+	if !ValidNotificationType(n.Type) {
+		return &ValidationError{"tipo de notificación inválido"}
+	}
+
+	var to notificationRecipient
+	err := db.QueryRow(`SELECT u.username, u.email, u.phone, u.language, s.timezone
+                        FROM users u LEFT JOIN notification_settings s ON s.user_id = u.id
+                        WHERE u.id = $1`, n.UserID).
+		Scan(&to.name, &to.email, &to.phone, &to.language, &to.timezone)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if n.Template != "" {
+		content, err := templates.Render(n.Template, to.language.String, n.view(to, n.Data))
+		if err != nil {
+			return err
+		}
+		n.Subject, n.Message, n.emailHTML, n.emailText = content.Subject, content.Message, content.HTML, content.Text
+	} else {
+		content, err := templates.Render(templates.Notification, to.language.String,
+			n.view(to, templates.NotificationData{Message: n.Message}))
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(n.Subject) == "" {
+			n.Subject = content.Subject
+		}
+		n.emailHTML, n.emailText = content.HTML, content.Text
+	}
+
+	query := `INSERT INTO notifications (user_id, type, subject, message, sent_at)
+              VALUES ($1, $2, $3, $4, $5) RETURNING id, sent_at`
+	if err := db.QueryRow(query, n.UserID, n.Type, n.Subject, n.Message, time.Now()).Scan(&n.ID, &n.SentAt); err != nil {
+		return err
+	}
+	return n.route(db, to)
+}
+
+// view is what the templates receive; emails of non-transactional types carry an unsubscribe link.
+// Dates are shown in the user's timezone, or in UTC if they never set one.
+func (n *Notification) view(to notificationRecipient, data interface{}) templates.View {
+	loc, err := time.LoadLocation(to.timezone.String)
+	if err != nil {
+		loc = time.UTC
+	}
+	return templates.View{Name: to.name.String, Data: data, UnsubscribeURL: UnsubscribeURL(n.UserID, n.Type),
+		Location: loc}
+}
+
+// route writes the deliveries of the notification according to the user's preferences for its type.
+// Channels without a recipient (no email, no phone, no registered device) are skipped. During the
+// user's quiet hours the deliveries of non-transactional types wait until the quiet hours end; the
+// notification itself is in the inbox right away.
+func (n *Notification) route(db DBTX, to notificationRecipient) error {
+	channels, err := GetChannelPreferences(db, n.UserID)
+	if err != nil {
+		return err
+	}
+	settings, err := GetNotificationSettings(db, n.UserID)
+	if err != nil {
+		return err
+	}
+	prefs := ChannelPreferences{}
+	for _, channel := range Channels {
+		prefs[channel] = settings.ChannelEnabled(n.Type, channel, channels)
+	}
+	var deferUntil *time.Time
+	if !IsTransactional(n.Type) {
+		if until, quiet := settings.QuietUntil(time.Now()); quiet {
+			deferUntil = &until
+		}
 	}
-	defer rows.Close()
 
-	var notifications []Notification
+	var deliveries []Delivery
+	if prefs[ChannelEmail] && strings.TrimSpace(to.email.String) != "" {
+		deliveries = append(deliveries, Delivery{Channel: ChannelEmail, Recipient: to.email.String, Subject: n.Subject,
+			Body: n.emailHTML, TextBody: n.emailText})
+	}
+	if prefs[ChannelSMS] && strings.TrimSpace(to.phone.String) != "" {
+		deliveries = append(deliveries, Delivery{Channel: ChannelSMS, Recipient: to.phone.String, Subject: n.Subject, Body: n.Message})
+	}
+	if prefs[ChannelPush] {
+		tokens, err := GetPushTokens(db, n.UserID)
+		if err != nil {
+			return err
+		}
+		for _, t := range tokens {
+			deliveries = append(deliveries, Delivery{Channel: ChannelPush, Recipient: t.Token, Subject: n.Subject, Body: n.Message})
+		}
+	}
+
+	n.Deliveries = n.Deliveries[:0]
+	for _, d := range deliveries {
+		d.NotificationID = &n.ID
+		if deferUntil != nil {
+			d.NextAttemptAt = *deferUntil
+		}
+		if err := d.Enqueue(db); err != nil {
+			return err
+		}
+		n.Deliveries = append(n.Deliveries, d)
+	}
+	return nil
+}
+
+// Page sizes of the notification inbox
+const (
+	DefaultNotificationPageSize = 20
+	MaxNotificationPageSize     = 100
+)
+
+// NotificationPage is one page of the inbox plus the cursor for the next one
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
+	UnreadCount   int            `json:"unread_count"`
+}
+
+// GetInbox lists the user's notifications, newest first, optionally only the unread ones. Deleted
+// notifications are never returned.
+func GetInbox(db *sql.DB, userID int, unreadOnly bool, limit int, cursor string) (NotificationPage, error) {
+	page := NotificationPage{Notifications: []Notification{}}
+	if limit <= 0 {
+		limit = DefaultNotificationPageSize
+	}
+	if limit > MaxNotificationPageSize {
+		limit = MaxNotificationPageSize
+	}
+	before, err := decodeIDCursor(cursor)
+	if err != nil {
+		return page, err
+	}
+
+	rows, err := db.Query(`SELECT id, user_id, type, subject, message, sent_at, read_at FROM notifications
+                           WHERE user_id = $1 AND deleted_at IS NULL AND (NOT $2 OR read_at IS NULL)
+                           AND ($3 = 0 OR id < $3)
+                           ORDER BY id DESC LIMIT $4`, userID, unreadOnly, before, limit+1)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
 	for rows.Next() {
-		var notification Notification
-		if err := rows.Scan(&notification.ID, &notification.UserID, &notification.Message, &notification.SentAt); err != nil {
-			return nil, err
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Subject, &n.Message, &n.SentAt, &n.ReadAt); err != nil {
+			return page, err
 		}
-		notifications = append(notifications, notification)
+		page.Notifications = append(page.Notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+	rows.Close()
+
+	if len(page.Notifications) > limit {
+		page.Notifications = page.Notifications[:limit]
+		page.NextCursor = encodeIDCursor(int64(page.Notifications[limit-1].ID))
 	}
-	return notifications, nil
+	page.UnreadCount, err = CountUnreadNotifications(db, userID)
+	return page, err
+}
+
+// CountUnreadNotifications counts the user's unread notifications
+func CountUnreadNotifications(db *sql.DB, userID int) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM notifications
+                        WHERE user_id = $1 AND read_at IS NULL AND deleted_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationRead marks one of the user's notifications as read; marking it again keeps the
+// first read time. It returns sql.ErrNoRows for notifications of other users.
+func MarkNotificationRead(db *sql.DB, userID, id int) error {
+	return expectOneRow(db.Exec(`UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+                                 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, id, userID))
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user as read and returns how many
+// changed
+func MarkAllNotificationsRead(db *sql.DB, userID int) (int64, error) {
+	result, err := db.Exec(`UPDATE notifications SET read_at = NOW()
+                            WHERE user_id = $1 AND read_at IS NULL AND deleted_at IS NULL`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteNotification removes a notification from the user's inbox. The row is kept, hidden, so the
+// delivery history stays complete.
+func DeleteNotification(db *sql.DB, userID, id int) error {
+	return expectOneRow(db.Exec(`UPDATE notifications SET deleted_at = NOW()
+                                 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, id, userID))
 }
@@ -5,19 +5,34 @@ import (
 	"time"
 )
 
+// Tipos de pago
+const (
+	PaymentKindRental = "rental" // Pago del alquiler
+	PaymentKindDamage = "damage" // Cargo por un reporte de daños
+)
+
 type Payment struct {
 	ID            int       `json:"id"`
 	ReservationID int       `json:"reservation_id"`
 	Amount        float64   `json:"amount"`
 	Status        string    `json:"status"` // pagado, pendiente
+	Kind          string    `json:"kind"`   // rental, damage
+	Description   *string   `json:"description,omitempty"`
 	PaymentDate   time.Time `json:"payment_date"`
 }
 
-// Procesar el pago
-func (p *Payment) ProcessPayment(db *sql.DB) error {
+// Procesar el pago; db puede ser una transacción
+func (p *Payment) ProcessPayment(db DBTX) error {
+	if p.Amount <= 0 {
+		return &ValidationError{"el monto debe ser positivo"}
+	}
 	query := `INSERT INTO payments (reservation_id, amount, status, payment_date) 
-              VALUES ($1, $2, 'pagado', $3) RETURNING id`
-	return db.QueryRow(query, p.ReservationID, p.Amount, time.Now()).Scan(&p.ID)
+              VALUES ($1, $2, 'pagado', $3) RETURNING id, status, kind, payment_date`
+	err := db.QueryRow(query, p.ReservationID, p.Amount, time.Now()).Scan(&p.ID, &p.Status, &p.Kind, &p.PaymentDate)
+	if isForeignKeyViolation(err) {
+		return sql.ErrNoRows
+	}
+	return err
 }
 
 // // Generar factura
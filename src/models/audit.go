@@ -0,0 +1,104 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Audited entities
+const (
+	AuditReservation = "reservation"
+	AuditPayment     = "payment"
+	AuditVehicle     = "vehicle"
+)
+
+// AuditEntry is one business change in the audit log
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	EventID    *int64          `json:"event_id,omitempty"`
+	Type       string          `json:"type"`
+	Entity     string          `json:"entity"`
+	EntityID   int64           `json:"entity_id"`
+	UserID     *int            `json:"user_id,omitempty"`
+	ActorID    *int            `json:"actor_id,omitempty"` // Nil for devices and background jobs
+	Data       json.RawMessage `json:"data"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	RecordedAt time.Time       `json:"recorded_at"`
+}
+
+// RecordAudit adds the entry; an entry for the same event is only recorded once
+func RecordAudit(db DBTX, a *AuditEntry) error {
+	err := db.QueryRow(`INSERT INTO audit_log (event_id, type, entity, entity_id, user_id, actor_id, data, occurred_at)
+                        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+                        ON CONFLICT (event_id) DO NOTHING
+                        RETURNING id, recorded_at`,
+		a.EventID, a.Type, a.Entity, a.EntityID, a.UserID, a.ActorID, a.Data, a.OccurredAt).Scan(&a.ID, &a.RecordedAt)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+// AuditFilter narrows the audit log; zero values match everything
+type AuditFilter struct {
+	Entity   string
+	EntityID int64
+	UserID   int
+	Limit    int
+	Cursor   string
+}
+
+// AuditPage is one page of the audit log plus the cursor for the next one
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+const (
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 500
+)
+
+// GetAuditLog lists the entries matching the filter, newest first
+func GetAuditLog(db *sql.DB, f AuditFilter) (AuditPage, error) {
+	page := AuditPage{Entries: []AuditEntry{}}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultAuditPageSize
+	}
+	if limit > MaxAuditPageSize {
+		limit = MaxAuditPageSize
+	}
+	before, err := decodeIDCursor(f.Cursor)
+	if err != nil {
+		return page, err
+	}
+
+	rows, err := db.Query(`SELECT id, event_id, type, entity, entity_id, user_id, actor_id, data, occurred_at, recorded_at
+                           FROM audit_log
+                           WHERE ($1 = '' OR entity = $1) AND ($2 = 0 OR entity_id = $2) AND ($3 = 0 OR user_id = $3)
+                           AND ($4 = 0 OR id < $4)
+                           ORDER BY id DESC LIMIT $5`, f.Entity, f.EntityID, f.UserID, before, limit+1)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a AuditEntry
+		if err := rows.Scan(&a.ID, &a.EventID, &a.Type, &a.Entity, &a.EntityID, &a.UserID, &a.ActorID, &a.Data,
+			&a.OccurredAt, &a.RecordedAt); err != nil {
+			return page, err
+		}
+		page.Entries = append(page.Entries, a)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if len(page.Entries) > limit {
+		page.Entries = page.Entries[:limit]
+		page.NextCursor = encodeIDCursor(page.Entries[limit-1].ID)
+	}
+	return page, nil
+}
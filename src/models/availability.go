@@ -0,0 +1,144 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Availability states
+const (
+	AvailabilityAvailable   = "available"   // Can be reserved now
+	AvailabilityInUse       = "in_use"      // An active reservation covers the current time
+	AvailabilityMaintenance = "maintenance" // A downtime window covers the current time
+	AvailabilityUnavailable = "unavailable" // Taken out of service by status; no free window is known
+	AvailabilityRetired     = "retired"
+)
+
+// Block kinds
+const (
+	BlockReservation = "reservation"
+	BlockDowntime    = "downtime"
+)
+
+// Block is a period in which a vehicle cannot be reserved
+type Block struct {
+	Kind  string
+	Start time.Time
+	End   time.Time
+}
+
+// FreeWindow is a period in which the vehicle can be reserved; a nil Until means open-ended
+type FreeWindow struct {
+	From  time.Time  `json:"from"`
+	Until *time.Time `json:"until"`
+}
+
+// Availability is the single, derived view of whether a vehicle can be reserved
+type Availability struct {
+	State    string      `json:"state"`
+	Until    *time.Time  `json:"until,omitempty"`  // End of the block that sets the current state
+	NextFree *FreeWindow `json:"next_free_window"` // First free window from now; nil when none is known
+}
+
+// ComputeAvailability derives the availability of a vehicle from its status and its active
+// reservations and downtime windows. It uses the same rules as reservableCond, which backs booking,
+// the availability check and the availability filter of SearchVehicles: only "available" vehicles can
+// be reserved, and a block [Start, End) overlaps a request [from, to) when Start < to and End > from.
+func ComputeAvailability(status string, blocks []Block, now time.Time) Availability {
+	switch status {
+	case VehicleStatusRetired:
+		return Availability{State: AvailabilityRetired}
+	case VehicleStatusAvailable, "":
+	default:
+		// maintenance, out_of_service, reserved or rented set by hand: no free window until staff change it
+		return Availability{State: AvailabilityUnavailable}
+	}
+
+	sorted := make([]Block, 0, len(blocks))
+	for _, b := range blocks {
+		if b.End.After(now) {
+			sorted = append(sorted, b)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	availability := Availability{State: AvailabilityAvailable}
+	free := now
+	for _, b := range sorted {
+		if b.Start.After(free) {
+			// Gap before this block: that is the next free window
+			until := b.Start
+			availability.NextFree = &FreeWindow{From: free, Until: &until}
+			return availability
+		}
+		// The block covers the current cursor
+		if free.Equal(now) && availability.State == AvailabilityAvailable {
+			availability.State = AvailabilityInUse
+			if b.Kind == BlockDowntime {
+				availability.State = AvailabilityMaintenance
+			}
+		}
+		if b.End.After(free) {
+			free = b.End
+			if availability.State != AvailabilityAvailable {
+				end := free
+				availability.Until = &end
+			}
+		}
+	}
+	availability.NextFree = &FreeWindow{From: free}
+	return availability
+}
+
+// attachAvailability computes the availability of the vehicles from their pending blocks, loaded in
+// two queries for the whole page
+func attachAvailability(db *sql.DB, vehicles []Vehicle, now time.Time) error {
+	if len(vehicles) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(vehicles))
+	for i := range vehicles {
+		ids[i] = int64(vehicles[i].ID)
+	}
+
+	blocks := map[int][]Block{}
+	for kind, query := range map[string]string{
+		BlockReservation: `SELECT vehicle_id, start_time, end_time FROM reservations
+                           WHERE vehicle_id = ANY($1) AND status = 'activa' AND end_time > $2`,
+		BlockDowntime: `SELECT vehicle_id, start_time, end_time FROM vehicle_downtime
+                        WHERE vehicle_id = ANY($1) AND end_time > $2`,
+	} {
+		if err := loadBlocks(db, query, kind, pq.Array(ids), now, blocks); err != nil {
+			return err
+		}
+	}
+
+	for i := range vehicles {
+		status := ""
+		if vehicles[i].Status != nil {
+			status = *vehicles[i].Status
+		}
+		vehicles[i].Availability = ComputeAvailability(status, blocks[vehicles[i].ID], now)
+	}
+	return nil
+}
+
+func loadBlocks(db *sql.DB, query, kind string, ids interface{}, now time.Time, blocks map[int][]Block) error {
+	rows, err := db.Query(query, ids, now)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var vehicleID int
+		b := Block{Kind: kind}
+		if err := rows.Scan(&vehicleID, &b.Start, &b.End); err != nil {
+			return err
+		}
+		blocks[vehicleID] = append(blocks[vehicleID], b)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"go-auth-api/src/testdb"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestComputeAvailability(t *testing.T) {
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+	ptr := func(h int) *time.Time { v := at(h); return &v }
+	now := at(10)
+	res := func(start, end int) Block { return Block{Kind: BlockReservation, Start: at(start), End: at(end)} }
+	down := func(start, end int) Block { return Block{Kind: BlockDowntime, Start: at(start), End: at(end)} }
+	openFrom := func(h int) *FreeWindow { return &FreeWindow{From: at(h)} }
+	window := func(from, until int) *FreeWindow { return &FreeWindow{From: at(from), Until: ptr(until)} }
+
+	tests := []struct {
+		name   string
+		status string
+		blocks []Block
+		want   Availability
+	}{
+		{"free", VehicleStatusAvailable, nil,
+			Availability{State: AvailabilityAvailable, NextFree: openFrom(10)}},
+		{"no status counts as available", "", nil,
+			Availability{State: AvailabilityAvailable, NextFree: openFrom(10)}},
+		{"retired", VehicleStatusRetired, []Block{res(12, 14)},
+			Availability{State: AvailabilityRetired}},
+		{"out of service by status", VehicleStatusMaintenance, nil,
+			Availability{State: AvailabilityUnavailable}},
+		{"future reservation", VehicleStatusAvailable, []Block{res(12, 14)},
+			Availability{State: AvailabilityAvailable, NextFree: window(10, 12)}},
+		{"reservation in progress", VehicleStatusAvailable, []Block{res(9, 11)},
+			Availability{State: AvailabilityInUse, Until: ptr(11), NextFree: openFrom(11)}},
+		{"downtime in progress", VehicleStatusAvailable, []Block{down(8, 12)},
+			Availability{State: AvailabilityMaintenance, Until: ptr(12), NextFree: openFrom(12)}},
+		{"block ending now is over", VehicleStatusAvailable, []Block{res(8, 10)},
+			Availability{State: AvailabilityAvailable, NextFree: openFrom(10)}},
+		{"block starting now covers now", VehicleStatusAvailable, []Block{res(10, 11)},
+			Availability{State: AvailabilityInUse, Until: ptr(11), NextFree: openFrom(11)}},
+		{"adjacent blocks leave no window between them", VehicleStatusAvailable, []Block{res(9, 11), down(11, 13)},
+			Availability{State: AvailabilityInUse, Until: ptr(13), NextFree: openFrom(13)}},
+		{"overlapping blocks", VehicleStatusAvailable, []Block{res(9, 12), down(11, 13)},
+			Availability{State: AvailabilityInUse, Until: ptr(13), NextFree: openFrom(13)}},
+		{"block inside another", VehicleStatusAvailable, []Block{down(9, 14), res(10, 11)},
+			Availability{State: AvailabilityMaintenance, Until: ptr(14), NextFree: openFrom(14)}},
+		{"gap after the current block", VehicleStatusAvailable, []Block{res(9, 11), res(12, 13)},
+			Availability{State: AvailabilityInUse, Until: ptr(11), NextFree: window(11, 12)}},
+		{"unsorted blocks", VehicleStatusAvailable, []Block{res(12, 13), down(11, 12), res(9, 11), res(15, 16)},
+			Availability{State: AvailabilityInUse, Until: ptr(13), NextFree: window(13, 15)}},
+		{"adjacent future blocks", VehicleStatusAvailable, []Block{res(12, 13), res(11, 12)},
+			Availability{State: AvailabilityAvailable, NextFree: window(10, 11)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ComputeAvailability(tt.status, tt.blocks, now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ComputeAvailability() = %s, want %s", describeAvailability(got), describeAvailability(tt.want))
+			}
+		})
+	}
+}
+
+func describeAvailability(a Availability) string {
+	s := a.State
+	if a.Until != nil {
+		s += " until " + a.Until.Format("15:04")
+	}
+	if a.NextFree != nil {
+		s += ", next free from " + a.NextFree.From.Format("15:04")
+		if a.NextFree.Until != nil {
+			s += " until " + a.NextFree.Until.Format("15:04")
+		}
+	}
+	return s
+}
+
+func TestIsVehicleAvailableUnknownVehicle(t *testing.T) {
+	db := testdb.Open(t)
+	vehicleID := seedTestVehicle(t, db)
+	start := time.Now().Add(time.Hour)
+
+	r := Reservation{VehicleID: vehicleID, StartTime: start, EndTime: start.Add(time.Hour)}
+	if ok, err := r.IsVehicleAvailable(db); err != nil || !ok {
+		t.Fatalf("IsVehicleAvailable = %t, %v; want true, nil", ok, err)
+	}
+	r.VehicleID = vehicleID + 1
+	if _, err := r.IsVehicleAvailable(db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("IsVehicleAvailable of a missing vehicle = %v, want sql.ErrNoRows", err)
+	}
+	if err := r.Create(db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Create for a missing vehicle = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Brand is an entry of the vehicle brand catalog
+type Brand struct {
+	ID   int    `json:"id"`
+	Name string `json:"name" binding:"required"`
+}
+
+// CarModel is an entry of the model catalog, optionally tied to a brand
+type CarModel struct {
+	ID      int    `json:"id"`
+	BrandID *int   `json:"brand_id"`
+	Name    string `json:"name" binding:"required"`
+}
+
+// FuelTypeRecord is an entry of the fuel type catalog
+type FuelTypeRecord struct {
+	ID   int    `json:"id"`
+	Type string `json:"type" binding:"required"`
+}
+
+// catalogWriteError translates constraint violations into ErrConflict
+func catalogWriteError(err error) error {
+	if isUniqueViolation(err) || isForeignKeyViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// expectOneRow turns an UPDATE/DELETE that touched nothing into sql.ErrNoRows
+func expectOneRow(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetBrands lists the brand catalog
+func GetBrands(db *sql.DB) ([]Brand, error) {
+	rows, err := db.Query(`SELECT id, name FROM brand ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	brands := []Brand{}
+	for rows.Next() {
+		var b Brand
+		if err := rows.Scan(&b.ID, &b.Name); err != nil {
+			return nil, err
+		}
+		brands = append(brands, b)
+	}
+	return brands, rows.Err()
+}
+
+func (b *Brand) Create(db *sql.DB) error {
+	b.Name = strings.TrimSpace(b.Name)
+	err := db.QueryRow(`INSERT INTO brand (name) VALUES ($1) RETURNING id`, b.Name).Scan(&b.ID)
+	return catalogWriteError(err)
+}
+
+func (b *Brand) Update(db *sql.DB) error {
+	b.Name = strings.TrimSpace(b.Name)
+	return catalogWriteError(expectOneRow(db.Exec(`UPDATE brand SET name = $1 WHERE id = $2`, b.Name, b.ID)))
+}
+
+// DeleteBrand removes a brand; it fails with ErrConflict while models or vehicles use it
+func DeleteBrand(db *sql.DB, id int) error {
+	return catalogWriteError(expectOneRow(db.Exec(`DELETE FROM brand WHERE id = $1`, id)))
+}
+
+// GetCarModels lists the model catalog, optionally restricted to one brand
+func GetCarModels(db *sql.DB, brandID *int) ([]CarModel, error) {
+	query := `SELECT id, brand_id, name FROM model WHERE ($1::int IS NULL OR brand_id = $1) ORDER BY name`
+	rows, err := db.Query(query, brandID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	carModels := []CarModel{}
+	for rows.Next() {
+		var m CarModel
+		var brand sql.NullInt64
+		if err := rows.Scan(&m.ID, &brand, &m.Name); err != nil {
+			return nil, err
+		}
+		if brand.Valid {
+			id := int(brand.Int64)
+			m.BrandID = &id
+		}
+		carModels = append(carModels, m)
+	}
+	return carModels, rows.Err()
+}
+
+func (m *CarModel) Create(db *sql.DB) error {
+	m.Name = strings.TrimSpace(m.Name)
+	err := db.QueryRow(`INSERT INTO model (brand_id, name) VALUES ($1, $2) RETURNING id`, m.BrandID, m.Name).Scan(&m.ID)
+	return catalogWriteError(err)
+}
+
+func (m *CarModel) Update(db *sql.DB) error {
+	m.Name = strings.TrimSpace(m.Name)
+	return catalogWriteError(expectOneRow(db.Exec(`UPDATE model SET brand_id = $1, name = $2 WHERE id = $3`, m.BrandID, m.Name, m.ID)))
+}
+
+// DeleteCarModel removes a model; it fails with ErrConflict while vehicles use it
+func DeleteCarModel(db *sql.DB, id int) error {
+	return catalogWriteError(expectOneRow(db.Exec(`DELETE FROM model WHERE id = $1`, id)))
+}
+
+// GetFuelTypes lists the fuel type catalog
+func GetFuelTypes(db *sql.DB) ([]FuelTypeRecord, error) {
+	rows, err := db.Query(`SELECT id, type FROM fuel_type ORDER BY type`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fuelTypes := []FuelTypeRecord{}
+	for rows.Next() {
+		var f FuelTypeRecord
+		if err := rows.Scan(&f.ID, &f.Type); err != nil {
+			return nil, err
+		}
+		fuelTypes = append(fuelTypes, f)
+	}
+	return fuelTypes, rows.Err()
+}
+
+func (f *FuelTypeRecord) Create(db *sql.DB) error {
+	f.Type = strings.TrimSpace(f.Type)
+	err := db.QueryRow(`INSERT INTO fuel_type (type) VALUES ($1) RETURNING id`, f.Type).Scan(&f.ID)
+	return catalogWriteError(err)
+}
+
+func (f *FuelTypeRecord) Update(db *sql.DB) error {
+	f.Type = strings.TrimSpace(f.Type)
+	return catalogWriteError(expectOneRow(db.Exec(`UPDATE fuel_type SET type = $1 WHERE id = $2`, f.Type, f.ID)))
+}
+
+// DeleteFuelType removes a fuel type; it fails with ErrConflict while vehicles use it
+func DeleteFuelType(db *sql.DB, id int) error {
+	return catalogWriteError(expectOneRow(db.Exec(`DELETE FROM fuel_type WHERE id = $1`, id)))
+}
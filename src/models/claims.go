@@ -1,11 +1,50 @@
 package models
 
 import (
+	"errors"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
 // Claims estructura de las reclamaciones del token
 type Claims struct {
+	UserID   int    `json:"user_id"`
 	Username string `json:"username"`
+	Role     string `json:"role"`
 	jwt.RegisteredClaims
 }
+
+// jwtKey signs and verifies the session tokens; it is set at startup by ConfigureJWT
+var jwtKey []byte
+
+// ConfigureJWT sets the key of the session tokens
+func ConfigureJWT(key []byte) {
+	jwtKey = key
+}
+
+// SignClaims issues a session token for the claims
+func SignClaims(claims *Claims) (string, error) {
+	if len(jwtKey) == 0 {
+		return "", errors.New("clave JWT no configurada")
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtKey)
+}
+
+// ParseClaims verifies a session token and returns its claims. Only HS256 tokens signed with the
+// configured key are accepted.
+func ParseClaims(token string) (*Claims, error) {
+	if len(jwtKey) == 0 {
+		return nil, errors.New("clave JWT no configurada")
+	}
+	claims := &Claims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if !parsed.Valid {
+		return nil, errors.New("token inválido")
+	}
+	return claims, nil
+}
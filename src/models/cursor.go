@@ -0,0 +1,37 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("cursor inválido")
+
+// idCursor marks the last row of a page of a listing ordered by descending id. Ids grow with
+// creation time, so the id alone is a stable cursor.
+type idCursor struct {
+	ID int64 `json:"id"`
+}
+
+func encodeIDCursor(id int64) string {
+	raw, _ := json.Marshal(idCursor{ID: id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeIDCursor returns the id after which the page starts, or 0 for the first page
+func decodeIDCursor(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	var cur idCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &cur); err != nil || cur.ID <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return cur.ID, nil
+}
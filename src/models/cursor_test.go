@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestIDCursor(t *testing.T) {
+	if id, err := decodeIDCursor(encodeIDCursor(42)); err != nil || id != 42 {
+		t.Errorf("round trip = %d, %v; want 42", id, err)
+	}
+	if id, err := decodeIDCursor(""); err != nil || id != 0 {
+		t.Errorf("empty cursor = %d, %v; want the first page", id, err)
+	}
+	// Cursors issued before the listings had their own format still work
+	legacy := base64.RawURLEncoding.EncodeToString([]byte(`{"v":0,"id":7}`))
+	if id, err := decodeIDCursor(legacy); err != nil || id != 7 {
+		t.Errorf("legacy cursor = %d, %v; want 7", id, err)
+	}
+	for _, bad := range []string{"%%%", base64.RawURLEncoding.EncodeToString([]byte(`[1]`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"id":0}`))} {
+		if _, err := decodeIDCursor(bad); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeIDCursor(%q) = %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Checklist stages
+const (
+	ChecklistStart = "start"
+	ChecklistEnd   = "end"
+)
+
+// ChecklistItems are the points every condition checklist must cover
+var ChecklistItems = []string{"exterior", "interior", "tires", "lights", "windshield", "fuel_or_charge", "documents"}
+
+// ErrChecklistRequired is returned when a trip starts or ends without its condition checklist
+var ErrChecklistRequired = errors.New("se requiere la lista de condición del vehículo")
+
+// ErrReportNotOpen is returned when changing a damage report that was already charged or dismissed
+var ErrReportNotOpen = errors.New("el reporte de daños ya fue resuelto")
+
+// ChecklistItem is the state of one point of a checklist
+type ChecklistItem struct {
+	OK    bool    `json:"ok"`
+	Notes *string `json:"notes,omitempty"`
+}
+
+// ConditionChecklist is the inspection of the vehicle at the start or the end of a trip
+type ConditionChecklist struct {
+	ID            int                      `json:"id"`
+	ReservationID int                      `json:"reservation_id"`
+	Stage         string                   `json:"stage" binding:"required"`
+	Items         map[string]ChecklistItem `json:"items" binding:"required"`
+	FuelLevel     *float64                 `json:"fuel_level"`
+	OdometerKm    *float64                 `json:"odometer_km"`
+	Notes         *string                  `json:"notes"`
+	SubmittedBy   int                      `json:"submitted_by"`
+	CreatedAt     time.Time                `json:"created_at"`
+}
+
+// Validate checks that every required item is present and the readings are in range
+func (cl *ConditionChecklist) Validate() error {
+	if cl.Stage != ChecklistStart && cl.Stage != ChecklistEnd {
+		return &ValidationError{"stage debe ser start o end"}
+	}
+	for _, item := range ChecklistItems {
+		if _, ok := cl.Items[item]; !ok {
+			return &ValidationError{fmt.Sprintf("falta el punto %q de la lista (requeridos: %s)",
+				item, strings.Join(ChecklistItems, ", "))}
+		}
+	}
+	for name, item := range cl.Items {
+		if !item.OK && (item.Notes == nil || strings.TrimSpace(*item.Notes) == "") {
+			return &ValidationError{fmt.Sprintf("describa el problema del punto %q", name)}
+		}
+	}
+	if !validPercentage(cl.FuelLevel) {
+		return &ValidationError{"fuel_level debe estar entre 0 y 100"}
+	}
+	if cl.OdometerKm != nil && *cl.OdometerKm < 0 {
+		return &ValidationError{"odometer_km no puede ser negativo"}
+	}
+	return nil
+}
+
+// Create stores the checklist. Each stage is submitted once per reservation and the end checklist
+// needs the start one.
+func (cl *ConditionChecklist) Create(db DBTX) error {
+	if err := cl.Validate(); err != nil {
+		return err
+	}
+	if cl.Stage == ChecklistEnd {
+		if ok, err := HasChecklist(db, cl.ReservationID, ChecklistStart); err != nil {
+			return err
+		} else if !ok {
+			return &ValidationError{"primero debe enviarse la lista de inicio"}
+		}
+	}
+
+	items, err := json.Marshal(cl.Items)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO condition_checklists (reservation_id, stage, items, fuel_level, odometer_km, notes, submitted_by)
+              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`
+	err = db.QueryRow(query, cl.ReservationID, cl.Stage, items, cl.FuelLevel, cl.OdometerKm, cl.Notes, cl.SubmittedBy).
+		Scan(&cl.ID, &cl.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// HasChecklist reports whether the reservation already has the checklist of the stage
+func HasChecklist(db DBTX, reservationID int, stage string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM condition_checklists WHERE reservation_id = $1 AND stage = $2)`,
+		reservationID, stage).Scan(&exists)
+	return exists, err
+}
+
+// GetChecklists returns the checklists of a reservation, start first
+func GetChecklists(db *sql.DB, reservationID int) ([]ConditionChecklist, error) {
+	rows, err := db.Query(`SELECT id, reservation_id, stage, items, fuel_level, odometer_km, notes, submitted_by, created_at
+                           FROM condition_checklists WHERE reservation_id = $1 ORDER BY created_at`, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checklists := []ConditionChecklist{}
+	for rows.Next() {
+		var cl ConditionChecklist
+		var items []byte
+		if err := rows.Scan(&cl.ID, &cl.ReservationID, &cl.Stage, &items, &cl.FuelLevel, &cl.OdometerKm, &cl.Notes,
+			&cl.SubmittedBy, &cl.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(items, &cl.Items); err != nil {
+			return nil, err
+		}
+		checklists = append(checklists, cl)
+	}
+	return checklists, rows.Err()
+}
+
+// Damage severities
+const (
+	SeverityMinor    = "minor"
+	SeverityModerate = "moderate"
+	SeveritySevere   = "severe"
+)
+
+// Damage report statuses
+const (
+	DamageOpen      = "open"
+	DamageCharged   = "charged"
+	DamageDismissed = "dismissed"
+)
+
+// DamagePhoto is one picture attached to a damage report
+type DamagePhoto struct {
+	ID           int       `json:"id"`
+	ReportID     int       `json:"report_id"`
+	ImageURL     string    `json:"image_url"`
+	ThumbnailURL *string   `json:"thumbnail_url"`
+	StorageKey   *string   `json:"-"`
+	ThumbnailKey *string   `json:"-"`
+	ContentType  *string   `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DamageReport is damage found on a vehicle during a reservation
+type DamageReport struct {
+	ID              int           `json:"id"`
+	ReservationID   int           `json:"reservation_id"`
+	VehicleID       int           `json:"vehicle_id"`
+	ReportedBy      int           `json:"reported_by"`
+	LocationOnCar   string        `json:"location_on_car" binding:"required"` // e.g. "puerta delantera izquierda"
+	Severity        string        `json:"severity" binding:"required"`
+	Description     *string       `json:"description"`
+	OccurredAt      time.Time     `json:"occurred_at"`
+	Status          string        `json:"status"`
+	ChargePaymentID *int          `json:"charge_payment_id,omitempty"`
+	ResolvedBy      *int          `json:"resolved_by,omitempty"`
+	ResolvedAt      *time.Time    `json:"resolved_at,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+	Photos          []DamagePhoto `json:"photos"`
+}
+
+const damageReportColumns = `id, reservation_id, vehicle_id, reported_by, location_on_car, severity, description,
+                             occurred_at, status, charge_payment_id, resolved_by, resolved_at, created_at`
+
+func (r *DamageReport) scanFields() []interface{} {
+	return []interface{}{&r.ID, &r.ReservationID, &r.VehicleID, &r.ReportedBy, &r.LocationOnCar, &r.Severity,
+		&r.Description, &r.OccurredAt, &r.Status, &r.ChargePaymentID, &r.ResolvedBy, &r.ResolvedAt, &r.CreatedAt}
+}
+
+// Validate checks the business rules that do not need the database
+func (r *DamageReport) Validate() error {
+	r.LocationOnCar = strings.TrimSpace(r.LocationOnCar)
+	switch {
+	case r.LocationOnCar == "":
+		return &ValidationError{"location_on_car es requerido"}
+	case r.Severity != SeverityMinor && r.Severity != SeverityModerate && r.Severity != SeveritySevere:
+		return &ValidationError{"severity debe ser minor, moderate o severe"}
+	case r.OccurredAt.After(time.Now().Add(maxClockSkew)):
+		return &ValidationError{"occurred_at no puede estar en el futuro"}
+	}
+	return nil
+}
+
+// Create files the report against the reservation's vehicle
+func (r *DamageReport) Create(db *sql.DB) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	occurredAt := r.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+	query := `INSERT INTO damage_reports (reservation_id, vehicle_id, reported_by, location_on_car, severity,
+                                          description, occurred_at)
+              SELECT id, vehicle_id, $2, $3, $4, $5, $6 FROM reservations WHERE id = $1
+              RETURNING ` + damageReportColumns
+	err := db.QueryRow(query, r.ReservationID, r.ReportedBy, r.LocationOnCar, r.Severity, r.Description, occurredAt).
+		Scan(r.scanFields()...)
+	if err != nil {
+		return err
+	}
+	r.Photos = []DamagePhoto{}
+	return nil
+}
+
+// GetDamageReport returns a report with its photos
+func GetDamageReport(db *sql.DB, id int) (DamageReport, error) {
+	var r DamageReport
+	err := db.QueryRow(`SELECT `+damageReportColumns+` FROM damage_reports WHERE id = $1`, id).Scan(r.scanFields()...)
+	if err != nil {
+		return r, err
+	}
+	reports := []DamageReport{r}
+	if err := attachDamagePhotos(db, reports); err != nil {
+		return r, err
+	}
+	return reports[0], nil
+}
+
+// GetDamageReports lists the reports of a reservation, or every report with the given status when
+// reservationID is nil, newest first
+func GetDamageReports(db *sql.DB, reservationID *int, status string) ([]DamageReport, error) {
+	rows, err := db.Query(`SELECT `+damageReportColumns+` FROM damage_reports
+                           WHERE ($1::int IS NULL OR reservation_id = $1) AND ($2 = '' OR status = $2)
+                           ORDER BY created_at DESC`, reservationID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []DamageReport{}
+	for rows.Next() {
+		var r DamageReport
+		if err := rows.Scan(r.scanFields()...); err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	return reports, attachDamagePhotos(db, reports)
+}
+
+// attachDamagePhotos loads the photos of all the reports in one query
+func attachDamagePhotos(db *sql.DB, reports []DamageReport) error {
+	if len(reports) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(reports))
+	index := make(map[int]int, len(reports))
+	for i := range reports {
+		ids[i] = int64(reports[i].ID)
+		index[reports[i].ID] = i
+		reports[i].Photos = []DamagePhoto{}
+	}
+
+	rows, err := db.Query(`SELECT id, report_id, image_url, thumbnail_url, storage_key, thumbnail_key, content_type, created_at
+                           FROM damage_photos WHERE report_id = ANY($1) ORDER BY id`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p DamagePhoto
+		if err := rows.Scan(&p.ID, &p.ReportID, &p.ImageURL, &p.ThumbnailURL, &p.StorageKey, &p.ThumbnailKey,
+			&p.ContentType, &p.CreatedAt); err != nil {
+			return err
+		}
+		i := index[p.ReportID]
+		reports[i].Photos = append(reports[i].Photos, p)
+	}
+	return rows.Err()
+}
+
+// AddDamagePhoto attaches a stored picture to an open report
+func AddDamagePhoto(db *sql.DB, p *DamagePhoto) error {
+	query := `INSERT INTO damage_photos (report_id, image_url, thumbnail_url, storage_key, thumbnail_key, content_type)
+              SELECT id, $2, $3, $4, $5, $6 FROM damage_reports WHERE id = $1 AND status = 'open'
+              RETURNING id, created_at`
+	err := db.QueryRow(query, p.ReportID, p.ImageURL, p.ThumbnailURL, p.StorageKey, p.ThumbnailKey, p.ContentType).
+		Scan(&p.ID, &p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrReportNotOpen
+	}
+	return err
+}
+
+// ChargeDamageReport turns an open report into a damage charge: a pending payment of the reservation
+// that references the report. The report is marked as charged in the same transaction.
+func ChargeDamageReport(db *sql.DB, reportID, staffID int, amount float64, description *string) (Payment, error) {
+	var payment Payment
+	if amount <= 0 {
+		return payment, &ValidationError{"el monto debe ser positivo"}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return payment, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRow(`SELECT reservation_id, status FROM damage_reports WHERE id = $1 FOR UPDATE`, reportID).
+		Scan(&payment.ReservationID, &status); err != nil {
+		return payment, err
+	}
+	if status != DamageOpen {
+		return payment, ErrReportNotOpen
+	}
+
+	if description == nil {
+		text := fmt.Sprintf("Cargo por daños, reporte %d", reportID)
+		description = &text
+	}
+	payment.Amount = amount
+	payment.Status = "pendiente"
+	payment.Kind = PaymentKindDamage
+	payment.Description = description
+	err = tx.QueryRow(`INSERT INTO payments (reservation_id, amount, status, kind, description)
+                       VALUES ($1, $2, $3, $4, $5) RETURNING id, payment_date`,
+		payment.ReservationID, payment.Amount, payment.Status, payment.Kind, payment.Description).
+		Scan(&payment.ID, &payment.PaymentDate)
+	if err != nil {
+		return payment, err
+	}
+
+	_, err = tx.Exec(`UPDATE damage_reports SET status = 'charged', charge_payment_id = $1, resolved_by = $2,
+                                                resolved_at = NOW()
+                      WHERE id = $3`, payment.ID, staffID, reportID)
+	if err != nil {
+		return payment, err
+	}
+	return payment, tx.Commit()
+}
+
+// DismissDamageReport closes an open report without charging
+func DismissDamageReport(db *sql.DB, reportID, staffID int) error {
+	err := expectOneRow(db.Exec(`UPDATE damage_reports SET status = 'dismissed', resolved_by = $1, resolved_at = NOW()
+                                 WHERE id = $2 AND status = 'open'`, staffID, reportID))
+	if err == sql.ErrNoRows {
+		if _, getErr := GetDamageReport(db, reportID); getErr == nil {
+			return ErrReportNotOpen
+		}
+	}
+	return err
+}
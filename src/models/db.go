@@ -0,0 +1,10 @@
+package models
+
+import "database/sql"
+
+// DBTX is satisfied by both *sql.DB and *sql.Tx, so a write can join the caller's transaction
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
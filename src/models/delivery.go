@@ -0,0 +1,213 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Notification channels
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelPush  = "push"
+)
+
+// Channels lists every channel a notification can be delivered on
+var Channels = []string{ChannelEmail, ChannelSMS, ChannelPush}
+
+// ValidChannel reports whether channel is one of Channels
+func ValidChannel(channel string) bool {
+	for _, c := range Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery statuses
+const (
+	DeliveryPending = "pending" // Waiting for its next attempt
+	DeliverySending = "sending" // Claimed by a worker until locked_until
+	DeliverySent    = "sent"
+	DeliveryDead    = "dead" // Permanent failure or out of attempts; only retried by hand
+)
+
+// Backoff between delivery attempts: deliveryBackoffBase doubled per attempt, capped at deliveryBackoffMax
+const (
+	deliveryBackoffBase = 30 * time.Second
+	deliveryBackoffMax  = 2 * time.Hour
+)
+
+// Delivery is one message of the delivery outbox: a notification sent to one recipient over one
+// channel, or a standalone email when NotificationID is nil
+type Delivery struct {
+	ID             int64      `json:"id"`
+	NotificationID *int       `json:"notification_id,omitempty"`
+	Channel        string     `json:"channel"`
+	Recipient      string     `json:"recipient"`
+	Subject        string     `json:"subject"`
+	Body           string     `json:"-"`
+	TextBody       string     `json:"-"` // Plain-text alternative of an email body
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	MaxAttempts    int        `json:"max_attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastError      *string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+}
+
+const deliveryColumns = `id, notification_id, channel, recipient, subject, body, text_body, status, attempts,
+                         max_attempts, next_attempt_at, last_error, created_at, sent_at`
+
+func (d *Delivery) scanFields() []interface{} {
+	return []interface{}{&d.ID, &d.NotificationID, &d.Channel, &d.Recipient, &d.Subject, &d.Body, &d.TextBody, &d.Status,
+		&d.Attempts, &d.MaxAttempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.SentAt}
+}
+
+func scanDeliveries(rows *sql.Rows) ([]Delivery, error) {
+	defer rows.Close()
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		if err := rows.Scan(d.scanFields()...); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Enqueue writes the delivery to the outbox. Pass the transaction of the business change so the
+// message is sent if and only if the change commits. A zero NextAttemptAt means right away.
+func (d *Delivery) Enqueue(db DBTX) error {
+	d.Recipient = strings.TrimSpace(d.Recipient)
+	if d.Recipient == "" {
+		return &ValidationError{"el destinatario es requerido"}
+	}
+	if !ValidChannel(d.Channel) {
+		return &ValidationError{"canal inválido"}
+	}
+	var nextAttempt *time.Time
+	if !d.NextAttemptAt.IsZero() {
+		nextAttempt = &d.NextAttemptAt
+	}
+	return db.QueryRow(`INSERT INTO notification_deliveries (notification_id, channel, recipient, subject, body, text_body,
+                                                             next_attempt_at)
+                        VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, NOW())) RETURNING `+deliveryColumns,
+		d.NotificationID, d.Channel, d.Recipient, d.Subject, d.Body, d.TextBody, nextAttempt).Scan(d.scanFields()...)
+}
+
+// EnqueueEmail writes a standalone email, not tied to a notification, to the outbox
+func EnqueueEmail(db DBTX, to, subject, body string) (int64, error) {
+	d := Delivery{Channel: ChannelEmail, Recipient: to, Subject: subject, Body: body}
+	err := d.Enqueue(db)
+	return d.ID, err
+}
+
+// DeliveryBackoff is the wait before the next attempt after the given number of failed attempts
+func DeliveryBackoff(attempts int) time.Duration {
+	wait := deliveryBackoffBase
+	for i := 1; i < attempts && wait < deliveryBackoffMax; i++ {
+		wait *= 2
+	}
+	if wait > deliveryBackoffMax {
+		wait = deliveryBackoffMax
+	}
+	return wait
+}
+
+// ClaimDeliveries leases up to limit due messages to the calling worker. Messages whose lease expired
+// (a worker died mid-send) are claimed again. Concurrent workers never get the same message.
+func ClaimDeliveries(db *sql.DB, limit int, lease time.Duration) ([]Delivery, error) {
+	rows, err := db.Query(`UPDATE notification_deliveries
+                           SET status = 'sending', locked_until = NOW() + $2 * INTERVAL '1 second'
+                           WHERE id IN (SELECT id FROM notification_deliveries
+                                        WHERE (status = 'pending' AND next_attempt_at <= NOW())
+                                        OR (status = 'sending' AND locked_until < NOW())
+                                        ORDER BY next_attempt_at
+                                        LIMIT $1 FOR UPDATE SKIP LOCKED)
+                           RETURNING `+deliveryColumns, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// MarkDeliverySent records a successful delivery
+func MarkDeliverySent(db *sql.DB, id int64) error {
+	return expectOneRow(db.Exec(`UPDATE notification_deliveries
+                                 SET status = 'sent', attempts = attempts + 1, sent_at = NOW(),
+                                     locked_until = NULL, last_error = NULL
+                                 WHERE id = $1`, id))
+}
+
+// MarkDeliveryFailed records a failed attempt. The message is scheduled again with exponential backoff,
+// or moved to the dead state when the failure is permanent or it ran out of attempts.
+func MarkDeliveryFailed(db *sql.DB, d Delivery, cause error, permanent bool) error {
+	attempts := d.Attempts + 1
+	status, next := DeliveryPending, time.Now().Add(DeliveryBackoff(attempts))
+	if permanent || attempts >= d.MaxAttempts {
+		status, next = DeliveryDead, time.Now()
+	}
+	return expectOneRow(db.Exec(`UPDATE notification_deliveries
+                                 SET status = $1, attempts = $2, next_attempt_at = $3, locked_until = NULL, last_error = $4
+                                 WHERE id = $5`, status, attempts, next, cause.Error(), d.ID))
+}
+
+// RetryDelivery puts a dead message back in the queue with a fresh set of attempts
+func RetryDelivery(db *sql.DB, id int64) error {
+	err := expectOneRow(db.Exec(`UPDATE notification_deliveries SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+                                 WHERE id = $1 AND status = 'dead'`, id))
+	if err == sql.ErrNoRows {
+		var exists bool
+		if db.QueryRow(`SELECT EXISTS (SELECT 1 FROM notification_deliveries WHERE id = $1)`, id).Scan(&exists) == nil && exists {
+			return ErrConflict
+		}
+	}
+	return err
+}
+
+// DeliveryFilter selects deliveries in the admin listing; zero values do not filter
+type DeliveryFilter struct {
+	Status         string
+	Channel        string
+	NotificationID int
+	Limit          int
+}
+
+// GetDeliveries lists the outbox, newest first
+func GetDeliveries(db *sql.DB, f DeliveryFilter) ([]Delivery, error) {
+	rows, err := db.Query(`SELECT `+deliveryColumns+` FROM notification_deliveries
+                           WHERE ($1 = '' OR status = $1) AND ($2 = '' OR channel = $2)
+                           AND ($3 = 0 OR notification_id = $3)
+                           ORDER BY id DESC LIMIT $4`, f.Status, f.Channel, f.NotificationID, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// DeliveryStats counts the outbox messages by channel and status
+func DeliveryStats(db *sql.DB) (map[string]map[string]int, error) {
+	stats := make(map[string]map[string]int, len(Channels))
+	for _, channel := range Channels {
+		stats[channel] = map[string]int{DeliveryPending: 0, DeliverySending: 0, DeliverySent: 0, DeliveryDead: 0}
+	}
+	rows, err := db.Query(`SELECT channel, status, COUNT(*) FROM notification_deliveries GROUP BY channel, status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var channel, status string
+		var count int
+		if err := rows.Scan(&channel, &status, &count); err != nil {
+			return nil, err
+		}
+		stats[channel][status] = count
+	}
+	return stats, rows.Err()
+}
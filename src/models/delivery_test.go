@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeliveryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{8, 64 * time.Minute},
+		{9, 2 * time.Hour}, // 128 minutes, capped
+		{30, 2 * time.Hour},
+		{1000, 2 * time.Hour}, // No overflow however many attempts failed
+	}
+	for _, tt := range tests {
+		if got := DeliveryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("DeliveryBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Device authentication methods
+const (
+	DeviceAuthHMAC        = "hmac"
+	DeviceAuthCertificate = "certificate"
+)
+
+// Device is an in-car telematics unit bound to one vehicle
+type Device struct {
+	ID              int        `json:"id"`
+	VehicleID       int        `json:"vehicle_id"`
+	Name            string     `json:"name"`
+	AuthType        string     `json:"auth_type"`
+	CertFingerprint *string    `json:"cert_fingerprint,omitempty"`
+	Status          string     `json:"status"`
+	CreatedAt       time.Time  `json:"created_at"`
+	RotatedAt       *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	LastSeenAt      *time.Time `json:"last_seen_at,omitempty"`
+	Secret          string     `json:"-"` // HMAC key; only returned to the operator when issued or rotated
+}
+
+const deviceColumns = `id, vehicle_id, name, auth_type, cert_fingerprint, status, created_at, rotated_at,
+                       revoked_at, last_seen_at, COALESCE(secret, '')`
+
+func (d *Device) scanFields() []interface{} {
+	return []interface{}{&d.ID, &d.VehicleID, &d.Name, &d.AuthType, &d.CertFingerprint, &d.Status, &d.CreatedAt,
+		&d.RotatedAt, &d.RevokedAt, &d.LastSeenAt, &d.Secret}
+}
+
+// newDeviceSecret generates a 256-bit HMAC key encoded in hex
+func newDeviceSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// normalizeFingerprint accepts "AA:BB:..." or "aabb..." forms
+func normalizeFingerprint(fp string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fp), ":", ""))
+}
+
+// credentials fills the secret or fingerprint according to the auth type
+func (d *Device) credentials(fingerprint string) error {
+	switch d.AuthType {
+	case DeviceAuthHMAC:
+		secret, err := newDeviceSecret()
+		if err != nil {
+			return err
+		}
+		d.Secret = secret
+		d.CertFingerprint = nil
+	case DeviceAuthCertificate:
+		fp := normalizeFingerprint(fingerprint)
+		if len(fp) != 64 {
+			return &ValidationError{"cert_fingerprint debe ser el SHA-256 del certificado (64 caracteres hex)"}
+		}
+		if _, err := hex.DecodeString(fp); err != nil {
+			return &ValidationError{"cert_fingerprint inválido"}
+		}
+		d.CertFingerprint = &fp
+		d.Secret = ""
+	default:
+		return &ValidationError{"auth_type debe ser hmac o certificate"}
+	}
+	return nil
+}
+
+// IssueDevice registers a unit for a vehicle and generates its credential
+func IssueDevice(db *sql.DB, vehicleID int, name, authType, fingerprint string) (Device, error) {
+	d := Device{VehicleID: vehicleID, Name: strings.TrimSpace(name), AuthType: authType}
+	if err := d.credentials(fingerprint); err != nil {
+		return d, err
+	}
+
+	var secret *string
+	if d.Secret != "" {
+		secret = &d.Secret
+	}
+	query := `INSERT INTO devices (vehicle_id, name, auth_type, secret, cert_fingerprint)
+              VALUES ($1, $2, $3, $4, $5) RETURNING ` + deviceColumns
+	err := db.QueryRow(query, d.VehicleID, d.Name, d.AuthType, secret, d.CertFingerprint).Scan(d.scanFields()...)
+	if isUniqueViolation(err) {
+		return d, ErrConflict
+	}
+	if isForeignKeyViolation(err) {
+		return d, &ValidationError{"vehículo inexistente"}
+	}
+	return d, err
+}
+
+// RotateDeviceCredential replaces the credential of an active unit; the old one stops working at once
+func RotateDeviceCredential(db *sql.DB, id int, fingerprint string) (Device, error) {
+	d, err := GetDevice(db, id)
+	if err != nil {
+		return d, err
+	}
+	if d.Status != "active" {
+		return d, &ValidationError{"el dispositivo está revocado"}
+	}
+	if err := d.credentials(fingerprint); err != nil {
+		return d, err
+	}
+
+	var secret *string
+	if d.Secret != "" {
+		secret = &d.Secret
+	}
+	query := `UPDATE devices SET secret = $1, cert_fingerprint = $2, rotated_at = NOW()
+              WHERE id = $3 AND status = 'active' RETURNING ` + deviceColumns
+	err = db.QueryRow(query, secret, d.CertFingerprint, id).Scan(d.scanFields()...)
+	if isUniqueViolation(err) {
+		return d, ErrConflict
+	}
+	return d, err
+}
+
+// RevokeDevice disables a unit permanently
+func RevokeDevice(db *sql.DB, id int) error {
+	return expectOneRow(db.Exec(`UPDATE devices SET status = 'revoked', revoked_at = NOW(), secret = NULL
+                                 WHERE id = $1 AND status = 'active'`, id))
+}
+
+// GetDevice returns a unit by id, including its secret
+func GetDevice(db *sql.DB, id int) (Device, error) {
+	var d Device
+	err := db.QueryRow(`SELECT `+deviceColumns+` FROM devices WHERE id = $1`, id).Scan(d.scanFields()...)
+	return d, err
+}
+
+// GetActiveDeviceByFingerprint returns the active unit that owns a client certificate
+func GetActiveDeviceByFingerprint(db *sql.DB, fingerprint string) (Device, error) {
+	var d Device
+	err := db.QueryRow(`SELECT `+deviceColumns+` FROM devices
+                        WHERE LOWER(cert_fingerprint) = $1 AND status = 'active'`,
+		normalizeFingerprint(fingerprint)).Scan(d.scanFields()...)
+	return d, err
+}
+
+// GetDevices lists the units, optionally only those of one vehicle
+func GetDevices(db *sql.DB, vehicleID *int) ([]Device, error) {
+	rows, err := db.Query(`SELECT `+deviceColumns+` FROM devices
+                           WHERE ($1::int IS NULL OR vehicle_id = $1) ORDER BY id`, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []Device{}
+	for rows.Next() {
+		var d Device
+		if err := rows.Scan(d.scanFields()...); err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+// TouchDevice records the last time a unit authenticated
+func TouchDevice(db *sql.DB, id int) error {
+	_, err := db.Exec(`UPDATE devices SET last_seen_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+// UseDeviceNonce records the nonce of a signed request until expires and reports whether this is its
+// first use by the device. A nonce whose previous use already expired counts as new.
+func UseDeviceNonce(db DBTX, deviceID int, nonce string, now, expires time.Time) (bool, error) {
+	var id int
+	err := db.QueryRow(`INSERT INTO device_nonces (device_id, nonce, expires_at) VALUES ($1, $2, $3)
+                        ON CONFLICT (device_id, nonce) DO UPDATE SET expires_at = EXCLUDED.expires_at
+                        WHERE device_nonces.expires_at <= $4
+                        RETURNING device_id`, deviceID, nonce, expires, now).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// DeleteExpiredDeviceNonces removes the nonces that can no longer be replayed and returns how many
+func DeleteExpiredDeviceNonces(db DBTX, now time.Time) (int64, error) {
+	res, err := db.Exec(`DELETE FROM device_nonces WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"go-auth-api/src/testdb"
+	"testing"
+	"time"
+)
+
+func TestUseDeviceNonce(t *testing.T) {
+	db := testdb.Open(t)
+	vehicleID := seedTestVehicle(t, db)
+	device, err := IssueDevice(db, vehicleID, "unidad", DeviceAuthHMAC, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other int
+	if err := db.QueryRow(`INSERT INTO devices (vehicle_id, auth_type, secret, status)
+                           VALUES ($1, 'hmac', 'x', 'revoked') RETURNING id`, vehicleID).Scan(&other); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	ttl := 10 * time.Minute
+	steps := []struct {
+		name     string
+		deviceID int
+		nonce    string
+		at       time.Time
+		want     bool
+	}{
+		{"first use", device.ID, "abcdef0123456789", now, true},
+		{"replay", device.ID, "abcdef0123456789", now.Add(time.Minute), false},
+		{"replay just before expiry", device.ID, "abcdef0123456789", now.Add(ttl - time.Second), false},
+		{"same nonce from another device", other, "abcdef0123456789", now.Add(time.Minute), true},
+		{"another nonce", device.ID, "abcdef0123456780", now.Add(time.Minute), true},
+		{"reuse after expiry", device.ID, "abcdef0123456789", now.Add(ttl), true},
+		{"replay of the renewed nonce", device.ID, "abcdef0123456789", now.Add(ttl + time.Minute), false},
+	}
+	for _, s := range steps {
+		got, err := UseDeviceNonce(db, s.deviceID, s.nonce, s.at, s.at.Add(ttl))
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if got != s.want {
+			t.Errorf("%s: UseDeviceNonce = %t, want %t", s.name, got, s.want)
+		}
+	}
+
+	// Only the nonces of the other device and the second one of the first device have expired by then
+	deleted, err := DeleteExpiredDeviceNonces(db, now.Add(ttl+time.Minute))
+	if err != nil || deleted != 2 {
+		t.Errorf("DeleteExpiredDeviceNonces = %d, %v; want 2, nil", deleted, err)
+	}
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Statuses of a subscriber's handling of a domain event
+const (
+	EventHandlerPending = "pending"
+	EventHandlerDone    = "done"
+	EventHandlerDead    = "dead" // Out of attempts; retried by hand
+)
+
+// DomainEvent is an entry of the event outbox. UserID is the customer the event is about and ActorID
+// who caused it; ActorID is nil for devices and background jobs.
+type DomainEvent struct {
+	ID         int64                `json:"id"`
+	Type       string               `json:"type"`
+	UserID     *int                 `json:"user_id,omitempty"`
+	ActorID    *int                 `json:"actor_id,omitempty"`
+	Payload    json.RawMessage      `json:"payload"`
+	OccurredAt time.Time            `json:"occurred_at"`
+	Handlers   []EventHandlerStatus `json:"handlers,omitempty"` // Only filled by GetDomainEvents
+}
+
+const domainEventColumns = `e.id, e.type, e.user_id, e.actor_id, e.payload, e.occurred_at`
+
+func (e *DomainEvent) scanFields() []interface{} {
+	return []interface{}{&e.ID, &e.Type, &e.UserID, &e.ActorID, &e.Payload, &e.OccurredAt}
+}
+
+// EventHandlerStatus is the progress of one subscriber on an event
+type EventHandlerStatus struct {
+	Subscriber    string     `json:"subscriber"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     *string    `json:"last_error,omitempty"`
+	HandledAt     *time.Time `json:"handled_at,omitempty"`
+}
+
+// EventHandlerJob is a claimed handler row with the event to run it on
+type EventHandlerJob struct {
+	DomainEvent
+	Subscriber  string
+	Attempts    int
+	MaxAttempts int
+}
+
+// InsertDomainEvent writes the event to the outbox; pass the transaction of the change so the event
+// exists only if the change was committed. It fills ID and OccurredAt.
+func InsertDomainEvent(db DBTX, e *DomainEvent) error {
+	return db.QueryRow(`INSERT INTO domain_events (type, user_id, actor_id, payload)
+                        VALUES ($1, $2, $3, $4) RETURNING id, occurred_at`, e.Type, e.UserID, e.ActorID, e.Payload).
+		Scan(&e.ID, &e.OccurredAt)
+}
+
+// FanOutDomainEvents creates the handler rows of up to limit new events, one per subscriber returned
+// by subscribers for the event type, and returns how many events it took. Concurrent dispatchers
+// never take the same event.
+func FanOutDomainEvents(db *sql.DB, limit int, subscribers func(eventType string) []string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, type FROM domain_events WHERE fanned_out_at IS NULL
+                           ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		return 0, err
+	}
+	type pending struct {
+		id        int64
+		eventType string
+	}
+	var events []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.eventType); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, p)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, p := range events {
+		names := subscribers(p.eventType)
+		if len(names) > 0 {
+			_, err := tx.Exec(`INSERT INTO domain_event_handlers (event_id, subscriber)
+                               SELECT $1, unnest($2::text[]) ON CONFLICT DO NOTHING`, p.id, pq.Array(names))
+			if err != nil {
+				return 0, err
+			}
+		}
+		if _, err := tx.Exec(`UPDATE domain_events SET fanned_out_at = NOW() WHERE id = $1`, p.id); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), tx.Commit()
+}
+
+// ClaimEventHandler locks the oldest due handler row of one of the given subscribers inside tx, so the
+// handler runs in the same transaction that marks it done. It returns sql.ErrNoRows when there is
+// nothing to do.
+func ClaimEventHandler(tx *sql.Tx, subscribers []string) (EventHandlerJob, error) {
+	var j EventHandlerJob
+	fields := append(j.scanFields(), &j.Subscriber, &j.Attempts, &j.MaxAttempts)
+	err := tx.QueryRow(`SELECT `+domainEventColumns+`, h.subscriber, h.attempts, h.max_attempts
+                        FROM domain_event_handlers h JOIN domain_events e ON e.id = h.event_id
+                        WHERE h.status = 'pending' AND h.next_attempt_at <= NOW() AND h.subscriber = ANY ($1)
+                        ORDER BY h.next_attempt_at, h.event_id
+                        LIMIT 1 FOR UPDATE OF h SKIP LOCKED`, pq.Array(subscribers)).Scan(fields...)
+	return j, err
+}
+
+// CompleteEventHandler marks the claimed handler row as done
+func CompleteEventHandler(tx *sql.Tx, j EventHandlerJob) error {
+	return expectOneRow(tx.Exec(`UPDATE domain_event_handlers
+                                 SET status = 'done', attempts = attempts + 1, handled_at = NOW(), last_error = NULL
+                                 WHERE event_id = $1 AND subscriber = $2`, j.ID, j.Subscriber))
+}
+
+// FailEventHandler records a failed run and schedules the next one with backoff, or marks the row dead
+// when it ran out of attempts
+func FailEventHandler(db *sql.DB, j EventHandlerJob, handlerErr error) error {
+	attempts := j.Attempts + 1
+	status, next := EventHandlerPending, time.Now().Add(DeliveryBackoff(attempts))
+	if attempts >= j.MaxAttempts {
+		status, next = EventHandlerDead, time.Now()
+	}
+	return expectOneRow(db.Exec(`UPDATE domain_event_handlers
+                                 SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4
+                                 WHERE event_id = $5 AND subscriber = $6`,
+		status, attempts, next, handlerErr.Error(), j.ID, j.Subscriber))
+}
+
+// GetDomainEvents lists the newest events, optionally of one type and only those with a subscriber in
+// the given status, each with the progress of its subscribers
+func GetDomainEvents(db *sql.DB, eventType, handlerStatus string, limit int) ([]DomainEvent, error) {
+	rows, err := db.Query(`SELECT `+domainEventColumns+` FROM domain_events e
+                           WHERE ($1 = '' OR e.type = $1)
+                           AND ($2 = '' OR EXISTS (SELECT 1 FROM domain_event_handlers h
+                                                   WHERE h.event_id = e.id AND h.status = $2))
+                           ORDER BY e.id DESC LIMIT $3`, eventType, handlerStatus, limit)
+	if err != nil {
+		return nil, err
+	}
+	events := []DomainEvent{}
+	index := map[int64]int{}
+	var ids []int64
+	for rows.Next() {
+		var e DomainEvent
+		if err := rows.Scan(e.scanFields()...); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[e.ID] = len(events)
+		ids = append(ids, e.ID)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(ids) == 0 {
+		return events, nil
+	}
+
+	rows, err = db.Query(`SELECT event_id, subscriber, status, attempts, next_attempt_at, last_error, handled_at
+                          FROM domain_event_handlers WHERE event_id = ANY ($1)
+                          ORDER BY event_id, subscriber`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var eventID int64
+		var h EventHandlerStatus
+		if err := rows.Scan(&eventID, &h.Subscriber, &h.Status, &h.Attempts, &h.NextAttemptAt, &h.LastError, &h.HandledAt); err != nil {
+			return nil, err
+		}
+		e := &events[index[eventID]]
+		e.Handlers = append(e.Handlers, h)
+	}
+	return events, rows.Err()
+}
+
+// RetryEventHandler schedules a dead handler row again with a fresh set of attempts. It returns
+// ErrConflict if the row is still pending or already done.
+func RetryEventHandler(db *sql.DB, eventID int64, subscriber string) error {
+	err := expectOneRow(db.Exec(`UPDATE domain_event_handlers
+                                 SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+                                 WHERE event_id = $1 AND subscriber = $2 AND status = 'dead'`, eventID, subscriber))
+	if err == sql.ErrNoRows {
+		var exists bool
+		if qErr := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM domain_event_handlers
+                                WHERE event_id = $1 AND subscriber = $2)`, eventID, subscriber).Scan(&exists); qErr != nil {
+			return qErr
+		}
+		if exists {
+			return ErrConflict
+		}
+	}
+	return err
+}
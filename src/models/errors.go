@@ -0,0 +1,34 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// ValidationError reports input that breaks a business rule; controllers answer it with 400
+type ValidationError struct {
+	Msg string
+}
+
+func (e *ValidationError) Error() string { return e.Msg }
+
+// ErrConflict is returned when the change clashes with existing data (duplicates, rows in use)
+var ErrConflict = errors.New("conflicto con los datos existentes")
+
+// isUniqueViolation reports whether err is a PostgreSQL unique_violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err is a PostgreSQL foreign_key_violation
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// ValidCoordinates reports whether lat/lng are a valid WGS84 position
+func ValidCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"database/sql"
+	"go-auth-api/src/geo"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// favoriteNotifyCooldown avoids repeating the availability notice when a vehicle flips status often
+const favoriteNotifyCooldown = time.Hour
+
+// Favorite is a vehicle saved by a user, with the optional availability notice
+type Favorite struct {
+	UserID              int        `json:"user_id"`
+	VehicleID           int        `json:"vehicle_id"`
+	Brand               string     `json:"brand"`
+	Model               string     `json:"model"`
+	LicensePlate        string     `json:"license_plate"`
+	Status              *string    `json:"status"`
+	NotifyWhenAvailable bool       `json:"notify_when_available"`
+	NotifyLatitude      *float64   `json:"notify_latitude"` // Centre of the area the user cares about
+	NotifyLongitude     *float64   `json:"notify_longitude"`
+	NotifyRadiusKm      *float64   `json:"notify_radius_km"`
+	LastNotifiedAt      *time.Time `json:"last_notified_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// FavoriteSettings are the availability notice options of a favorite
+type FavoriteSettings struct {
+	NotifyWhenAvailable bool     `json:"notify_when_available"`
+	Latitude            *float64 `json:"latitude"`
+	Longitude           *float64 `json:"longitude"`
+	RadiusKm            *float64 `json:"radius_km"`
+}
+
+// Validate requires a complete area when the notice is enabled
+func (s FavoriteSettings) Validate() error {
+	if !s.NotifyWhenAvailable {
+		return nil
+	}
+	if s.Latitude == nil || s.Longitude == nil || s.RadiusKm == nil {
+		return &ValidationError{"para el aviso indique latitude, longitude y radius_km"}
+	}
+	if !ValidCoordinates(*s.Latitude, *s.Longitude) {
+		return &ValidationError{"coordenadas inválidas"}
+	}
+	if *s.RadiusKm <= 0 {
+		return &ValidationError{"radius_km debe ser positivo"}
+	}
+	return nil
+}
+
+// SaveFavorite adds the vehicle to the user's favorites or updates its notice options
+func SaveFavorite(db *sql.DB, userID, vehicleID int, s FavoriteSettings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if !s.NotifyWhenAvailable {
+		s.Latitude, s.Longitude, s.RadiusKm = nil, nil, nil
+	}
+	_, err := db.Exec(`INSERT INTO user_favorites (user_id, vehicle_id, notify_when_available, notify_latitude,
+                                                   notify_longitude, notify_radius_km)
+                       VALUES ($1, $2, $3, $4, $5, $6)
+                       ON CONFLICT (user_id, vehicle_id) DO UPDATE
+                       SET notify_when_available = EXCLUDED.notify_when_available,
+                           notify_latitude = EXCLUDED.notify_latitude,
+                           notify_longitude = EXCLUDED.notify_longitude,
+                           notify_radius_km = EXCLUDED.notify_radius_km`,
+		userID, vehicleID, s.NotifyWhenAvailable, s.Latitude, s.Longitude, s.RadiusKm)
+	if isForeignKeyViolation(err) {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// RemoveFavorite removes the vehicle from the user's favorites
+func RemoveFavorite(db *sql.DB, userID, vehicleID int) error {
+	return expectOneRow(db.Exec(`DELETE FROM user_favorites WHERE user_id = $1 AND vehicle_id = $2`, userID, vehicleID))
+}
+
+// GetFavorites lists the user's favorites, most recent first
+func GetFavorites(db *sql.DB, userID int) ([]Favorite, error) {
+	rows, err := db.Query(`SELECT f.user_id, f.vehicle_id, b.name, m.name, v.license_plate, v.status,
+                                  f.notify_when_available, f.notify_latitude, f.notify_longitude, f.notify_radius_km,
+                                  f.last_notified_at, f.created_at
+                           FROM user_favorites f
+                           JOIN vehicles v ON v.id = f.vehicle_id
+                           JOIN brand b ON v.brand_id = b.id
+                           JOIN model m ON v.model_id = m.id
+                           WHERE f.user_id = $1
+                           ORDER BY f.created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	favorites := []Favorite{}
+	for rows.Next() {
+		var f Favorite
+		if err := rows.Scan(&f.UserID, &f.VehicleID, &f.Brand, &f.Model, &f.LicensePlate, &f.Status,
+			&f.NotifyWhenAvailable, &f.NotifyLatitude, &f.NotifyLongitude, &f.NotifyRadiusKm,
+			&f.LastNotifiedAt, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		favorites = append(favorites, f)
+	}
+	return favorites, rows.Err()
+}
+
+// MarkFavorites sets IsFavorited on the vehicles the user saved, in one query
+func MarkFavorites(db *sql.DB, userID int, vehicles []Vehicle) error {
+	if userID == 0 || len(vehicles) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(vehicles))
+	for i := range vehicles {
+		ids[i] = int64(vehicles[i].ID)
+	}
+
+	rows, err := db.Query(`SELECT vehicle_id FROM user_favorites WHERE user_id = $1 AND vehicle_id = ANY($2)`,
+		userID, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	favorited := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		favorited[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range vehicles {
+		vehicles[i].IsFavorited = favorited[vehicles[i].ID]
+	}
+	return nil
+}
+
+// ClaimAvailabilityNotices returns the users to tell that the vehicle became available at (lat, lng):
+// those who opted in, whose area contains the position and who were not told within the cooldown.
+// They are marked as notified; pass a transaction so the mark is kept only with the notifications.
+func ClaimAvailabilityNotices(db DBTX, vehicleID int, lat, lng float64) ([]int, error) {
+	rows, err := db.Query(`SELECT user_id, notify_latitude, notify_longitude, notify_radius_km
+                           FROM user_favorites
+                           WHERE vehicle_id = $1 AND notify_when_available
+                           AND (last_notified_at IS NULL OR last_notified_at < $2)
+                           FOR UPDATE SKIP LOCKED`, vehicleID, time.Now().Add(-favoriteNotifyCooldown))
+	if err != nil {
+		return nil, err
+	}
+	var users []int
+	position := geo.Point{Lat: lat, Lng: lng}
+	for rows.Next() {
+		var userID int
+		var centre geo.Point
+		var radius float64
+		if err := rows.Scan(&userID, &centre.Lat, &centre.Lng, &radius); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if geo.HaversineKm(centre, position) <= radius {
+			users = append(users, userID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if len(users) == 0 {
+		return nil, nil
+	}
+	_, err = db.Exec(`UPDATE user_favorites SET last_notified_at = NOW() WHERE vehicle_id = $1 AND user_id = ANY($2)`,
+		vehicleID, pq.Array(users))
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
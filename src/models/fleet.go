@@ -0,0 +1,267 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrVehicleNotRetired is returned when deleting a vehicle that is still in service
+var ErrVehicleNotRetired = errors.New("el vehículo debe retirarse antes de eliminarse")
+
+// ErrVehicleHasFutureReservations is returned when deleting a vehicle with pending reservations
+var ErrVehicleHasFutureReservations = errors.New("el vehículo tiene reservas futuras")
+
+// ErrDuplicatePlate is returned when another vehicle already uses the license plate
+var ErrDuplicatePlate = errors.New("ya existe un vehículo con esa placa")
+
+// VehiclePricing is the rate card of a vehicle
+type VehiclePricing struct {
+	PricePerMinute float64 `json:"price_per_minute"`
+	PricePerMile   float64 `json:"price_per_mile"`
+}
+
+func (p VehiclePricing) Validate() error {
+	if p.PricePerMinute < 0 || p.PricePerMile < 0 {
+		return &ValidationError{"los precios no pueden ser negativos"}
+	}
+	return nil
+}
+
+// VehicleInput is the payload operators send to create or update a vehicle
+type VehicleInput struct {
+	BrandID        int     `json:"brand_id" binding:"required"`
+	ModelID        int     `json:"model_id" binding:"required"`
+	FuelTypeID     int     `json:"fuel_type_id" binding:"required"`
+	LicensePlate   string  `json:"license_plate" binding:"required"`
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	Type           *string `json:"type"`
+	FuelEfficiency float64 `json:"fuel_efficiency"`
+	IsEconomic     bool    `json:"is_economic"`
+	IsLuxury       bool    `json:"is_luxury"`
+	VehiclePricing
+}
+
+// Validate checks the business rules that do not need the database
+func (in *VehicleInput) Validate() error {
+	in.LicensePlate = strings.ToUpper(strings.TrimSpace(in.LicensePlate))
+	if in.LicensePlate == "" {
+		return &ValidationError{"la placa es requerida"}
+	}
+	if !ValidCoordinates(in.Latitude, in.Longitude) {
+		return &ValidationError{"coordenadas inválidas"}
+	}
+	if in.FuelEfficiency < 0 {
+		return &ValidationError{"la eficiencia de combustible no puede ser negativa"}
+	}
+	return in.VehiclePricing.Validate()
+}
+
+// vehicleWriteError translates constraint violations of vehicle writes
+func vehicleWriteError(err error) error {
+	if isUniqueViolation(err) {
+		return ErrDuplicatePlate
+	}
+	if isForeignKeyViolation(err) {
+		return &ValidationError{"marca, modelo o tipo de combustible inexistente"}
+	}
+	return err
+}
+
+// plateInUse reports whether another vehicle (other than excludeID) already has the plate
+func plateInUse(tx *sql.Tx, plate string, excludeID int) (bool, error) {
+	var exists bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM vehicles WHERE UPPER(license_plate) = UPPER($1) AND id <> $2)`,
+		plate, excludeID).Scan(&exists)
+	return exists, err
+}
+
+// CreateVehicle inserts the vehicle and its pricing in one transaction and returns its id
+func CreateVehicle(db *sql.DB, in VehicleInput) (int, error) {
+	if err := in.Validate(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if taken, err := plateInUse(tx, in.LicensePlate, 0); err != nil {
+		return 0, err
+	} else if taken {
+		return 0, ErrDuplicatePlate
+	}
+
+	var id int
+	query := `INSERT INTO vehicles (brand_id, model_id, fuel_type_id, license_plate, latitude, longitude,
+                                    type, fuel_efficiency, is_economic, is_luxury, status)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	err = tx.QueryRow(query, in.BrandID, in.ModelID, in.FuelTypeID, in.LicensePlate, in.Latitude, in.Longitude,
+		in.Type, in.FuelEfficiency, in.IsEconomic, in.IsLuxury, VehicleStatusAvailable).Scan(&id)
+	if err != nil {
+		return 0, vehicleWriteError(err)
+	}
+
+	if _, err := tx.Exec(`INSERT INTO pricing (vehicle_id, price_per_minute, price_per_mile) VALUES ($1, $2, $3)`,
+		id, in.PricePerMinute, in.PricePerMile); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// UpdateVehicle replaces the editable attributes and pricing of a vehicle
+func UpdateVehicle(db *sql.DB, id int, in VehicleInput) error {
+	if err := in.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if taken, err := plateInUse(tx, in.LicensePlate, id); err != nil {
+		return err
+	} else if taken {
+		return ErrDuplicatePlate
+	}
+
+	query := `UPDATE vehicles SET brand_id = $1, model_id = $2, fuel_type_id = $3, license_plate = $4,
+                     latitude = $5, longitude = $6, type = $7, fuel_efficiency = $8,
+                     is_economic = $9, is_luxury = $10
+              WHERE id = $11`
+	err = expectOneRow(tx.Exec(query, in.BrandID, in.ModelID, in.FuelTypeID, in.LicensePlate, in.Latitude,
+		in.Longitude, in.Type, in.FuelEfficiency, in.IsEconomic, in.IsLuxury, id))
+	if err != nil {
+		return vehicleWriteError(err)
+	}
+
+	if err := upsertPricing(tx, id, in.VehiclePricing); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func upsertPricing(tx *sql.Tx, vehicleID int, p VehiclePricing) error {
+	_, err := tx.Exec(`INSERT INTO pricing (vehicle_id, price_per_minute, price_per_mile) VALUES ($1, $2, $3)
+                       ON CONFLICT (vehicle_id) DO UPDATE
+                       SET price_per_minute = EXCLUDED.price_per_minute, price_per_mile = EXCLUDED.price_per_mile`,
+		vehicleID, p.PricePerMinute, p.PricePerMile)
+	return err
+}
+
+// SetVehiclePricing sets the rate card of an existing vehicle
+func SetVehiclePricing(db *sql.DB, vehicleID int, p VehiclePricing) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM vehicles WHERE id = $1)`, vehicleID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	if err := upsertPricing(tx, vehicleID, p); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Retire takes the vehicle out of service, fills its position and returns its previous status; it
+// stays in the database for history. It returns sql.ErrNoRows if the vehicle does not exist.
+func (v *Vehicle) Retire(db DBTX) (string, error) {
+	var previous string
+	err := db.QueryRow(`WITH old AS (SELECT id, status FROM vehicles WHERE id = $3 FOR UPDATE)
+                        UPDATE vehicles v SET status = $1, retired_at = $2 FROM old WHERE v.id = old.id
+                        RETURNING v.latitude, v.longitude, COALESCE(old.status, '')`,
+		VehicleStatusRetired, time.Now(), v.ID).Scan(&v.Latitude, &v.Longitude, &previous)
+	if err != nil {
+		return "", err
+	}
+	status := VehicleStatusRetired
+	v.Status = &status
+	return previous, nil
+}
+
+// DeleteVehicle removes a retired vehicle that has no future active reservations. It returns the
+// storage keys of the uploaded images that were removed with it, so the caller can delete the blobs
+// once the rows are gone.
+func DeleteVehicle(db *sql.DB, id int) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status sql.NullString
+	// Lock the row so no reservation is taken between the checks and the delete
+	if err := tx.QueryRow(`SELECT status FROM vehicles WHERE id = $1 FOR UPDATE`, id).Scan(&status); err != nil {
+		return nil, err
+	}
+	if status.String != VehicleStatusRetired {
+		return nil, ErrVehicleNotRetired
+	}
+
+	var future bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM reservations
+                       WHERE vehicle_id = $1 AND status = 'activa' AND end_time > $2)`, id, time.Now()).Scan(&future)
+	if err != nil {
+		return nil, err
+	}
+	if future {
+		return nil, ErrVehicleHasFutureReservations
+	}
+
+	// Images added by URL have no keys and nothing to delete from storage
+	rows, err := tx.Query(`DELETE FROM vehicle_images WHERE vehicle_id = $1 RETURNING storage_key, thumbnail_key`, id)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for rows.Next() {
+		var storageKey, thumbnailKey sql.NullString
+		if err := rows.Scan(&storageKey, &thumbnailKey); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		for _, key := range []sql.NullString{storageKey, thumbnailKey} {
+			if key.Valid && key.String != "" {
+				keys = append(keys, key.String)
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range []string{
+		`DELETE FROM pricing WHERE vehicle_id = $1`,
+		`DELETE FROM vehicles WHERE id = $1`,
+	} {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			if isForeignKeyViolation(err) {
+				// Past reservations or payments still reference it: keep it retired
+				return nil, ErrConflict
+			}
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
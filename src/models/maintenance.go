@@ -0,0 +1,313 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrDowntimeOverlapsReservation is returned when a downtime window clashes with an active reservation
+var ErrDowntimeOverlapsReservation = errors.New("la ventana de mantenimiento se solapa con una reserva activa")
+
+// MaintenanceRecord is one service performed on a vehicle
+type MaintenanceRecord struct {
+	ID          int       `json:"id"`
+	VehicleID   int       `json:"vehicle_id"`
+	Type        string    `json:"type" binding:"required"`
+	PerformedAt time.Time `json:"performed_at" binding:"required"`
+	Cost        float64   `json:"cost"`
+	OdometerKm  *float64  `json:"odometer_km"`
+	Notes       *string   `json:"notes"`
+	CreatedBy   *int      `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Validate checks the business rules that do not need the database
+func (m *MaintenanceRecord) Validate() error {
+	m.Type = strings.TrimSpace(m.Type)
+	switch {
+	case m.Type == "":
+		return &ValidationError{"el tipo de mantenimiento es requerido"}
+	case m.PerformedAt.After(time.Now().Add(maxClockSkew)):
+		return &ValidationError{"performed_at no puede estar en el futuro"}
+	case m.Cost < 0:
+		return &ValidationError{"el costo no puede ser negativo"}
+	case m.OdometerKm != nil && *m.OdometerKm < 0:
+		return &ValidationError{"odometer_km no puede ser negativo"}
+	}
+	return nil
+}
+
+// Create stores the record and restarts the schedule of the same type, if the vehicle has one
+func (m *MaintenanceRecord) Create(db *sql.DB) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO maintenance_records (vehicle_id, type, performed_at, cost, odometer_km, notes, created_by)
+              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`
+	err = tx.QueryRow(query, m.VehicleID, m.Type, m.PerformedAt, m.Cost, m.OdometerKm, m.Notes, m.CreatedBy).
+		Scan(&m.ID, &m.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return sql.ErrNoRows
+	}
+	if err != nil {
+		return err
+	}
+
+	// Without a reported odometer the vehicle's current reading is the new baseline
+	_, err = tx.Exec(`UPDATE maintenance_schedules s
+                      SET last_done_at = $1,
+                          last_done_km = COALESCE($2, (SELECT odometer_km FROM vehicles WHERE id = s.vehicle_id)),
+                          last_notified_at = NULL
+                      WHERE s.vehicle_id = $3 AND LOWER(s.type) = LOWER($4) AND s.last_done_at <= $1`,
+		m.PerformedAt, m.OdometerKm, m.VehicleID, m.Type)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetMaintenanceRecords lists the service history of a vehicle, newest first
+func GetMaintenanceRecords(db *sql.DB, vehicleID int) ([]MaintenanceRecord, error) {
+	rows, err := db.Query(`SELECT id, vehicle_id, type, performed_at, cost, odometer_km, notes, created_by, created_at
+                           FROM maintenance_records WHERE vehicle_id = $1 ORDER BY performed_at DESC, id DESC`, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []MaintenanceRecord{}
+	for rows.Next() {
+		var m MaintenanceRecord
+		if err := rows.Scan(&m.ID, &m.VehicleID, &m.Type, &m.PerformedAt, &m.Cost, &m.OdometerKm, &m.Notes,
+			&m.CreatedBy, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, m)
+	}
+	return records, rows.Err()
+}
+
+// Downtime is a window in which a vehicle cannot be reserved
+type Downtime struct {
+	ID        int       `json:"id"`
+	VehicleID int       `json:"vehicle_id"`
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+	Reason    *string   `json:"reason"`
+	CreatedBy *int      `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Create stores the window; it is refused while an active reservation overlaps it
+func (d *Downtime) Create(db *sql.DB) error {
+	if !d.StartTime.Before(d.EndTime) {
+		return &ValidationError{"start_time debe ser anterior a end_time"}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the vehicle so no reservation is taken between the check and the insert
+	var id int
+	if err := tx.QueryRow(`SELECT id FROM vehicles WHERE id = $1 FOR UPDATE`, d.VehicleID).Scan(&id); err != nil {
+		return err
+	}
+	var overlaps bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM reservations
+                       WHERE vehicle_id = $1 AND status = 'activa' AND start_time < $3 AND end_time > $2)`,
+		d.VehicleID, d.StartTime, d.EndTime).Scan(&overlaps)
+	if err != nil {
+		return err
+	}
+	if overlaps {
+		return ErrDowntimeOverlapsReservation
+	}
+
+	err = tx.QueryRow(`INSERT INTO vehicle_downtime (vehicle_id, start_time, end_time, reason, created_by)
+                       VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		d.VehicleID, d.StartTime, d.EndTime, d.Reason, d.CreatedBy).Scan(&d.ID, &d.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetDowntime lists the downtime windows of a vehicle that end after since, oldest first
+func GetDowntime(db *sql.DB, vehicleID int, since time.Time) ([]Downtime, error) {
+	rows, err := db.Query(`SELECT id, vehicle_id, start_time, end_time, reason, created_by, created_at
+                           FROM vehicle_downtime WHERE vehicle_id = $1 AND end_time > $2
+                           ORDER BY start_time`, vehicleID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := []Downtime{}
+	for rows.Next() {
+		var d Downtime
+		if err := rows.Scan(&d.ID, &d.VehicleID, &d.StartTime, &d.EndTime, &d.Reason, &d.CreatedBy, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		windows = append(windows, d)
+	}
+	return windows, rows.Err()
+}
+
+// DeleteDowntime removes a downtime window
+func DeleteDowntime(db *sql.DB, id int) error {
+	return expectOneRow(db.Exec(`DELETE FROM vehicle_downtime WHERE id = $1`, id))
+}
+
+// MaintenanceSchedule triggers a service reminder every IntervalDays and/or every IntervalKm,
+// counted from the last maintenance record of the same type
+type MaintenanceSchedule struct {
+	ID             int        `json:"id"`
+	VehicleID      int        `json:"vehicle_id"`
+	Type           string     `json:"type" binding:"required"`
+	IntervalDays   *int       `json:"interval_days"`
+	IntervalKm     *float64   `json:"interval_km"`
+	LastDoneAt     time.Time  `json:"last_done_at"`
+	LastDoneKm     *float64   `json:"last_done_km"`
+	LastNotifiedAt *time.Time `json:"last_notified_at"`
+	Active         bool       `json:"active"`
+}
+
+// Validate checks the business rules that do not need the database
+func (s *MaintenanceSchedule) Validate() error {
+	s.Type = strings.TrimSpace(s.Type)
+	switch {
+	case s.Type == "":
+		return &ValidationError{"el tipo de mantenimiento es requerido"}
+	case s.IntervalDays == nil && s.IntervalKm == nil:
+		return &ValidationError{"indique interval_days, interval_km o ambos"}
+	case s.IntervalDays != nil && *s.IntervalDays <= 0, s.IntervalKm != nil && *s.IntervalKm <= 0:
+		return &ValidationError{"los intervalos deben ser positivos"}
+	}
+	return nil
+}
+
+const maintenanceScheduleColumns = `id, vehicle_id, type, interval_days, interval_km, last_done_at, last_done_km,
+                                    last_notified_at, active`
+
+func (s *MaintenanceSchedule) scanFields() []interface{} {
+	return []interface{}{&s.ID, &s.VehicleID, &s.Type, &s.IntervalDays, &s.IntervalKm, &s.LastDoneAt, &s.LastDoneKm,
+		&s.LastNotifiedAt, &s.Active}
+}
+
+// Create starts the schedule counting from now and the vehicle's current odometer
+func (s *MaintenanceSchedule) Create(db *sql.DB) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	query := `INSERT INTO maintenance_schedules (vehicle_id, type, interval_days, interval_km, last_done_km)
+              VALUES ($1, $2, $3, $4, (SELECT odometer_km FROM vehicles WHERE id = $1))
+              RETURNING ` + maintenanceScheduleColumns
+	err := db.QueryRow(query, s.VehicleID, s.Type, s.IntervalDays, s.IntervalKm).Scan(s.scanFields()...)
+	if isForeignKeyViolation(err) {
+		return sql.ErrNoRows
+	}
+	return catalogWriteError(err)
+}
+
+// Update changes the intervals and the active flag; the count is kept
+func (s *MaintenanceSchedule) Update(db *sql.DB) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	query := `UPDATE maintenance_schedules SET type = $1, interval_days = $2, interval_km = $3, active = $4
+              WHERE id = $5 RETURNING ` + maintenanceScheduleColumns
+	err := db.QueryRow(query, s.Type, s.IntervalDays, s.IntervalKm, s.Active, s.ID).Scan(s.scanFields()...)
+	return catalogWriteError(err)
+}
+
+// DeleteMaintenanceSchedule removes a schedule
+func DeleteMaintenanceSchedule(db *sql.DB, id int) error {
+	return expectOneRow(db.Exec(`DELETE FROM maintenance_schedules WHERE id = $1`, id))
+}
+
+// GetMaintenanceSchedules lists the schedules of a vehicle
+func GetMaintenanceSchedules(db *sql.DB, vehicleID int) ([]MaintenanceSchedule, error) {
+	rows, err := db.Query(`SELECT `+maintenanceScheduleColumns+` FROM maintenance_schedules
+                           WHERE vehicle_id = $1 ORDER BY type`, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []MaintenanceSchedule{}
+	for rows.Next() {
+		var s MaintenanceSchedule
+		if err := rows.Scan(s.scanFields()...); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// MaintenanceDue is a schedule whose time or mileage threshold has been reached
+type MaintenanceDue struct {
+	MaintenanceSchedule
+	LicensePlate string     `json:"license_plate"`
+	OdometerKm   *float64   `json:"odometer_km"`
+	DueAt        *time.Time `json:"due_at,omitempty"`     // When the time threshold was reached
+	KmOverdue    *float64   `json:"km_overdue,omitempty"` // Kilometres past the mileage threshold
+}
+
+// GetDueMaintenance lists the active schedules past a threshold. With pendingOnly it skips those
+// already reminded since their last service.
+func GetDueMaintenance(db *sql.DB, pendingOnly bool) ([]MaintenanceDue, error) {
+	query := `SELECT s.id, s.vehicle_id, s.type, s.interval_days, s.interval_km, s.last_done_at, s.last_done_km,
+                     s.last_notified_at, s.active, v.license_plate, v.odometer_km, due.due_at, due.km_overdue
+              FROM maintenance_schedules s
+              JOIN vehicles v ON v.id = s.vehicle_id
+              CROSS JOIN LATERAL (SELECT
+                  CASE WHEN s.interval_days IS NOT NULL
+                       THEN s.last_done_at + s.interval_days * INTERVAL '1 day' END AS due_at,
+                  CASE WHEN s.interval_km IS NOT NULL AND v.odometer_km IS NOT NULL
+                       THEN v.odometer_km - COALESCE(s.last_done_km, 0) - s.interval_km END AS km_overdue) due
+              WHERE s.active AND v.status <> 'retired'
+              AND (due.due_at <= NOW() OR due.km_overdue >= 0)
+              AND (NOT $1 OR s.last_notified_at IS NULL)
+              ORDER BY s.vehicle_id, s.type`
+	rows, err := db.Query(query, pendingOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := []MaintenanceDue{}
+	for rows.Next() {
+		var d MaintenanceDue
+		fields := append(d.scanFields(), &d.LicensePlate, &d.OdometerKm, &d.DueAt, &d.KmOverdue)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+		// Only report the thresholds actually crossed
+		if d.DueAt != nil && d.DueAt.After(time.Now()) {
+			d.DueAt = nil
+		}
+		if d.KmOverdue != nil && *d.KmOverdue < 0 {
+			d.KmOverdue = nil
+		}
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// MarkMaintenanceNotified records that the reminder of a schedule was sent
+func MarkMaintenanceNotified(db *sql.DB, scheduleID int) error {
+	return expectOneRow(db.Exec(`UPDATE maintenance_schedules SET last_notified_at = NOW() WHERE id = $1`, scheduleID))
+}
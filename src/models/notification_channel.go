@@ -0,0 +1,139 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// ChannelPreferences tells, per channel, whether the user wants to receive notifications on it
+type ChannelPreferences map[string]bool
+
+// defaultChannelPreferences apply to the channels a user never configured. SMS costs money per
+// message, so it is opt-in.
+var defaultChannelPreferences = ChannelPreferences{ChannelEmail: true, ChannelSMS: false, ChannelPush: true}
+
+// Validate rejects unknown channels
+func (p ChannelPreferences) Validate() error {
+	if len(p) == 0 {
+		return &ValidationError{"indique al menos un canal"}
+	}
+	for channel := range p {
+		if !ValidChannel(channel) {
+			return &ValidationError{"canal inválido: " + channel}
+		}
+	}
+	return nil
+}
+
+// GetChannelPreferences returns the user's preference for every channel, defaults included
+func GetChannelPreferences(db DBTX, userID int) (ChannelPreferences, error) {
+	prefs := make(ChannelPreferences, len(Channels))
+	for channel, enabled := range defaultChannelPreferences {
+		prefs[channel] = enabled
+	}
+
+	rows, err := db.Query(`SELECT channel, enabled FROM notification_channel_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var channel string
+		var enabled bool
+		if err := rows.Scan(&channel, &enabled); err != nil {
+			return nil, err
+		}
+		prefs[channel] = enabled
+	}
+	return prefs, rows.Err()
+}
+
+// SetChannelPreferences stores the given channels; channels not included keep their current value
+func SetChannelPreferences(db *sql.DB, userID int, prefs ChannelPreferences) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for channel, enabled := range prefs {
+		if _, err := tx.Exec(`INSERT INTO notification_channel_preferences (user_id, channel, enabled)
+                              VALUES ($1, $2, $3)
+                              ON CONFLICT (user_id, channel) DO UPDATE
+                              SET enabled = EXCLUDED.enabled, updated_at = NOW()`, userID, channel, enabled); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Push token platforms
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+	PlatformWeb     = "web"
+)
+
+// PushToken is a device registered for push notifications
+type PushToken struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	Token      string    `json:"token"`
+	Platform   string    `json:"platform"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Register stores the token for the user. A token moves to the last user that registers it, so a
+// shared device stops receiving the notifications of whoever used it before.
+func (t *PushToken) Register(db *sql.DB) error {
+	t.Token = strings.TrimSpace(t.Token)
+	if t.Token == "" {
+		return &ValidationError{"token es requerido"}
+	}
+	switch t.Platform {
+	case PlatformAndroid, PlatformIOS, PlatformWeb:
+	default:
+		return &ValidationError{"platform debe ser android, ios o web"}
+	}
+	return db.QueryRow(`INSERT INTO push_tokens (user_id, token, platform) VALUES ($1, $2, $3)
+                        ON CONFLICT (token) DO UPDATE
+                        SET user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, last_seen_at = NOW()
+                        RETURNING id, created_at, last_seen_at`, t.UserID, t.Token, t.Platform).
+		Scan(&t.ID, &t.CreatedAt, &t.LastSeenAt)
+}
+
+// RemovePushToken unregisters one of the user's tokens
+func RemovePushToken(db *sql.DB, userID int, token string) error {
+	return expectOneRow(db.Exec(`DELETE FROM push_tokens WHERE user_id = $1 AND token = $2`, userID, token))
+}
+
+// ForgetPushToken drops a token the push service reported as no longer valid
+func ForgetPushToken(db *sql.DB, token string) error {
+	_, err := db.Exec(`DELETE FROM push_tokens WHERE token = $1`, token)
+	return err
+}
+
+// GetPushTokens lists the user's registered devices, most recently seen first
+func GetPushTokens(db DBTX, userID int) ([]PushToken, error) {
+	rows, err := db.Query(`SELECT id, user_id, token, platform, created_at, last_seen_at FROM push_tokens
+                           WHERE user_id = $1 ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []PushToken{}
+	for rows.Next() {
+		var t PushToken
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Token, &t.Platform, &t.CreatedAt, &t.LastSeenAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
@@ -0,0 +1,285 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"go-auth-api/src/templates"
+	"strings"
+	"time"
+)
+
+// Notification types. Preferences, quiet hours and unsubscribe links work per type.
+const (
+	TypeReservation = "reservation" // Reservation confirmations
+	TypeReminder    = "reminder"    // Pickup and return reminders
+	TypeOverdue     = "overdue"     // Vehicle not returned on time
+	TypeReceipt     = "receipt"
+	TypeSecurity    = "security" // Password resets and account alerts
+	TypeFavorite    = "favorite" // A favorite vehicle became available
+	TypeGeofence    = "geofence"
+	TypeMaintenance = "maintenance"
+	TypeMessage     = "message" // Free-form messages from the staff
+	TypeMarketing   = "marketing"
+)
+
+// NotificationTypes lists every notification type
+var NotificationTypes = []string{TypeReservation, TypeReminder, TypeOverdue, TypeReceipt, TypeSecurity,
+	TypeFavorite, TypeGeofence, TypeMaintenance, TypeMessage, TypeMarketing}
+
+// Transactional types are sent during quiet hours and carry no unsubscribe link
+var transactionalTypes = map[string]bool{TypeReservation: true, TypeReceipt: true, TypeSecurity: true}
+
+// templateTypes is the type of the notifications rendered from each template
+var templateTypes = map[string]string{
+	templates.Notification:         TypeMessage,
+	templates.ReservationConfirmed: TypeReservation,
+	templates.PickupReminder:       TypeReminder,
+	templates.ReturnReminder:       TypeReminder,
+	templates.ReservationOverdue:   TypeOverdue,
+	templates.PaymentReceipt:       TypeReceipt,
+	templates.PasswordReset:        TypeSecurity,
+}
+
+// ValidNotificationType reports whether t is one of NotificationTypes
+func ValidNotificationType(t string) bool {
+	for _, nt := range NotificationTypes {
+		if nt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTransactional reports whether notifications of the type bypass quiet hours
+func IsTransactional(t string) bool {
+	return transactionalTypes[t]
+}
+
+// NotificationSettings are the user's delivery rules on top of the channel preferences
+type NotificationSettings struct {
+	Timezone       string                        `json:"timezone"`    // IANA name, e.g. America/La_Paz
+	QuietStart     *string                       `json:"quiet_start"` // "22:00"; both nil when quiet hours are off
+	QuietEnd       *string                       `json:"quiet_end"`   // "07:00"; may be earlier than the start
+	MarketingOptIn bool                          `json:"marketing_opt_in"`
+	Types          map[string]ChannelPreferences `json:"types"` // Per type overrides of the channel preferences
+}
+
+// defaultNotificationSettings apply to users that never saved theirs
+func defaultNotificationSettings() NotificationSettings {
+	return NotificationSettings{Timezone: "UTC", Types: map[string]ChannelPreferences{}}
+}
+
+// parseClock parses "HH:MM" (seconds are accepted and ignored, as returned by Postgres)
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		if t, err = time.Parse("15:04:05", s); err != nil {
+			return 0, err
+		}
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Validate checks the timezone, the quiet hours and the type overrides
+func (s NotificationSettings) Validate() error {
+	if _, err := time.LoadLocation(s.Timezone); err != nil || s.Timezone == "" {
+		return &ValidationError{"timezone inválida, use un nombre IANA como America/La_Paz"}
+	}
+	if (s.QuietStart == nil) != (s.QuietEnd == nil) {
+		return &ValidationError{"indique quiet_start y quiet_end, o ninguno"}
+	}
+	if s.QuietStart != nil {
+		start, err := parseClock(*s.QuietStart)
+		if err != nil {
+			return &ValidationError{"quiet_start debe tener el formato HH:MM"}
+		}
+		end, err := parseClock(*s.QuietEnd)
+		if err != nil {
+			return &ValidationError{"quiet_end debe tener el formato HH:MM"}
+		}
+		if start == end {
+			return &ValidationError{"quiet_start y quiet_end no pueden ser iguales"}
+		}
+	}
+	for t, prefs := range s.Types {
+		if !ValidNotificationType(t) {
+			return &ValidationError{"tipo de notificación inválido: " + t}
+		}
+		if err := prefs.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QuietUntil reports whether now falls within the quiet hours and, if so, when they end. Quiet hours
+// may wrap midnight (22:00-07:00) and are evaluated in the user's timezone.
+func (s NotificationSettings) QuietUntil(now time.Time) (time.Time, bool) {
+	if s.QuietStart == nil || s.QuietEnd == nil {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, err1 := parseClock(*s.QuietStart)
+	end, err2 := parseClock(*s.QuietEnd)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, false
+	}
+
+	// Wall-clock minutes since midnight; on a DST change the elapsed time since midnight is off by the shift
+	local := now.In(loc)
+	at := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	// Noon always exists, whereas midnight itself is skipped by a DST change in some zones
+	today := time.Date(local.Year(), local.Month(), local.Day(), 12, 0, 0, 0, loc)
+
+	var until time.Time
+	switch {
+	case start < end && at >= start && at < end:
+		until = clockOn(today, end)
+	case start > end && at >= start:
+		until = clockOn(today.AddDate(0, 0, 1), end)
+	case start > end && at < end:
+		until = clockOn(today, end)
+	default:
+		return time.Time{}, false
+	}
+	// In the hour repeated when clocks go back, the end may resolve to its first occurrence, already past
+	if !until.After(now) {
+		_, untilOffset := until.Zone()
+		_, nowOffset := local.Zone()
+		until = until.Add(time.Duration(untilOffset-nowOffset) * time.Second)
+	}
+	return until, true
+}
+
+// clockOn is the first instant of the given day whose wall clock reaches clock. When clocks go forward
+// past it, that is the moment of the change.
+func clockOn(day time.Time, clock time.Duration) time.Time {
+	t := time.Date(day.Year(), day.Month(), day.Day(), int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, day.Location())
+	if time.Duration(t.Hour())*time.Hour+time.Duration(t.Minute())*time.Minute != clock {
+		// time.Date resolves a skipped wall clock with the offset before the change
+		_, t = t.ZoneBounds()
+	}
+	return t
+}
+
+// ChannelEnabled tells whether a notification of the type goes out on the channel: the type override
+// when there is one, otherwise the channel preference. Marketing needs the opt-in as well.
+func (s NotificationSettings) ChannelEnabled(t, channel string, channels ChannelPreferences) bool {
+	if t == TypeMarketing && !s.MarketingOptIn {
+		return false
+	}
+	if enabled, ok := s.Types[t][channel]; ok {
+		return enabled
+	}
+	return channels[channel]
+}
+
+// GetNotificationSettings returns the user's settings, defaults included
+func GetNotificationSettings(db DBTX, userID int) (NotificationSettings, error) {
+	s := defaultNotificationSettings()
+	var start, end sql.NullString
+	err := db.QueryRow(`SELECT timezone, quiet_start, quiet_end, marketing_opt_in FROM notification_settings
+                        WHERE user_id = $1`, userID).Scan(&s.Timezone, &start, &end, &s.MarketingOptIn)
+	if err != nil && err != sql.ErrNoRows {
+		return s, err
+	}
+	if start.Valid && end.Valid {
+		qs, qe := trimSeconds(start.String), trimSeconds(end.String)
+		s.QuietStart, s.QuietEnd = &qs, &qe
+	}
+
+	rows, err := db.Query(`SELECT type, channel, enabled FROM notification_type_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t, channel string
+		var enabled bool
+		if err := rows.Scan(&t, &channel, &enabled); err != nil {
+			return s, err
+		}
+		if s.Types[t] == nil {
+			s.Types[t] = ChannelPreferences{}
+		}
+		s.Types[t][channel] = enabled
+	}
+	return s, rows.Err()
+}
+
+// trimSeconds turns the "22:00:00" returned for TIME columns into "22:00"
+func trimSeconds(clock string) string {
+	if parts := strings.Split(clock, ":"); len(parts) >= 2 {
+		return fmt.Sprintf("%s:%s", parts[0], parts[1])
+	}
+	return clock
+}
+
+// SaveNotificationSettings replaces the user's settings, type overrides included
+func SaveNotificationSettings(db *sql.DB, userID int, s NotificationSettings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO notification_settings (user_id, timezone, quiet_start, quiet_end, marketing_opt_in)
+                      VALUES ($1, $2, $3, $4, $5)
+                      ON CONFLICT (user_id) DO UPDATE
+                      SET timezone = EXCLUDED.timezone, quiet_start = EXCLUDED.quiet_start,
+                          quiet_end = EXCLUDED.quiet_end, marketing_opt_in = EXCLUDED.marketing_opt_in,
+                          updated_at = NOW()`,
+		userID, s.Timezone, s.QuietStart, s.QuietEnd, s.MarketingOptIn)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM notification_type_preferences WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for t, prefs := range s.Types {
+		for channel, enabled := range prefs {
+			if _, err := tx.Exec(`INSERT INTO notification_type_preferences (user_id, type, channel, enabled)
+                                  VALUES ($1, $2, $3, $4)`, userID, t, channel, enabled); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// UnsubscribeEmail stops the emails of one notification type for the user. For marketing it also
+// withdraws the opt-in.
+func UnsubscribeEmail(db *sql.DB, userID int, t string) error {
+	if IsTransactional(t) {
+		return &ValidationError{"los mensajes transaccionales no admiten cancelar la suscripción"}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO notification_type_preferences (user_id, type, channel, enabled)
+                      VALUES ($1, $2, 'email', FALSE)
+                      ON CONFLICT (user_id, type, channel) DO UPDATE SET enabled = FALSE`, userID, t)
+	if isForeignKeyViolation(err) {
+		return sql.ErrNoRows
+	}
+	if err != nil {
+		return err
+	}
+	if t == TypeMarketing {
+		if _, err := tx.Exec(`INSERT INTO notification_settings (user_id, marketing_opt_in) VALUES ($1, FALSE)
+                              ON CONFLICT (user_id) DO UPDATE SET marketing_opt_in = FALSE, updated_at = NOW()`,
+			userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
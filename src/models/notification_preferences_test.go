@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietUntil(t *testing.T) {
+	utc := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name       string
+		timezone   string
+		start, end string // Empty when quiet hours are off
+		now        time.Time
+		want       time.Time // Zero when now is outside the quiet hours
+	}{
+		{"quiet hours off", "America/New_York", "", "", utc(10, 20, 3, 0), time.Time{}},
+		{"before midnight", "America/New_York", "22:00", "07:00", utc(10, 20, 3, 30), utc(10, 20, 11, 0)},
+		{"after midnight", "America/New_York", "22:00", "07:00", utc(10, 20, 7, 0), utc(10, 20, 11, 0)},
+		{"at the start", "America/New_York", "22:00", "07:00", utc(10, 20, 2, 0), utc(10, 20, 11, 0)},
+		{"at the end", "America/New_York", "22:00", "07:00", utc(10, 20, 11, 0), time.Time{}},
+		{"daytime", "America/New_York", "22:00", "07:00", utc(10, 20, 16, 0), time.Time{}},
+		{"same day range", "America/New_York", "13:00", "15:00", utc(10, 20, 18, 59), utc(10, 20, 19, 0)},
+		{"seconds in the database", "UTC", "13:00:00", "15:00:00", utc(10, 20, 14, 0), utc(10, 20, 15, 0)},
+		{"unknown timezone is UTC", "Mars/Olympus", "22:00", "07:00", utc(10, 20, 23, 0), utc(10, 21, 7, 0)},
+
+		// Clocks go forward on March 8 at 02:00: 03:30 EDT is only 2h30m after midnight
+		{"after a spring forward", "America/New_York", "01:00", "03:00", utc(3, 8, 7, 30), time.Time{}},
+		// 02:30 never happens that night, so the quiet hours end when clocks jump to 03:00
+		{"end skipped by a spring forward", "America/New_York", "22:00", "02:30", utc(3, 8, 4, 0), utc(3, 8, 7, 0)},
+		// Clocks go back on November 1 at 02:00: 06:30 EST is 7h30m after midnight
+		{"after a fall back", "America/New_York", "22:00", "07:00", utc(11, 1, 11, 30), utc(11, 1, 12, 0)},
+		// 01:15 EST, the second time 01:15 comes round; the end is the 01:30 that follows
+		{"repeated hour of a fall back", "America/New_York", "01:00", "01:30", utc(11, 1, 6, 15), utc(11, 1, 6, 30)},
+		// In Santiago midnight is skipped on September 6; the end is still that morning
+		{"midnight skipped", "America/Santiago", "22:00", "07:00", utc(9, 6, 6, 0), utc(9, 6, 10, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := time.LoadLocation(tt.timezone); err != nil && tt.timezone != "Mars/Olympus" {
+				t.Skipf("no timezone data: %v", err)
+			}
+			s := NotificationSettings{Timezone: tt.timezone}
+			if tt.start != "" {
+				s.QuietStart, s.QuietEnd = &tt.start, &tt.end
+			}
+			until, quiet := s.QuietUntil(tt.now)
+			if quiet != !tt.want.IsZero() || !until.Equal(tt.want) {
+				t.Errorf("QuietUntil(%s) = %s, %t; want %s, %t",
+					tt.now, until.UTC(), quiet, tt.want, !tt.want.IsZero())
+			}
+		})
+	}
+}
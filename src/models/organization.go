@@ -0,0 +1,93 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Roles of a user within an organization
+const (
+	OrganizationMember = "member"
+	OrganizationAdmin  = "admin" // Manages the webhook subscriptions of the organization
+)
+
+// Organization is a corporate client whose employees rent under its account
+type Organization struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name" binding:"required"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// OrganizationMembership is the organization a user belongs to and their role in it
+type OrganizationMembership struct {
+	OrganizationID int    `json:"organization_id"`
+	UserID         int    `json:"user_id"`
+	Role           string `json:"role"`
+}
+
+// Create stores the organization; names are unique
+func (o *Organization) Create(db *sql.DB) error {
+	o.Name = strings.TrimSpace(o.Name)
+	if o.Name == "" {
+		return &ValidationError{"el nombre es requerido"}
+	}
+	err := db.QueryRow(`INSERT INTO organizations (name) VALUES ($1) RETURNING id, created_at`, o.Name).
+		Scan(&o.ID, &o.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// GetOrganizations lists the organizations with their number of members
+func GetOrganizations(db *sql.DB) ([]Organization, error) {
+	rows, err := db.Query(`SELECT o.id, o.name, COUNT(u.id), o.created_at
+                           FROM organizations o LEFT JOIN users u ON u.organization_id = o.id
+                           GROUP BY o.id ORDER BY o.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	organizations := []Organization{}
+	for rows.Next() {
+		var o Organization
+		if err := rows.Scan(&o.ID, &o.Name, &o.MemberCount, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, o)
+	}
+	return organizations, rows.Err()
+}
+
+// SetOrganizationMember adds the user to the organization, or changes their role. A user belongs to
+// one organization at most; adding them to another one moves them.
+func SetOrganizationMember(db *sql.DB, organizationID, userID int, role string) error {
+	if role != OrganizationMember && role != OrganizationAdmin {
+		return &ValidationError{"role debe ser member o admin"}
+	}
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM organizations WHERE id = $1)`, organizationID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return expectOneRow(db.Exec(`UPDATE users SET organization_id = $1, organization_role = $2 WHERE id = $3`,
+		organizationID, role, userID))
+}
+
+// RemoveOrganizationMember takes the user out of the organization
+func RemoveOrganizationMember(db *sql.DB, organizationID, userID int) error {
+	return expectOneRow(db.Exec(`UPDATE users SET organization_id = NULL, organization_role = NULL
+                                 WHERE id = $1 AND organization_id = $2`, userID, organizationID))
+}
+
+// GetOrganizationMembership returns the user's organization; sql.ErrNoRows when they have none
+func GetOrganizationMembership(db DBTX, userID int) (OrganizationMembership, error) {
+	m := OrganizationMembership{UserID: userID}
+	err := db.QueryRow(`SELECT organization_id, COALESCE(organization_role, 'member') FROM users
+                        WHERE id = $1 AND organization_id IS NOT NULL`, userID).Scan(&m.OrganizationID, &m.Role)
+	return m, err
+}
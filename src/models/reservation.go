@@ -6,6 +6,9 @@ import (
 	"time"
 )
 
+// ErrVehicleUnavailable se devuelve cuando otra reserva o un mantenimiento ocupa el rango pedido
+var ErrVehicleUnavailable = errors.New("el vehículo no está disponible en el rango de tiempo solicitado")
+
 type Reservation struct {
 	ID        int       `json:"id"`
 	UserID    int       `json:"user_id"`
@@ -15,35 +18,41 @@ type Reservation struct {
 	Status    string    `json:"status"` // activa, completada, cancelada
 }
 
-// Crear una nueva reserva
-func (r *Reservation) Create(db *sql.DB) error {
+// Crear una nueva reserva; db debe ser una transacción para que el bloqueo del vehículo dure
+// hasta el INSERT
+func (r *Reservation) Create(db DBTX) error {
+	// Bloquear el vehículo: dos reservas simultáneas no pueden pasar ambas la verificación
+	var id int
+	if err := db.QueryRow(`SELECT id FROM vehicles WHERE id = $1 FOR UPDATE`, r.VehicleID).Scan(&id); err != nil {
+		return err
+	}
+
 	// Verificar disponibilidad del vehículo
-	query := `SELECT COUNT(*) FROM reservations WHERE vehicle_id = $1 AND status = 'activa' AND 
-              ((start_time <= $2 AND end_time >= $2) OR (start_time <= $3 AND end_time >= $3))`
-	var count int
-	err := db.QueryRow(query, r.VehicleID, r.StartTime, r.EndTime).Scan(&count)
+	blocked, err := vehicleBlocked(db, r.VehicleID, r.StartTime, r.EndTime)
 	if err != nil {
 		return err
 	}
 
-	if count > 0 {
-		return errors.New("el vehículo no está disponible en el rango de tiempo solicitado")
+	if blocked {
+		return ErrVehicleUnavailable
 	}
 
 	// Crear la reserva
-	query = `INSERT INTO reservations (user_id, vehicle_id, start_time, end_time, status) 
+	query := `INSERT INTO reservations (user_id, vehicle_id, start_time, end_time, status) 
              VALUES ($1, $2, $3, $4, 'activa') RETURNING id`
 	return db.QueryRow(query, r.UserID, r.VehicleID, r.StartTime, r.EndTime).Scan(&r.ID)
 }
 func (r *Reservation) GetByID(db *sql.DB, id int) error {
-	query := `SELECT user_id, vehicle_id, start_time, end_time, status 
+	query := `SELECT id, user_id, vehicle_id, start_time, end_time, status 
               FROM reservations WHERE id = $1`
-	return db.QueryRow(query, id).Scan(&r.UserID, &r.VehicleID, &r.StartTime, &r.EndTime, &r.Status)
+	return db.QueryRow(query, id).Scan(&r.ID, &r.UserID, &r.VehicleID, &r.StartTime, &r.EndTime, &r.Status)
 }
 
-func (r *Reservation) GetAll(db *sql.DB) ([]Reservation, error) {
-	query := `SELECT id, user_id, vehicle_id, start_time, end_time, status FROM reservations`
-	rows, err := db.Query(query)
+// GetAll lista las reservas, solo las del usuario userID si no es nil
+func (r *Reservation) GetAll(db *sql.DB, userID *int) ([]Reservation, error) {
+	query := `SELECT id, user_id, vehicle_id, start_time, end_time, status FROM reservations
+              WHERE ($1::int IS NULL OR user_id = $1) ORDER BY id`
+	rows, err := db.Query(query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -63,41 +72,104 @@ func (r *Reservation) GetAll(db *sql.DB) ([]Reservation, error) {
 	return reservations, nil
 }
 
-// Verificar la disponibilidad del vehículo en el rango de tiempo solicitado
-// Verificar la disponibilidad del vehículo en el rango de tiempo solicitado
+// reservableCond es la condición SQL para que el vehículo con alias v pueda reservarse en [from, to):
+// su estado es available y ninguna reserva activa ni ventana de mantenimiento se solapa. La comparten
+// las reservas, la verificación de disponibilidad y el filtro de SearchVehicles, y ComputeAvailability
+// aplica la misma regla.
+func reservableCond(from, to string) string {
+	return reservableCondExcept(from, to, "0")
+}
+
+// reservableCondExcept es reservableCond sin contar la reserva con id except, para cambiar las fechas
+// de una reserva sin que choque consigo misma
+func reservableCondExcept(from, to, except string) string {
+	return `COALESCE(v.status, 'available') = 'available'
+                AND NOT EXISTS (SELECT 1 FROM reservations r
+                                WHERE r.vehicle_id = v.id AND r.status = 'activa' AND r.id <> ` + except + `
+                                AND r.start_time < ` + to + ` AND r.end_time > ` + from + `)
+                AND NOT EXISTS (SELECT 1 FROM vehicle_downtime d
+                                WHERE d.vehicle_id = v.id AND d.start_time < ` + to + ` AND d.end_time > ` + from + `)`
+}
+
+// vehicleBlocked indica si el vehículo no puede reservarse en [start, end): no está available o una
+// reserva activa o una ventana de mantenimiento se solapa. Devuelve sql.ErrNoRows si el vehículo no existe.
+func vehicleBlocked(db DBTX, vehicleID int, start, end time.Time) (bool, error) {
+	query := `SELECT NOT (` + reservableCond("$2", "$3") + `) FROM vehicles v WHERE v.id = $1`
+	var blocked bool
+	err := db.QueryRow(query, vehicleID, start, end).Scan(&blocked)
+	return blocked, err
+}
+
+// Verificar la disponibilidad del vehículo en el rango de tiempo solicitado,
+// incluidas las ventanas de mantenimiento; sql.ErrNoRows si el vehículo no existe
 func (r *Reservation) IsVehicleAvailable(db *sql.DB) (bool, error) {
-	query := `SELECT COUNT(*) FROM reservations 
-              WHERE vehicle_id = $1 
-              AND status = 'activa' 
-              AND ((start_time <= $2 AND end_time >= $2) 
-              OR (start_time <= $3 AND end_time >= $3) 
-              OR ($2 <= start_time AND $3 >= start_time))`
-
-	var count int
-	err := db.QueryRow(query, r.VehicleID, r.StartTime, r.EndTime).Scan(&count)
+	blocked, err := vehicleBlocked(db, r.VehicleID, r.StartTime, r.EndTime)
 	if err != nil {
 		return false, err
 	}
+	return !blocked, nil
+}
 
-	if count > 0 {
-		return false, nil
-	}
+// reservationTransitions son los cambios de estado permitidos; completada y cancelada son finales
+var reservationTransitions = map[string][]string{"activa": {"completada", "cancelada"}}
 
-	return true, nil
+// CanChangeReservationStatus indica si una reserva puede pasar de from a to; conservar el estado
+// siempre se permite
+func CanChangeReservationStatus(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, s := range reservationTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
 }
 
-// Actualizar reserva
-func (r *Reservation) Update(db *sql.DB, reservationID int) error {
+// Actualizar reserva. Si queda activa con otras fechas, o vuelve a activarse, se bloquea el vehículo
+// y se verifica la disponibilidad como en Create, sin contar la propia reserva; db debe ser una
+// transacción para que el bloqueo dure hasta el UPDATE
+func (r *Reservation) Update(db DBTX, reservationID int) error {
+	var current Reservation
+	err := db.QueryRow(`SELECT vehicle_id, start_time, end_time, status FROM reservations WHERE id = $1 FOR UPDATE`,
+		reservationID).Scan(&current.VehicleID, &current.StartTime, &current.EndTime, &current.Status)
+	if err != nil {
+		return err
+	}
+
+	reserving := r.Status == "activa" &&
+		(current.Status != "activa" || !r.StartTime.Equal(current.StartTime) || !r.EndTime.Equal(current.EndTime))
+	if reserving {
+		var id int
+		if err := db.QueryRow(`SELECT id FROM vehicles WHERE id = $1 FOR UPDATE`, current.VehicleID).Scan(&id); err != nil {
+			return err
+		}
+		// Un vehículo reservado por esta misma reserva sigue disponible para ella
+		query := `SELECT NOT EXISTS (SELECT 1 FROM vehicles v WHERE v.id = $1 AND ` +
+			reservableCondExcept("$2", "$3", "$4") + `)`
+		var blocked bool
+		if err := db.QueryRow(query, current.VehicleID, r.StartTime, r.EndTime, reservationID).Scan(&blocked); err != nil {
+			return err
+		}
+		if blocked {
+			return ErrVehicleUnavailable
+		}
+	}
+
 	query := `UPDATE reservations SET start_time = $1, end_time = $2, status = $3 
               WHERE id = $4`
-	_, err := db.Exec(query, r.StartTime, r.EndTime, r.Status, reservationID)
-	return err
+	return expectOneRow(db.Exec(query, r.StartTime, r.EndTime, r.Status, reservationID))
 }
 
-// Eliminar reserva
-func DeleteReservation(db *sql.DB, id int) error {
+// Eliminar reserva. Devuelve ErrConflict si ya tiene historial (telemetría, comandos, listas de
+// condición, pagos, reseñas...) que la referencia, y sql.ErrNoRows si no existe
+func DeleteReservation(db DBTX, id int) error {
 	query := `DELETE FROM reservations WHERE id = $1`
-	_, err := db.Exec(query, id)
+	err := expectOneRow(db.Exec(query, id))
+	if isForeignKeyViolation(err) {
+		return ErrConflict
+	}
 	return err
 }
 
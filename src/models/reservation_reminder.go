@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Reservation reminder kinds
+const (
+	ReminderPickup  = "pickup"  // Before the reservation starts
+	ReminderReturn  = "return"  // Before the reservation ends
+	ReminderOverdue = "overdue" // The reservation ended and the vehicle was not returned
+)
+
+// DueReminder is an active reservation that needs a reminder, with what the message shows
+type DueReminder struct {
+	ReservationID int
+	UserID        int
+	Vehicle       string // Brand and model
+	LicensePlate  string
+	StartTime     time.Time
+	EndTime       time.Time
+}
+
+// GetDueReminders lists the active reservations whose start (pickup) or end (return, overdue) falls in
+// [from, to) and that did not get the reminder of that kind yet
+func GetDueReminders(db *sql.DB, kind string, from, to time.Time, limit int) ([]DueReminder, error) {
+	column := "r.end_time"
+	if kind == ReminderPickup {
+		column = "r.start_time"
+	}
+	query := `SELECT r.id, r.user_id, b.name || ' ' || m.name, v.license_plate, r.start_time, r.end_time
+              FROM reservations r
+              JOIN vehicles v ON v.id = r.vehicle_id
+              JOIN brand b ON b.id = v.brand_id
+              JOIN model m ON m.id = v.model_id
+              WHERE r.status = 'activa' AND ` + column + ` >= $2 AND ` + column + ` < $3
+              AND NOT EXISTS (SELECT 1 FROM reservation_reminders rr WHERE rr.reservation_id = r.id AND rr.kind = $1)
+              ORDER BY ` + column + ` LIMIT $4`
+	rows, err := db.Query(query, kind, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []DueReminder
+	for rows.Next() {
+		var d DueReminder
+		if err := rows.Scan(&d.ReservationID, &d.UserID, &d.Vehicle, &d.LicensePlate, &d.StartTime, &d.EndTime); err != nil {
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// ClaimReminder records that the reminder is being sent. It returns false when it was already
+// recorded, by an earlier run or by another replica. Use the transaction that sends the notification
+// so a failed send can be tried again.
+func ClaimReminder(db DBTX, reservationID int, kind string) (bool, error) {
+	var claimed bool
+	err := db.QueryRow(`INSERT INTO reservation_reminders (reservation_id, kind) VALUES ($1, $2)
+                        ON CONFLICT DO NOTHING RETURNING TRUE`, reservationID, kind).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return claimed, err
+}
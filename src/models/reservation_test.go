@@ -0,0 +1,123 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"go-auth-api/src/testdb"
+	"testing"
+	"time"
+)
+
+func TestCanChangeReservationStatus(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"activa", "activa", true},
+		{"activa", "cancelada", true},
+		{"activa", "completada", true},
+		{"cancelada", "activa", false},
+		{"cancelada", "completada", false},
+		{"completada", "activa", false},
+		{"completada", "cancelada", false},
+		{"cancelada", "cancelada", true},
+		{"activa", "pendiente", false},
+	}
+	for _, tt := range tests {
+		if got := CanChangeReservationStatus(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanChangeReservationStatus(%q, %q) = %t, want %t", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestReservationUpdateChecksAvailability(t *testing.T) {
+	db := testdb.Open(t)
+	vehicleID := seedTestVehicle(t, db)
+	user := User{Username: "ana", Password: "secreto123", Email: "ana@example.com"}
+	if err := user.Register(db); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	hour := func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+	book := func(start, end time.Time) Reservation {
+		r := Reservation{UserID: user.ID, VehicleID: vehicleID, StartTime: start, EndTime: end}
+		if err := r.Create(db); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	mine := book(hour(10), hour(12))
+	book(hour(14), hour(16))
+
+	steps := []struct {
+		name       string
+		start, end time.Time
+		status     string
+		want       error
+	}{
+		{"shrink inside its own range", hour(10), hour(11), "activa", nil},
+		{"extend into a free slot", hour(9), hour(13), "activa", nil},
+		{"move onto another reservation", hour(13), hour(15), "activa", ErrVehicleUnavailable},
+		{"end right where the next one starts", hour(10), hour(14), "activa", nil},
+		{"cancel", hour(10), hour(14), "cancelada", nil},
+		{"book the freed slot", hour(11), hour(13), "", nil},
+		{"reactivate over the new reservation", hour(10), hour(14), "activa", ErrVehicleUnavailable},
+	}
+	for _, s := range steps {
+		if s.status == "" {
+			book(s.start, s.end)
+			continue
+		}
+		update := Reservation{StartTime: s.start, EndTime: s.end, Status: s.status}
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = update.Update(tx, mine.ID)
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			tx.Rollback()
+		}
+		if !errors.Is(err, s.want) {
+			t.Errorf("%s: Update = %v, want %v", s.name, err, s.want)
+		}
+	}
+
+	missing := Reservation{StartTime: hour(1), EndTime: hour(2), Status: "activa"}
+	if err := missing.Update(db, mine.ID+100); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Update of a missing reservation = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestDeleteReservation(t *testing.T) {
+	db := testdb.Open(t)
+	vehicleID := seedTestVehicle(t, db)
+	user := User{Username: "ana", Password: "secreto123", Email: "ana@example.com"}
+	if err := user.Register(db); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(time.Hour)
+	unused := Reservation{UserID: user.ID, VehicleID: vehicleID, StartTime: start, EndTime: start.Add(time.Hour)}
+	started := Reservation{UserID: user.ID, VehicleID: vehicleID, StartTime: start.Add(2 * time.Hour), EndTime: start.Add(3 * time.Hour)}
+	for _, r := range []*Reservation{&unused, &started} {
+		if err := r.Create(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Telemetry recorded during the trip references the reservation
+	if _, err := db.Exec(`INSERT INTO vehicle_telemetry (vehicle_id, reservation_id, recorded_at, latitude, longitude)
+                          VALUES ($1, $2, $3, 18.47, -69.9)`, vehicleID, started.ID, started.StartTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeleteReservation(db, started.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("DeleteReservation with telemetry = %v, want ErrConflict", err)
+	}
+	if err := DeleteReservation(db, unused.ID); err != nil {
+		t.Errorf("DeleteReservation = %v, want nil", err)
+	}
+	if err := DeleteReservation(db, unused.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("DeleteReservation of a deleted reservation = %v, want sql.ErrNoRows", err)
+	}
+}
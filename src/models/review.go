@@ -0,0 +1,235 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Review statuses
+const (
+	ReviewPublished = "published"
+	ReviewHidden    = "hidden" // Removed by moderation; excluded from listings and from the rating
+)
+
+const (
+	DefaultReviewPageSize = 20
+	MaxReviewPageSize     = 100
+	maxReviewComment      = 2000
+)
+
+// ErrReviewNotAllowed is returned when reviewing a reservation that has not been completed
+var ErrReviewNotAllowed = errors.New("solo se pueden reseñar reservas completadas")
+
+// Review is the rating a customer leaves for the vehicle of a completed reservation
+type Review struct {
+	ID               int        `json:"id"`
+	ReservationID    int        `json:"reservation_id"`
+	VehicleID        int        `json:"vehicle_id"`
+	UserID           int        `json:"user_id"`
+	Username         string     `json:"username"`
+	Rating           int        `json:"rating"`
+	Comment          *string    `json:"comment"`
+	Status           string     `json:"status"`
+	ModerationReason *string    `json:"moderation_reason,omitempty"`
+	ModeratedBy      *int       `json:"moderated_by,omitempty"`
+	ModeratedAt      *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+const reviewSelect = `SELECT r.id, r.reservation_id, r.vehicle_id, r.user_id, u.username, r.rating, r.comment, r.status,
+                             r.moderation_reason, r.moderated_by, r.moderated_at, r.created_at
+                      FROM vehicle_reviews r
+                      JOIN users u ON u.id = r.user_id`
+
+func (r *Review) scanFields() []interface{} {
+	return []interface{}{&r.ID, &r.ReservationID, &r.VehicleID, &r.UserID, &r.Username, &r.Rating, &r.Comment,
+		&r.Status, &r.ModerationReason, &r.ModeratedBy, &r.ModeratedAt, &r.CreatedAt}
+}
+
+// ReviewPage is one page of reviews plus the cursor for the next one
+type ReviewPage struct {
+	Reviews    []Review `json:"reviews"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// lockVehicleRating locks the vehicle row until the transaction ends. Every change to a vehicle's
+// reviews takes it before touching them, so the aggregate computed by refreshVehicleRating always
+// sees the reviews committed by the previous writer instead of overwriting them with a stale count.
+func lockVehicleRating(tx *sql.Tx, vehicleID int) error {
+	var id int
+	return tx.QueryRow(`SELECT id FROM vehicles WHERE id = $1 FOR UPDATE`, vehicleID).Scan(&id)
+}
+
+// refreshVehicleRating recomputes the stored rating aggregate from the published reviews
+func refreshVehicleRating(tx *sql.Tx, vehicleID int) error {
+	_, err := tx.Exec(`UPDATE vehicles v SET rating = agg.avg, rating_count = agg.count
+                       FROM (SELECT COALESCE(ROUND(AVG(rating)::numeric, 2), 0)::float8 AS avg, COUNT(*) AS count
+                             FROM vehicle_reviews WHERE vehicle_id = $1 AND status = 'published') agg
+                       WHERE v.id = $1`, vehicleID)
+	return err
+}
+
+// CreateReview stores the review of a reservation. Only the customer of the reservation can review it,
+// only once and only after it was completed.
+func CreateReview(db *sql.DB, userID, reservationID, rating int, comment *string) (Review, error) {
+	var review Review
+	if rating < 1 || rating > 5 {
+		return review, &ValidationError{"rating debe estar entre 1 y 5"}
+	}
+	if comment != nil {
+		trimmed := strings.TrimSpace(*comment)
+		if len(trimmed) > maxReviewComment {
+			return review, &ValidationError{fmt.Sprintf("el comentario no puede superar %d caracteres", maxReviewComment)}
+		}
+		comment = &trimmed
+		if trimmed == "" {
+			comment = nil
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return review, err
+	}
+	defer tx.Rollback()
+
+	var owner, vehicleID int
+	var status string
+	err = tx.QueryRow(`SELECT user_id, vehicle_id, status FROM reservations WHERE id = $1 FOR UPDATE`, reservationID).
+		Scan(&owner, &vehicleID, &status)
+	if err != nil {
+		return review, err
+	}
+	if owner != userID {
+		// Do not reveal reservations of other customers
+		return review, sql.ErrNoRows
+	}
+	if status != "completada" {
+		return review, ErrReviewNotAllowed
+	}
+	if err := lockVehicleRating(tx, vehicleID); err != nil {
+		return review, err
+	}
+
+	err = tx.QueryRow(`INSERT INTO vehicle_reviews (reservation_id, vehicle_id, user_id, rating, comment)
+                       VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		reservationID, vehicleID, userID, rating, comment).Scan(&review.ID)
+	if isUniqueViolation(err) {
+		return review, ErrConflict
+	}
+	if err != nil {
+		return review, err
+	}
+	if err := refreshVehicleRating(tx, vehicleID); err != nil {
+		return review, err
+	}
+	if err := tx.QueryRow(reviewSelect+` WHERE r.id = $1`, review.ID).Scan(review.scanFields()...); err != nil {
+		return review, err
+	}
+	return review, tx.Commit()
+}
+
+// GetVehicleReviews lists the published reviews of a vehicle, newest first
+func GetVehicleReviews(db *sql.DB, vehicleID, limit int, cursor string) (ReviewPage, error) {
+	return listReviews(db, "r.vehicle_id = $1 AND r.status = 'published'", vehicleID, limit, cursor)
+}
+
+// GetReviewsByStatus lists every review with the status (any status when empty), newest first
+func GetReviewsByStatus(db *sql.DB, status string, limit int, cursor string) (ReviewPage, error) {
+	return listReviews(db, "($1 = '' OR r.status = $1)", status, limit, cursor)
+}
+
+// listReviews pages through the reviews matching cond (whose only parameter is $1) by descending id
+func listReviews(db *sql.DB, cond string, arg interface{}, limit int, cursor string) (ReviewPage, error) {
+	page := ReviewPage{Reviews: []Review{}}
+	if limit <= 0 {
+		limit = DefaultReviewPageSize
+	}
+	if limit > MaxReviewPageSize {
+		limit = MaxReviewPageSize
+	}
+	before, err := decodeIDCursor(cursor)
+	if err != nil {
+		return page, err
+	}
+
+	rows, err := db.Query(reviewSelect+` WHERE `+cond+` AND ($2 = 0 OR r.id < $2) ORDER BY r.id DESC LIMIT $3`,
+		arg, before, limit+1)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r Review
+		if err := rows.Scan(r.scanFields()...); err != nil {
+			return page, err
+		}
+		page.Reviews = append(page.Reviews, r)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if len(page.Reviews) > limit {
+		page.Reviews = page.Reviews[:limit]
+		page.NextCursor = encodeIDCursor(int64(page.Reviews[limit-1].ID))
+	}
+	return page, nil
+}
+
+// ModerateReview hides or republishes a review and refreshes the vehicle's rating
+func ModerateReview(db *sql.DB, reviewID, staffID int, status string, reason *string) error {
+	if status != ReviewPublished && status != ReviewHidden {
+		return &ValidationError{"status debe ser published o hidden"}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var vehicleID int
+	if err := tx.QueryRow(`SELECT vehicle_id FROM vehicle_reviews WHERE id = $1`, reviewID).Scan(&vehicleID); err != nil {
+		return err
+	}
+	if err := lockVehicleRating(tx, vehicleID); err != nil {
+		return err
+	}
+	if err := expectOneRow(tx.Exec(`UPDATE vehicle_reviews SET status = $1, moderation_reason = $2, moderated_by = $3,
+                                                                moderated_at = NOW()
+                                    WHERE id = $4`, status, reason, staffID, reviewID)); err != nil {
+		return err
+	}
+	if err := refreshVehicleRating(tx, vehicleID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteReview removes a review and refreshes the vehicle's rating
+func DeleteReview(db *sql.DB, reviewID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var vehicleID int
+	if err := tx.QueryRow(`SELECT vehicle_id FROM vehicle_reviews WHERE id = $1`, reviewID).Scan(&vehicleID); err != nil {
+		return err
+	}
+	if err := lockVehicleRating(tx, vehicleID); err != nil {
+		return err
+	}
+	if err := expectOneRow(tx.Exec(`DELETE FROM vehicle_reviews WHERE id = $1`, reviewID)); err != nil {
+		return err
+	}
+	if err := refreshVehicleRating(tx, vehicleID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,246 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"go-auth-api/src/geo"
+	"sort"
+	"time"
+)
+
+// MaxTelemetryBatch is the largest number of points accepted in one ingest call
+const MaxTelemetryBatch = 1000
+
+// maxClockSkew tolerates small clock differences between the units and the API
+const maxClockSkew = 5 * time.Minute
+
+// TelemetryPoint is one reading reported by an in-car unit
+type TelemetryPoint struct {
+	RecordedAt      time.Time `json:"recorded_at" binding:"required"`
+	Latitude        *float64  `json:"latitude" binding:"required"`
+	Longitude       *float64  `json:"longitude" binding:"required"`
+	OdometerKm      *float64  `json:"odometer_km"`
+	FuelLevel       *float64  `json:"fuel_level"`    // Percentage of the tank, 0-100
+	BatteryLevel    *float64  `json:"battery_level"` // Percentage of charge, 0-100
+	BatteryCharging *bool     `json:"battery_charging"`
+}
+
+func validPercentage(v *float64) bool {
+	return v == nil || (*v >= 0 && *v <= 100)
+}
+
+// Validate checks the ranges of one point
+func (p TelemetryPoint) Validate(now time.Time) error {
+	switch {
+	case p.RecordedAt.IsZero():
+		return &ValidationError{"recorded_at es requerido"}
+	case p.RecordedAt.After(now.Add(maxClockSkew)):
+		return &ValidationError{"recorded_at está en el futuro"}
+	case p.Latitude == nil || p.Longitude == nil || !ValidCoordinates(*p.Latitude, *p.Longitude):
+		return &ValidationError{"coordenadas inválidas"}
+	case p.OdometerKm != nil && *p.OdometerKm < 0:
+		return &ValidationError{"odometer_km no puede ser negativo"}
+	case !validPercentage(p.FuelLevel) || !validPercentage(p.BatteryLevel):
+		return &ValidationError{"fuel_level y battery_level deben estar entre 0 y 100"}
+	}
+	return nil
+}
+
+// TelemetrySummary reports what an ingest call changed
+type TelemetrySummary struct {
+	Accepted          int            `json:"accepted"`
+	Latitude          float64        `json:"latitude"`
+	Longitude         float64        `json:"longitude"`
+	LastTelemetryAt   *time.Time     `json:"last_telemetry_at"`
+	DistanceAddedKm   float64        `json:"distance_added_km"`
+	FuelConsumedAdded float64        `json:"fuel_consumed_added"`
+	PositionChanged   bool           `json:"-"` // At least one point was newer than the last reading
+	Previous          *geo.Point     `json:"-"` // Position before the batch, if the vehicle had reported before
+	Path              []TrackedPoint `json:"-"` // Points newer than the last reading, oldest first
+}
+
+// TrackedPoint is a position with the time it was recorded
+type TrackedPoint struct {
+	geo.Point
+	At time.Time
+}
+
+// vehicleReading is the latest state of a vehicle used as the baseline for derived values
+type vehicleReading struct {
+	at       sql.NullTime
+	lat, lng float64
+	odometer sql.NullFloat64 // Latest odometer reported, kept on the vehicle
+	fuel     sql.NullFloat64
+	battery  sql.NullFloat64
+	// pointOdometer is the odometer of the latest point itself, invalid when that point did not report
+	// one. Odometer deltas are only taken against it, so the stretch covered by a point without
+	// odometer is not counted again by the next point that has one.
+	pointOdometer sql.NullFloat64
+}
+
+// sortTelemetry returns a copy of points ordered by recorded_at, keeping the order of equal times
+func sortTelemetry(points []TelemetryPoint) []TelemetryPoint {
+	sorted := make([]TelemetryPoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].RecordedAt.Before(sorted[j].RecordedAt) })
+	return sorted
+}
+
+// apply moves the reading to p when p is newer and adds the derived distance and fuel to summary.
+// Points at or before the reading, including repeats of it, leave both unchanged.
+func (r *vehicleReading) apply(p TelemetryPoint, summary *TelemetrySummary) {
+	if r.at.Valid && !p.RecordedAt.After(r.at.Time) {
+		return
+	}
+
+	// Derived values need a previous reading to compare with
+	if r.at.Valid {
+		if p.OdometerKm != nil && r.pointOdometer.Valid && *p.OdometerKm >= r.pointOdometer.Float64 {
+			summary.DistanceAddedKm += *p.OdometerKm - r.pointOdometer.Float64
+		} else {
+			summary.DistanceAddedKm += geo.HaversineKm(
+				geo.Point{Lat: r.lat, Lng: r.lng},
+				geo.Point{Lat: *p.Latitude, Lng: *p.Longitude})
+		}
+		if p.FuelLevel != nil && r.fuel.Valid && *p.FuelLevel < r.fuel.Float64 {
+			summary.FuelConsumedAdded += r.fuel.Float64 - *p.FuelLevel
+		}
+	}
+
+	r.at = sql.NullTime{Time: p.RecordedAt, Valid: true}
+	r.lat, r.lng = *p.Latitude, *p.Longitude
+	r.pointOdometer = sql.NullFloat64{}
+	if p.OdometerKm != nil {
+		r.odometer = sql.NullFloat64{Float64: *p.OdometerKm, Valid: true}
+		r.pointOdometer = r.odometer
+	}
+	if p.FuelLevel != nil {
+		r.fuel = sql.NullFloat64{Float64: *p.FuelLevel, Valid: true}
+	}
+	if p.BatteryLevel != nil {
+		r.battery = sql.NullFloat64{Float64: *p.BatteryLevel, Valid: true}
+	}
+	summary.PositionChanged = true
+	summary.Path = append(summary.Path, TrackedPoint{Point: geo.Point{Lat: r.lat, Lng: r.lng}, At: p.RecordedAt})
+}
+
+// IngestTelemetry stores a batch of points for a vehicle and, for the points newer than the last
+// known reading, moves the vehicle's current position and accumulates the derived values:
+//   - Distance grows by the odometer delta from the previous point, or by the haversine distance when
+//     either of the two points has no odometer.
+//   - FuelConsumption grows by every drop of the fuel level (refuelling is not subtracted).
+//
+// Points older than the last reading are stored for the trail but do not change the vehicle.
+func IngestTelemetry(db *sql.DB, vehicleID int, points []TelemetryPoint) (TelemetrySummary, error) {
+	var summary TelemetrySummary
+	if len(points) == 0 {
+		return summary, &ValidationError{"se requiere al menos un punto"}
+	}
+	if len(points) > MaxTelemetryBatch {
+		return summary, &ValidationError{fmt.Sprintf("máximo %d puntos por envío", MaxTelemetryBatch)}
+	}
+	now := time.Now()
+	for i, p := range points {
+		if err := p.Validate(now); err != nil {
+			return summary, &ValidationError{fmt.Sprintf("punto %d: %s", i, err.Error())}
+		}
+	}
+
+	sorted := sortTelemetry(points)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return summary, err
+	}
+	defer tx.Rollback()
+
+	var last vehicleReading
+	err = tx.QueryRow(`SELECT v.last_telemetry_at, v.latitude, v.longitude, v.odometer_km, v.fuel_level, v.battery_level,
+                              (SELECT t.odometer_km FROM vehicle_telemetry t
+                               WHERE t.vehicle_id = v.id AND t.recorded_at = v.last_telemetry_at
+                               ORDER BY t.id DESC LIMIT 1)
+                       FROM vehicles v WHERE v.id = $1 FOR UPDATE OF v`, vehicleID).
+		Scan(&last.at, &last.lat, &last.lng, &last.odometer, &last.fuel, &last.battery, &last.pointOdometer)
+	if err != nil {
+		return summary, err
+	}
+	if last.at.Valid {
+		summary.Previous = &geo.Point{Lat: last.lat, Lng: last.lng}
+	}
+
+	insert := `INSERT INTO vehicle_telemetry (vehicle_id, reservation_id, recorded_at, latitude, longitude,
+                                              odometer_km, fuel_level, battery_level, battery_charging)
+               VALUES ($1, (SELECT id FROM reservations
+                            WHERE vehicle_id = $1 AND status = 'activa' AND start_time <= $2 AND end_time >= $2
+                            ORDER BY start_time DESC LIMIT 1),
+                       $2, $3, $4, $5, $6, $7, $8)`
+	for _, p := range sorted {
+		if _, err := tx.Exec(insert, vehicleID, p.RecordedAt, *p.Latitude, *p.Longitude,
+			p.OdometerKm, p.FuelLevel, p.BatteryLevel, p.BatteryCharging); err != nil {
+			return summary, err
+		}
+		summary.Accepted++
+
+		last.apply(p, &summary)
+	}
+
+	if summary.PositionChanged {
+		_, err := tx.Exec(`UPDATE vehicles SET latitude = $1, longitude = $2, odometer_km = $3, fuel_level = $4,
+                                  battery_level = $5, last_telemetry_at = $6,
+                                  distance = distance + $7, fuel_consumption = fuel_consumption + $8
+                           WHERE id = $9`,
+			last.lat, last.lng, last.odometer, last.fuel, last.battery, last.at.Time,
+			summary.DistanceAddedKm, summary.FuelConsumedAdded, vehicleID)
+		if err != nil {
+			return summary, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return summary, err
+	}
+
+	summary.Latitude, summary.Longitude = last.lat, last.lng
+	if last.at.Valid {
+		at := last.at.Time
+		summary.LastTelemetryAt = &at
+	}
+	return summary, nil
+}
+
+// TrailPoint is one position of a location trail
+type TrailPoint struct {
+	RecordedAt time.Time `json:"recorded_at"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	OdometerKm *float64  `json:"odometer_km,omitempty"`
+	FuelLevel  *float64  `json:"fuel_level,omitempty"`
+}
+
+// MaxTrailPoints caps the number of points returned by one trail query
+const MaxTrailPoints = 5000
+
+// GetLocationTrail returns the positions of a vehicle between from and to, oldest first
+func GetLocationTrail(db *sql.DB, vehicleID int, from, to time.Time) ([]TrailPoint, error) {
+	if !from.Before(to) {
+		return nil, &ValidationError{"from debe ser anterior a to"}
+	}
+	query := `SELECT recorded_at, latitude, longitude, odometer_km, fuel_level
+              FROM vehicle_telemetry
+              WHERE vehicle_id = $1 AND recorded_at >= $2 AND recorded_at <= $3
+              ORDER BY recorded_at LIMIT $4`
+	rows, err := db.Query(query, vehicleID, from, to, MaxTrailPoints)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trail := []TrailPoint{}
+	for rows.Next() {
+		var p TrailPoint
+		if err := rows.Scan(&p.RecordedAt, &p.Latitude, &p.Longitude, &p.OdometerKm, &p.FuelLevel); err != nil {
+			return nil, err
+		}
+		trail = append(trail, p)
+	}
+	return trail, rows.Err()
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"database/sql"
+	"go-auth-api/src/geo"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTelemetryDerivedValues(t *testing.T) {
+	t0 := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return t0.Add(time.Duration(minutes) * time.Minute) }
+	km := func(v float64) *float64 { return &v }
+	// Two positions about 1.1 km apart; most points stay at a so that only odometer deltas count
+	a, b := geo.Point{Lat: 18.47, Lng: -69.9}, geo.Point{Lat: 18.48, Lng: -69.9}
+	point := func(minutes int, pos geo.Point, odometer, fuel *float64) TelemetryPoint {
+		return TelemetryPoint{RecordedAt: at(minutes), Latitude: &pos.Lat, Longitude: &pos.Lng, OdometerKm: odometer, FuelLevel: fuel}
+	}
+	// Last stored reading: at a at t0 with odometer 100 and half a tank
+	previous := vehicleReading{
+		at: sql.NullTime{Time: t0, Valid: true}, lat: a.Lat, lng: a.Lng,
+		odometer:      sql.NullFloat64{Float64: 100, Valid: true},
+		pointOdometer: sql.NullFloat64{Float64: 100, Valid: true},
+		fuel:          sql.NullFloat64{Float64: 50, Valid: true},
+	}
+
+	tests := []struct {
+		name         string
+		last         vehicleReading
+		points       []TelemetryPoint
+		wantDistance float64
+		wantFuel     float64
+		wantOdometer float64
+		wantAt       time.Time
+	}{
+		{"in order", previous,
+			[]TelemetryPoint{point(1, a, km(110), km(45)), point(2, a, km(125), km(40))},
+			25, 10, 125, at(2)},
+		{"out of order", previous,
+			[]TelemetryPoint{point(2, a, km(125), km(40)), point(1, a, km(110), km(45))},
+			25, 10, 125, at(2)},
+		{"repeat of the stored reading", previous,
+			[]TelemetryPoint{point(0, a, km(150), km(10))},
+			0, 0, 100, t0},
+		{"duplicate inside the batch", previous,
+			[]TelemetryPoint{point(1, a, km(110), km(45)), point(1, a, km(110), km(45)), point(2, a, km(125), km(40))},
+			25, 10, 125, at(2)},
+		{"duplicate time with another odometer", previous,
+			[]TelemetryPoint{point(1, a, km(110), km(45)), point(1, a, km(300), km(5))},
+			10, 5, 110, at(1)},
+		{"older than the stored reading", previous,
+			[]TelemetryPoint{point(-5, a, km(90), km(60)), point(1, a, km(110), km(45))},
+			10, 5, 110, at(1)},
+		{"gap without odometer is not counted twice", previous,
+			[]TelemetryPoint{point(1, b, nil, nil), point(2, b, km(130), km(40))},
+			geo.HaversineKm(a, b), 10, 130, at(2)},
+		{"odometer going back falls back to haversine", previous,
+			[]TelemetryPoint{point(1, b, km(20), nil)},
+			geo.HaversineKm(a, b), 0, 20, at(1)},
+		{"refuelling is not subtracted", previous,
+			[]TelemetryPoint{point(3, a, nil, km(30)), point(1, a, nil, km(40)), point(2, a, nil, km(80))},
+			0, 10 + 50, 100, at(3)},
+		{"first reading only sets the baseline", vehicleReading{},
+			[]TelemetryPoint{point(2, a, km(125), km(40)), point(1, b, km(110), km(45))},
+			15, 5, 125, at(2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			last := tt.last
+			var summary TelemetrySummary
+			for _, p := range sortTelemetry(tt.points) {
+				last.apply(p, &summary)
+			}
+			if math.Abs(summary.DistanceAddedKm-tt.wantDistance) > 1e-9 {
+				t.Errorf("distance = %v, want %v", summary.DistanceAddedKm, tt.wantDistance)
+			}
+			if math.Abs(summary.FuelConsumedAdded-tt.wantFuel) > 1e-9 {
+				t.Errorf("fuel consumed = %v, want %v", summary.FuelConsumedAdded, tt.wantFuel)
+			}
+			if last.odometer.Float64 != tt.wantOdometer {
+				t.Errorf("odometer = %v, want %v", last.odometer.Float64, tt.wantOdometer)
+			}
+			if !last.at.Time.Equal(tt.wantAt) {
+				t.Errorf("last reading at %s, want %s", last.at.Time, tt.wantAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidUnsubscribeToken is returned for tokens that were not signed with the current key
+var ErrInvalidUnsubscribeToken = errors.New("enlace de cancelación inválido")
+
+// unsubscribe holds the settings of the unsubscribe links; links are left out of the emails until
+// ConfigureUnsubscribe is called
+var unsubscribe struct {
+	baseURL string
+	key     []byte
+}
+
+// ConfigureUnsubscribe sets the public base URL of the API and the key that signs the links
+func ConfigureUnsubscribe(baseURL string, key []byte) {
+	unsubscribe.baseURL = strings.TrimRight(baseURL, "/")
+	unsubscribe.key = key
+}
+
+func unsubscribeSignature(payload string) string {
+	mac := hmac.New(sha256.New, unsubscribe.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// UnsubscribeToken signs the user and the notification type. Tokens do not expire: an unsubscribe
+// link in an old email must keep working.
+func UnsubscribeToken(userID int, t string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(userID) + ":" + t))
+	return payload + "." + unsubscribeSignature(payload)
+}
+
+// UnsubscribeURL is the one-click link for the footer of the emails of the type, or "" when links
+// are not configured or the type is transactional
+func UnsubscribeURL(userID int, t string) string {
+	if unsubscribe.baseURL == "" || len(unsubscribe.key) == 0 || IsTransactional(t) {
+		return ""
+	}
+	return unsubscribe.baseURL + "/unsubscribe?token=" + url.QueryEscape(UnsubscribeToken(userID, t))
+}
+
+// ParseUnsubscribeToken verifies a token and returns the user and the notification type
+func ParseUnsubscribeToken(token string) (int, string, error) {
+	if len(unsubscribe.key) == 0 {
+		return 0, "", ErrInvalidUnsubscribeToken
+	}
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(unsubscribeSignature(payload))) {
+		return 0, "", ErrInvalidUnsubscribeToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return 0, "", ErrInvalidUnsubscribeToken
+	}
+	id, t, ok := strings.Cut(string(raw), ":")
+	userID, err := strconv.Atoi(id)
+	if !ok || err != nil || !ValidNotificationType(t) {
+		return 0, "", ErrInvalidUnsubscribeToken
+	}
+	return userID, t, nil
+}
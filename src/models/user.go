@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql"
 	"errors"
+	"go-auth-api/src/templates"
 	"log"
 
 	"golang.org/x/crypto/bcrypt"
@@ -15,17 +16,32 @@ type User struct {
 	PasswordHash string `json:"-"`
 	Email        string `json:"email"`
 	Phone        string `json:"phone"`
+	Role         string `json:"role"`     // customer, operator, admin; assigned by the database, never by the client
+	Language     string `json:"language"` // Language of the notifications: es or en
 }
 
+// User roles
+const (
+	RoleCustomer = "customer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
 // Registrar un nuevo usuario
 func (u *User) Register(db *sql.DB) error {
+	if u.Language == "" {
+		u.Language = templates.DefaultLanguage
+	}
+	if !templates.SupportedLanguage(u.Language) {
+		return &ValidationError{"language debe ser es o en"}
+	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost) // Usa Password aquí
 	if err != nil {
 		return err
 	}
 
-	query := `INSERT INTO users (username, password, email, phone) VALUES ($1, $2, $3, $4) RETURNING id`
-	err = db.QueryRow(query, u.Username, string(hashedPassword), u.Email, u.Phone).Scan(&u.ID)
+	query := `INSERT INTO users (username, password, email, phone, language) VALUES ($1, $2, $3, $4, $5) RETURNING id, role`
+	err = db.QueryRow(query, u.Username, string(hashedPassword), u.Email, u.Phone, u.Language).Scan(&u.ID, &u.Role)
 	return err
 }
 
@@ -33,8 +49,8 @@ func (u *User) Register(db *sql.DB) error {
 // Autenticar usuario
 func (u *User) Authenticate(db *sql.DB, password string) error {
 	// Cambiar el nombre del campo a 'password' en lugar de 'password_hash'
-	query := `SELECT id, password FROM users WHERE username = $1 or email = $1`
-	err := db.QueryRow(query, u.Username).Scan(&u.ID, &u.PasswordHash)
+	query := `SELECT id, password, role FROM users WHERE username = $1 or email = $1`
+	err := db.QueryRow(query, u.Username).Scan(&u.ID, &u.PasswordHash, &u.Role)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errors.New("usuario no encontrado")
@@ -55,3 +71,30 @@ func (u *User) Authenticate(db *sql.DB, password string) error {
 
 	return nil
 }
+
+// GetStaffUserIDs devuelve los ids de los operadores y administradores
+func GetStaffUserIDs(db *sql.DB) ([]int, error) {
+	rows, err := db.Query(`SELECT id FROM users WHERE role IN ($1, $2) ORDER BY id`, RoleOperator, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SetUserLanguage changes the language the user receives notifications in
+func SetUserLanguage(db *sql.DB, userID int, language string) error {
+	if !templates.SupportedLanguage(language) {
+		return &ValidationError{"language debe ser es o en"}
+	}
+	return expectOneRow(db.Exec(`UPDATE users SET language = $1 WHERE id = $2`, language, userID))
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // Define custom types
@@ -11,6 +13,36 @@ type FuelType *string
 type VehicleStatus *string
 type Rating float64
 
+// Vehicle status values. Only "available" vehicles are offered for new reservations.
+const (
+	VehicleStatusAvailable    = "available"
+	VehicleStatusReserved     = "reserved"
+	VehicleStatusRented       = "rented"
+	VehicleStatusMaintenance  = "maintenance"
+	VehicleStatusOutOfService = "out_of_service"
+	VehicleStatusRetired      = "retired"
+)
+
+// VehicleStatuses lists every valid status
+var VehicleStatuses = []string{
+	VehicleStatusAvailable,
+	VehicleStatusReserved,
+	VehicleStatusRented,
+	VehicleStatusMaintenance,
+	VehicleStatusOutOfService,
+	VehicleStatusRetired,
+}
+
+// ValidVehicleStatus reports whether status is one of VehicleStatuses
+func ValidVehicleStatus(status string) bool {
+	for _, s := range VehicleStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
 // Vehicle struct represents the vehicle model
 type Vehicle struct {
 	ID              int            `json:"id"`
@@ -21,18 +53,16 @@ type Vehicle struct {
 	Longitude       float64        `json:"longitude"`
 	Type            VehicleType    `json:"type"`
 	FuelType        FuelType       `json:"fuel_type"`
-	Distance        float64        `json:"distance"`
+	Distance        float64        `json:"distance"` // Kilometres driven, derived from telemetry
 	FuelEfficiency  float64        `json:"fuel_efficiency"`
-	FuelConsumption float64        `json:"fuel_consumption"`
+	FuelConsumption float64        `json:"fuel_consumption"` // Tank percentage consumed, derived from telemetry
 	PricePerMinute  float64        `json:"price_per_minute"`
 	PricePerMile    float64        `json:"price_per_mile"`
 	Status          VehicleStatus  `json:"status"`
-	Rating          Rating         `json:"rating"`
-	IsBooked        bool           `json:"is_booked"`
-	IsReserved      bool           `json:"is_reserved"`
-	IsAvailable     bool           `json:"is_available"`
-	IsRented        bool           `json:"is_rented"`
-	IsFavorited     bool           `json:"is_favorited"`
+	Rating          Rating         `json:"rating"`       // Average of the published reviews
+	RatingCount     int            `json:"rating_count"` // Number of published reviews
+	Availability    Availability   `json:"availability"` // Derived from status, reservations and downtime
+	IsFavorited     bool           `json:"is_favorited"` // Whether the calling user saved it, see MarkFavorites
 	IsEconomic      bool           `json:"is_economic"`
 	IsLuxury        bool           `json:"is_luxury"`
 	Images          []VehicleImage `json:"images"` // New field for multiple images
@@ -40,15 +70,27 @@ type Vehicle struct {
 
 // VehicleImage struct represents an image of a vehicle
 type VehicleImage struct {
-	ID        int    `json:"id"`
-	VehicleID int    `json:"vehicle_id"`
-	ImageURL  string `json:"image_url"`
-	IsPrimary bool   `json:"is_primary"`
+	ID           int     `json:"id"`
+	VehicleID    int     `json:"vehicle_id"`
+	ImageURL     string  `json:"image_url"`
+	ThumbnailURL *string `json:"thumbnail_url"`
+	IsPrimary    bool    `json:"is_primary"`
+	Position     int     `json:"position"`
+	StorageKey   *string `json:"-"` // Keys in the BlobStore; nil for images added by URL
+	ThumbnailKey *string `json:"-"`
+	ContentType  *string `json:"-"`
 }
 
-// GetVehicleImages retrieves all images for a specific vehicle
+const vehicleImageColumns = `id, vehicle_id, image_url, thumbnail_url, is_primary, position, storage_key, thumbnail_key, content_type`
+
+func (img *VehicleImage) scanFields() []interface{} {
+	return []interface{}{&img.ID, &img.VehicleID, &img.ImageURL, &img.ThumbnailURL, &img.IsPrimary,
+		&img.Position, &img.StorageKey, &img.ThumbnailKey, &img.ContentType}
+}
+
+// GetVehicleImages retrieves all images for a specific vehicle in display order
 func GetVehicleImages(db *sql.DB, vehicleID int) ([]VehicleImage, error) {
-	query := `SELECT id, vehicle_id, image_url, is_primary FROM vehicle_images WHERE vehicle_id = $1`
+	query := `SELECT ` + vehicleImageColumns + ` FROM vehicle_images WHERE vehicle_id = $1 ORDER BY position, id`
 	rows, err := db.Query(query, vehicleID)
 	if err != nil {
 		return nil, err
@@ -58,21 +100,57 @@ func GetVehicleImages(db *sql.DB, vehicleID int) ([]VehicleImage, error) {
 	var images []VehicleImage
 	for rows.Next() {
 		var img VehicleImage
-		if err := rows.Scan(&img.ID, &img.VehicleID, &img.ImageURL, &img.IsPrimary); err != nil {
+		if err := rows.Scan(img.scanFields()...); err != nil {
 			return nil, err
 		}
 		images = append(images, img)
 	}
-	return images, nil
+	return images, rows.Err()
+}
+
+// attachVehicleImages loads the images of all the given vehicles with a single query and
+// assigns them in memory, keeping the per-vehicle display order
+func attachVehicleImages(db *sql.DB, vehicles []Vehicle) error {
+	if len(vehicles) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(vehicles))
+	for i, v := range vehicles {
+		ids[i] = int64(v.ID)
+	}
+
+	query := `SELECT ` + vehicleImageColumns + ` FROM vehicle_images
+              WHERE vehicle_id = ANY($1) ORDER BY vehicle_id, position, id`
+	rows, err := db.Query(query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byVehicle := make(map[int][]VehicleImage, len(vehicles))
+	for rows.Next() {
+		var img VehicleImage
+		if err := rows.Scan(img.scanFields()...); err != nil {
+			return err
+		}
+		byVehicle[img.VehicleID] = append(byVehicle[img.VehicleID], img)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range vehicles {
+		vehicles[i].Images = byVehicle[vehicles[i].ID]
+	}
+	return nil
 }
 
 // GetAllVehicles retrieves all vehicles from the database, including their images
 func GetAllVehicles(db *sql.DB) ([]Vehicle, error) {
 	query := `SELECT v.id, b.name, m.name, v.license_plate, v.latitude, v.longitude, 
                      ft.type, v.distance, v.fuel_efficiency, v.fuel_consumption, 
-                     p.price_per_minute, p.price_per_mile, v.status, v.rating, 
-                     v.is_booked, v.is_reserved, v.is_available, v.is_rented, 
-                     v.is_favorited, v.is_economic, v.is_luxury
+                     p.price_per_minute, p.price_per_mile, v.status, v.rating, v.rating_count,
+                     v.is_economic, v.is_luxury
               FROM vehicles v
               JOIN brand b ON v.brand_id = b.id
               JOIN model m ON v.model_id = m.id
@@ -91,9 +169,8 @@ func GetAllVehicles(db *sql.DB) ([]Vehicle, error) {
 		var fuelTypeStr, statusStr sql.NullString
 		if err := rows.Scan(&v.ID, &v.Brand, &v.Model, &v.LicensePlate, &v.Latitude, &v.Longitude,
 			&fuelTypeStr, &v.Distance, &v.FuelEfficiency, &v.FuelConsumption,
-			&v.PricePerMinute, &v.PricePerMile, &statusStr, &v.Rating,
-			&v.IsBooked, &v.IsReserved, &v.IsAvailable, &v.IsRented,
-			&v.IsFavorited, &v.IsEconomic, &v.IsLuxury,
+			&v.PricePerMinute, &v.PricePerMile, &statusStr, &v.Rating, &v.RatingCount,
+			&v.IsEconomic, &v.IsLuxury,
 		); err != nil {
 			return nil, err
 		}
@@ -106,15 +183,20 @@ func GetAllVehicles(db *sql.DB) ([]Vehicle, error) {
 			v.Status = &statusStr.String
 		}
 
-		// Get vehicle images
-		images, err := GetVehicleImages(db, v.ID)
-		if err != nil {
-			return nil, err
-		}
-		v.Images = images
-
 		vehicles = append(vehicles, v)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Load every image in one query once the vehicle rows are released
+	if err := attachVehicleImages(db, vehicles); err != nil {
+		return nil, err
+	}
+	if err := attachAvailability(db, vehicles, time.Now()); err != nil {
+		return nil, err
+	}
 	return vehicles, nil
 }
 
@@ -122,9 +204,8 @@ func GetAllVehicles(db *sql.DB) ([]Vehicle, error) {
 func (v *Vehicle) GetByID(db *sql.DB, id int) error {
 	query := `SELECT v.id, b.name, m.name, v.license_plate, v.latitude, v.longitude, 
                      ft.type, v.distance, v.fuel_efficiency, v.fuel_consumption, 
-                     p.price_per_minute, p.price_per_mile, v.status, v.rating, 
-                     v.is_booked, v.is_reserved, v.is_available, v.is_rented, 
-                     v.is_favorited, v.is_economic, v.is_luxury
+                     p.price_per_minute, p.price_per_mile, v.status, v.rating, v.rating_count,
+                     v.is_economic, v.is_luxury
               FROM vehicles v
               JOIN brand b ON v.brand_id = b.id
               JOIN model m ON v.model_id = m.id
@@ -134,54 +215,80 @@ func (v *Vehicle) GetByID(db *sql.DB, id int) error {
 	err := db.QueryRow(query, id).Scan(&v.ID, &v.Brand, &v.Model, &v.LicensePlate,
 		&v.Latitude, &v.Longitude, &v.FuelType, &v.Distance,
 		&v.FuelEfficiency, &v.FuelConsumption, &v.PricePerMinute,
-		&v.PricePerMile, &v.Status, &v.Rating, &v.IsBooked,
-		&v.IsReserved, &v.IsAvailable, &v.IsRented, &v.IsFavorited,
+		&v.PricePerMile, &v.Status, &v.Rating, &v.RatingCount,
 		&v.IsEconomic, &v.IsLuxury)
 	if err != nil {
 		return err
 	}
 
 	// Get vehicle images
-	v.Images, err = GetVehicleImages(db, v.ID)
-	return err
+	if v.Images, err = GetVehicleImages(db, v.ID); err != nil {
+		return err
+	}
+	vehicles := []Vehicle{*v}
+	if err := attachAvailability(db, vehicles, time.Now()); err != nil {
+		return err
+	}
+	v.Availability = vehicles[0].Availability
+	return nil
 }
 
-func (v *Vehicle) UpdateLocation(db *sql.DB, lat, long float64) error {
-	query := `UPDATE vehicles SET latitude = $1, longitude = $2 WHERE id = $3`
-	_, err := db.Exec(query, lat, long, v.ID)
-	return err
+// GetVehiclePosition returns the current position of a vehicle
+func GetVehiclePosition(db *sql.DB, id int) (float64, float64, error) {
+	var lat, lng float64
+	err := db.QueryRow(`SELECT latitude, longitude FROM vehicles WHERE id = $1`, id).Scan(&lat, &lng)
+	return lat, lng, err
 }
 
-func (v *Vehicle) UpdateStatus(db *sql.DB, status string) error {
-	query := `UPDATE vehicles SET status = $1 WHERE id = $2`
-	_, err := db.Exec(query, status, v.ID)
-	return err
+// GetVehicleStatusAndPosition returns the operational status and the current position of a vehicle
+func GetVehicleStatusAndPosition(db DBTX, id int) (string, float64, float64, error) {
+	var status string
+	var lat, lng float64
+	err := db.QueryRow(`SELECT COALESCE(status, 'available'), latitude, longitude FROM vehicles WHERE id = $1`, id).
+		Scan(&status, &lat, &lng)
+	return status, lat, lng, err
 }
-func GetAllAvailableVehicles(db *sql.DB, startTime, endTime time.Time) ([]Vehicle, error) {
-	query := `
-        SELECT id, brand, model, license_plate, latitude, longitude, status
-        FROM vehicles v
-        WHERE v.id NOT IN (
-            SELECT vehicle_id 
-            FROM reservations 
-            WHERE (start_time <= $1 AND end_time >= $1) 
-            OR (start_time <= $2 AND end_time >= $2)
-            OR ($1 <= start_time AND $2 >= start_time)
-        ) AND v.status = 'available'`
-
-	rows, err := db.Query(query, startTime, endTime)
-	if err != nil {
-		return nil, err
+
+// UpdateLocation sets the current position; it returns sql.ErrNoRows if the vehicle does not exist
+func (v *Vehicle) UpdateLocation(db *sql.DB, lat, long float64) error {
+	if !ValidCoordinates(lat, long) {
+		return &ValidationError{"coordenadas inválidas"}
 	}
-	defer rows.Close()
+	query := `UPDATE vehicles SET latitude = $1, longitude = $2 WHERE id = $3`
+	if err := expectOneRow(db.Exec(query, lat, long, v.ID)); err != nil {
+		return err
+	}
+	v.Latitude, v.Longitude = lat, long
+	return nil
+}
 
-	var vehicles []Vehicle
-	for rows.Next() {
-		var v Vehicle
-		if err := rows.Scan(&v.ID, &v.Brand, &v.Model, &v.LicensePlate, &v.Latitude, &v.Longitude, &v.Status); err != nil {
-			return nil, err
-		}
-		vehicles = append(vehicles, v)
+// UpdateStatus sets the operational status and returns the previous one; it returns sql.ErrNoRows
+// if the vehicle does not exist and ErrConflict if it is retired, since retirement is final
+func (v *Vehicle) UpdateStatus(db DBTX, status string) (string, error) {
+	if !ValidVehicleStatus(status) {
+		return "", &ValidationError{"estado inválido"}
 	}
-	return vehicles, nil
+	var previous sql.NullString
+	if err := db.QueryRow(`SELECT status FROM vehicles WHERE id = $1 FOR UPDATE`, v.ID).Scan(&previous); err != nil {
+		return "", err
+	}
+	if previous.String == VehicleStatusRetired {
+		return "", ErrConflict
+	}
+	query := `UPDATE vehicles SET status = $1 WHERE id = $2 RETURNING latitude, longitude`
+	if err := db.QueryRow(query, status, v.ID).Scan(&v.Latitude, &v.Longitude); err != nil {
+		return "", err
+	}
+	v.Status = &status
+	return previous.String, nil
+}
+
+// GetAllAvailableVehicles lists the vehicles with status 'available' that have no active
+// reservation or downtime overlapping [startTime, endTime), applying the same filters as the general
+// listing. The rules match ComputeAvailability, so every returned vehicle has a free window there.
+func GetAllAvailableVehicles(db *sql.DB, startTime, endTime time.Time, f VehicleFilter) (VehiclePage, error) {
+	f.Status = VehicleStatusAvailable
+	f.AvailableFrom = &startTime
+	f.AvailableTo = &endTime
+	return SearchVehicles(db, f)
 }
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"go-auth-api/src/testdb"
+	"os"
+	"testing"
+	"time"
+)
+
+// BenchmarkListVehicles compares the batched image loading of GetAllVehicles with the previous
+// one-query-per-vehicle approach on fleets of 1k and 10k vehicles with three images each:
+//
+//	TEST_DATABASE_URL=... go test ./src/models -run '^$' -bench ListVehicles
+//
+// Unlike the database tests it fails instead of skipping without TEST_DATABASE_URL, so asking for
+// the numbers never silently produces none.
+func BenchmarkListVehicles(b *testing.B) {
+	if os.Getenv("TEST_DATABASE_URL") == "" {
+		b.Fatal("BenchmarkListVehicles measures real queries: set TEST_DATABASE_URL to a PostgreSQL database")
+	}
+	db := testdb.Open(b)
+
+	seeded := 0
+	for _, n := range []int{1000, 10000} {
+		if err := seedVehicles(db, seeded+1, n); err != nil {
+			b.Fatal(err)
+		}
+		seeded = n
+
+		b.Run(fmt.Sprintf("vehicles=%d/batched", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := GetAllVehicles(db); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("vehicles=%d/per-row", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := listVehiclesPerRow(db); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// seedVehicles inserts the vehicles numbered from to n, with pricing and three images each
+func seedVehicles(db *sql.DB, from, n int) error {
+	if from == 1 {
+		for _, stmt := range []string{
+			`INSERT INTO brand (name) SELECT 'Brand ' || g FROM generate_series(1, 20) g`,
+			`INSERT INTO model (name) SELECT 'Model ' || g FROM generate_series(1, 50) g`,
+			`INSERT INTO fuel_type (type) VALUES ('gasoline'), ('diesel'), ('electric'), ('hybrid')`,
+		} {
+			if _, err := db.Exec(stmt); err != nil {
+				return err
+			}
+		}
+	}
+	statements := []string{
+		`INSERT INTO vehicles (brand_id, model_id, fuel_type_id, license_plate, latitude, longitude,
+                               status, rating, is_economic, is_luxury)
+         SELECT 1 + g % 20, 1 + g % 50, 1 + g % 4, 'BENCH-' || g,
+                18.4 + random() / 10, -69.9 + random() / 10,
+                'available', round((1 + random() * 4)::numeric, 1), g % 3 = 0, g % 7 = 0
+         FROM generate_series($1::int, $2::int) g`,
+		`INSERT INTO pricing (vehicle_id, price_per_minute, price_per_mile)
+         SELECT id, round((0.1 + random())::numeric, 2), round((0.5 + random() * 2)::numeric, 2)
+         FROM vehicles WHERE license_plate IN (SELECT 'BENCH-' || g FROM generate_series($1::int, $2::int) g)`,
+		`INSERT INTO vehicle_images (vehicle_id, image_url, is_primary, position)
+         SELECT v.id, 'https://img.example.com/' || v.id || '/' || i || '.jpg', i = 1, i - 1
+         FROM vehicles v CROSS JOIN generate_series(1, 3) i
+         WHERE v.license_plate IN (SELECT 'BENCH-' || g FROM generate_series($1::int, $2::int) g)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt, from, n); err != nil {
+			return err
+		}
+	}
+	_, err := db.Exec(`ANALYZE`)
+	return err
+}
+
+// listVehiclesPerRow is GetAllVehicles as it was before the images were batched: same query,
+// scan and availability, but one image query per vehicle while the listing cursor is still open
+func listVehiclesPerRow(db *sql.DB) ([]Vehicle, error) {
+	rows, err := db.Query(vehicleSelect + vehicleFrom)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vehicles []Vehicle
+	for rows.Next() {
+		var v Vehicle
+		var fuelTypeStr, statusStr sql.NullString
+		if err := rows.Scan(&v.ID, &v.Brand, &v.Model, &v.LicensePlate, &v.Latitude, &v.Longitude,
+			&fuelTypeStr, &v.Distance, &v.FuelEfficiency, &v.FuelConsumption,
+			&v.PricePerMinute, &v.PricePerMile, &statusStr, &v.Rating, &v.RatingCount,
+			&v.IsEconomic, &v.IsLuxury,
+		); err != nil {
+			return nil, err
+		}
+		if fuelTypeStr.Valid {
+			v.FuelType = &fuelTypeStr.String
+		}
+		if statusStr.Valid {
+			v.Status = &statusStr.String
+		}
+		if v.Images, err = GetVehicleImages(db, v.ID); err != nil {
+			return nil, err
+		}
+		vehicles = append(vehicles, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := attachAvailability(db, vehicles, time.Now()); err != nil {
+		return nil, err
+	}
+	return vehicles, nil
+}
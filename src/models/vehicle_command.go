@@ -0,0 +1,190 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrCommandNotAllowed is returned when a customer requests a command reserved to staff
+var ErrCommandNotAllowed = errors.New("comando no permitido")
+
+// ErrOutsideReservationWindow is returned when a customer sends a command outside an active reservation
+var ErrOutsideReservationWindow = errors.New("la reserva no está en curso")
+
+// Remote commands
+const (
+	CommandUnlock     = "unlock"
+	CommandLock       = "lock"
+	CommandHonk       = "honk"
+	CommandImmobilize = "immobilize"
+)
+
+// Command statuses
+const (
+	CommandPending   = "pending"   // Waiting for the unit to fetch it
+	CommandDelivered = "delivered" // Fetched, waiting for the acknowledgement
+	CommandSucceeded = "succeeded"
+	CommandFailed    = "failed"
+	CommandExpired   = "expired" // Not acknowledged before expires_at
+)
+
+// A command expires if the unit does not fetch it within CommandDeliveryTimeout of being queued, or does
+// not acknowledge it within CommandAckTimeout of fetching it. The delivery timeout is longer than the
+// longest command long-poll, so a unit that reconnects between polls still gets its commands.
+const (
+	CommandDeliveryTimeout = 2 * time.Minute
+	CommandAckTimeout      = 30 * time.Second
+)
+
+// VehicleCommandsChannel is the PostgreSQL notification channel that announces new commands; the
+// payload is the vehicle id
+const VehicleCommandsChannel = "vehicle_commands"
+
+// VehicleCommand is a command queued for a vehicle's unit
+type VehicleCommand struct {
+	ID            int        `json:"id"`
+	VehicleID     int        `json:"vehicle_id"`
+	ReservationID *int       `json:"reservation_id,omitempty"`
+	RequestedBy   *int       `json:"requested_by,omitempty"`
+	Command       string     `json:"command"`
+	Status        string     `json:"status"`
+	Result        *string    `json:"result,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+}
+
+const vehicleCommandColumns = `id, vehicle_id, reservation_id, requested_by, command, status, result, created_at,
+                               delivered_at, completed_at, expires_at`
+
+func (cmd *VehicleCommand) scanFields() []interface{} {
+	return []interface{}{&cmd.ID, &cmd.VehicleID, &cmd.ReservationID, &cmd.RequestedBy, &cmd.Command, &cmd.Status,
+		&cmd.Result, &cmd.CreatedAt, &cmd.DeliveredAt, &cmd.CompletedAt, &cmd.ExpiresAt}
+}
+
+// ValidCommand reports whether name is a known command
+func ValidCommand(name string) bool {
+	switch name {
+	case CommandUnlock, CommandLock, CommandHonk, CommandImmobilize:
+		return true
+	}
+	return false
+}
+
+// CheckCustomerCommand enforces what a customer may send through a reservation: only unlock, lock and
+// honk, only for their own active reservation and only between its start and end time
+func CheckCustomerCommand(res Reservation, userID int, command string, now time.Time) error {
+	if res.UserID != userID {
+		return sql.ErrNoRows
+	}
+	if !ValidCommand(command) {
+		return &ValidationError{"comando inválido"}
+	}
+	if command == CommandImmobilize {
+		return ErrCommandNotAllowed
+	}
+	if res.Status != "activa" || now.Before(res.StartTime) || now.After(res.EndTime) {
+		return ErrOutsideReservationWindow
+	}
+	return nil
+}
+
+// Create queues the command with the delivery deadline
+func (cmd *VehicleCommand) Create(db *sql.DB) error {
+	if !ValidCommand(cmd.Command) {
+		return &ValidationError{"comando inválido"}
+	}
+	query := `INSERT INTO vehicle_commands (vehicle_id, reservation_id, requested_by, command, expires_at)
+              VALUES ($1, $2, $3, $4, $5) RETURNING ` + vehicleCommandColumns
+	err := db.QueryRow(query, cmd.VehicleID, cmd.ReservationID, cmd.RequestedBy, cmd.Command,
+		time.Now().Add(CommandDeliveryTimeout)).Scan(cmd.scanFields()...)
+	if isForeignKeyViolation(err) {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// expireVehicleCommands marks as expired the commands that missed their deadline
+func expireVehicleCommands(db *sql.DB) error {
+	_, err := db.Exec(`UPDATE vehicle_commands SET status = 'expired', completed_at = NOW()
+                       WHERE status IN ('pending', 'delivered') AND expires_at < NOW()`)
+	return err
+}
+
+// GetVehicleCommand returns a command, with its status brought up to date
+func GetVehicleCommand(db *sql.DB, id int) (VehicleCommand, error) {
+	var cmd VehicleCommand
+	if err := expireVehicleCommands(db); err != nil {
+		return cmd, err
+	}
+	err := db.QueryRow(`SELECT `+vehicleCommandColumns+` FROM vehicle_commands WHERE id = $1`, id).Scan(cmd.scanFields()...)
+	return cmd, err
+}
+
+// NotifyVehicleCommands announces on VehicleCommandsChannel that the vehicle has new commands, so the
+// replica holding its unit's connection wakes it up
+func NotifyVehicleCommands(db DBTX, vehicleID int) error {
+	_, err := db.Exec(`SELECT pg_notify($1, $2)`, VehicleCommandsChannel, strconv.Itoa(vehicleID))
+	return err
+}
+
+// ClaimVehicleCommands hands the pending commands of a vehicle to its unit, oldest first, and marks
+// them as delivered. The acknowledgement deadline starts then. Concurrent connections of the same unit
+// never receive the same command.
+func ClaimVehicleCommands(db *sql.DB, vehicleID int) ([]VehicleCommand, error) {
+	if err := expireVehicleCommands(db); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`UPDATE vehicle_commands SET status = 'delivered', delivered_at = NOW(),
+                                  expires_at = NOW() + $2 * INTERVAL '1 second'
+                           WHERE id IN (SELECT id FROM vehicle_commands
+                                        WHERE vehicle_id = $1 AND status = 'pending' AND expires_at >= NOW()
+                                        ORDER BY created_at FOR UPDATE SKIP LOCKED)
+                           RETURNING `+vehicleCommandColumns, vehicleID, CommandAckTimeout.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	commands := []VehicleCommand{}
+	for rows.Next() {
+		var cmd VehicleCommand
+		if err := rows.Scan(cmd.scanFields()...); err != nil {
+			return nil, err
+		}
+		commands = append(commands, cmd)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING does not keep the order of the subquery
+	sort.Slice(commands, func(i, j int) bool { return commands[i].ID < commands[j].ID })
+	return commands, nil
+}
+
+// AckVehicleCommand records the unit's result. Commands already completed or expired cannot be acknowledged.
+func AckVehicleCommand(db *sql.DB, vehicleID, id int, succeeded bool, result *string) (VehicleCommand, error) {
+	var cmd VehicleCommand
+	if err := expireVehicleCommands(db); err != nil {
+		return cmd, err
+	}
+	status := CommandFailed
+	if succeeded {
+		status = CommandSucceeded
+	}
+	err := db.QueryRow(`UPDATE vehicle_commands SET status = $1, result = $2, completed_at = NOW()
+                        WHERE id = $3 AND vehicle_id = $4 AND status IN ('pending', 'delivered')
+                        RETURNING `+vehicleCommandColumns, status, result, id, vehicleID).Scan(cmd.scanFields()...)
+	if err == sql.ErrNoRows {
+		// Distinguish an unknown command from one that can no longer be acknowledged
+		existing, getErr := GetVehicleCommand(db, id)
+		if getErr == nil && existing.VehicleID == vehicleID {
+			return existing, ErrConflict
+		}
+	}
+	return cmd, err
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// VehicleExists reports whether a vehicle with the id exists
+func VehicleExists(db *sql.DB, id int) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM vehicles WHERE id = $1)`, id).Scan(&exists)
+	return exists, err
+}
+
+// Create inserts the image at the end of the vehicle's gallery; the first image becomes primary
+func (img *VehicleImage) Create(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Serialize concurrent uploads for the same vehicle
+	if _, err := tx.Exec(`SELECT id FROM vehicles WHERE id = $1 FOR UPDATE`, img.VehicleID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO vehicle_images (vehicle_id, image_url, thumbnail_url, storage_key, thumbnail_key, content_type,
+                                          position, is_primary)
+              SELECT $1, $2, $3, $4, $5, $6,
+                     COALESCE(MAX(position), 0) + 1, NOT COALESCE(BOOL_OR(is_primary), FALSE)
+              FROM vehicle_images WHERE vehicle_id = $1
+              RETURNING id, position, is_primary`
+	err = tx.QueryRow(query, img.VehicleID, img.ImageURL, img.ThumbnailURL, img.StorageKey, img.ThumbnailKey,
+		img.ContentType).Scan(&img.ID, &img.Position, &img.IsPrimary)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetPrimaryVehicleImage marks one image as the vehicle's primary image
+func SetPrimaryVehicleImage(db *sql.DB, vehicleID, imageID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM vehicle_images WHERE id = $1 AND vehicle_id = $2)`,
+		imageID, vehicleID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec(`UPDATE vehicle_images SET is_primary = FALSE WHERE vehicle_id = $1 AND is_primary`, vehicleID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE vehicle_images SET is_primary = TRUE WHERE id = $1`, imageID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReorderVehicleImages sets the gallery order; imageIDs must list every image of the vehicle once
+func ReorderVehicleImages(db *sql.DB, vehicleID int, imageIDs []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id FROM vehicle_images WHERE vehicle_id = $1 FOR UPDATE`, vehicleID)
+	if err != nil {
+		return err
+	}
+	current := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		current[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(imageIDs) != len(current) {
+		return &ValidationError{"debe indicar todas las imágenes del vehículo exactamente una vez"}
+	}
+	seen := map[int]bool{}
+	for _, id := range imageIDs {
+		if !current[id] || seen[id] {
+			return &ValidationError{"debe indicar todas las imágenes del vehículo exactamente una vez"}
+		}
+		seen[id] = true
+	}
+
+	for i, id := range imageIDs {
+		if _, err := tx.Exec(`UPDATE vehicle_images SET position = $1 WHERE id = $2`, i+1, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteVehicleImage removes an image and returns it so the caller can delete its blobs.
+// If it was the primary image, the next one in display order is promoted.
+func DeleteVehicleImage(db *sql.DB, vehicleID, imageID int) (VehicleImage, error) {
+	var img VehicleImage
+	tx, err := db.Begin()
+	if err != nil {
+		return img, err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM vehicle_images WHERE id = $1 AND vehicle_id = $2 RETURNING ` + vehicleImageColumns
+	if err := tx.QueryRow(query, imageID, vehicleID).Scan(img.scanFields()...); err != nil {
+		return img, err
+	}
+
+	if img.IsPrimary {
+		_, err := tx.Exec(`UPDATE vehicle_images SET is_primary = TRUE
+                           WHERE id = (SELECT id FROM vehicle_images WHERE vehicle_id = $1 ORDER BY position, id LIMIT 1)`,
+			vehicleID)
+		if err != nil {
+			return img, err
+		}
+	}
+	return img, tx.Commit()
+}
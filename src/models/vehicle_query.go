@@ -0,0 +1,261 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultVehiclePageSize = 20
+	MaxVehiclePageSize     = 100
+)
+
+// FilterError reports a filter combination that cannot be applied
+type FilterError struct {
+	Msg string
+}
+
+func (e *FilterError) Error() string { return e.Msg }
+
+// VehicleFilter groups every filter, sort and pagination option accepted by the vehicle listings
+type VehicleFilter struct {
+	Brand         string
+	Model         string
+	FuelType      string
+	MinPrice      *float64
+	MaxPrice      *float64
+	MinRating     *float64
+	IsEconomic    *bool
+	IsLuxury      *bool
+	Status        string
+	AvailableFrom *time.Time
+	AvailableTo   *time.Time
+	Latitude      *float64 // Reference point used when sorting by distance
+	Longitude     *float64
+	SortBy        string // "price", "rating", "distance" or empty for insertion order
+	Descending    bool
+	Limit         int
+	Cursor        string
+	UserID        int // Caller whose favorites are marked in the results; 0 marks none
+}
+
+// VehiclePage is one page of a vehicle listing plus the cursor for the next one
+type VehiclePage struct {
+	Vehicles   []Vehicle `json:"vehicles"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// vehicleCursor marks the last row of a page: the sort key and the id used as tie-breaker
+type vehicleCursor struct {
+	Value float64 `json:"v"`
+	ID    int     `json:"id"`
+}
+
+func encodeVehicleCursor(cur vehicleCursor) string {
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeVehicleCursor(s string) (vehicleCursor, error) {
+	var cur vehicleCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cur, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &cur); err != nil {
+		return cur, ErrInvalidCursor
+	}
+	return cur, nil
+}
+
+// sqlArgs accumulates positional parameters so filters can be composed without string interpolation
+type sqlArgs []interface{}
+
+// add appends a value and returns its placeholder
+func (a *sqlArgs) add(v interface{}) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+const vehicleSelect = `SELECT v.id, b.name, m.name, v.license_plate, v.latitude, v.longitude,
+                     ft.type, v.distance, v.fuel_efficiency, v.fuel_consumption,
+                     p.price_per_minute, p.price_per_mile, v.status, v.rating, v.rating_count,
+                     v.is_economic, v.is_luxury`
+
+const vehicleFrom = `
+              FROM vehicles v
+              JOIN brand b ON v.brand_id = b.id
+              JOIN model m ON v.model_id = m.id
+              JOIN fuel_type ft ON v.fuel_type_id = ft.id
+              JOIN pricing p ON p.vehicle_id = v.id`
+
+// sortExpression returns the SQL expression the listing is ordered by
+func (f VehicleFilter) sortExpression(args *sqlArgs) (string, error) {
+	switch f.SortBy {
+	case "":
+		return "v.id", nil
+	case "price":
+		return "p.price_per_minute", nil
+	case "rating":
+		return "COALESCE(v.rating, 0)", nil
+	case "distance":
+		if f.Latitude == nil || f.Longitude == nil {
+			return "", &FilterError{"ordenar por distancia requiere lat y lng"}
+		}
+		lat := args.add(*f.Latitude)
+		lng := args.add(*f.Longitude)
+		// Great-circle distance in kilometres (haversine)
+		return fmt.Sprintf(`(6371 * 2 * ASIN(SQRT(
+                POWER(SIN(RADIANS(v.latitude - %[1]s::float8) / 2), 2) +
+                COS(RADIANS(%[1]s::float8)) * COS(RADIANS(v.latitude)) *
+                POWER(SIN(RADIANS(v.longitude - %[2]s::float8) / 2), 2))))`, lat, lng), nil
+	default:
+		return "", &FilterError{"orden no soportado: " + f.SortBy}
+	}
+}
+
+// where builds the WHERE conditions for the filter
+func (f VehicleFilter) where(args *sqlArgs) ([]string, error) {
+	var conds []string
+	if f.Brand != "" {
+		conds = append(conds, "LOWER(b.name) = LOWER("+args.add(f.Brand)+")")
+	}
+	if f.Model != "" {
+		conds = append(conds, "LOWER(m.name) = LOWER("+args.add(f.Model)+")")
+	}
+	if f.FuelType != "" {
+		conds = append(conds, "LOWER(ft.type) = LOWER("+args.add(f.FuelType)+")")
+	}
+	if f.MinPrice != nil {
+		conds = append(conds, "p.price_per_minute >= "+args.add(*f.MinPrice))
+	}
+	if f.MaxPrice != nil {
+		conds = append(conds, "p.price_per_minute <= "+args.add(*f.MaxPrice))
+	}
+	if f.MinRating != nil {
+		conds = append(conds, "COALESCE(v.rating, 0) >= "+args.add(*f.MinRating))
+	}
+	if f.IsEconomic != nil {
+		conds = append(conds, "v.is_economic = "+args.add(*f.IsEconomic))
+	}
+	if f.IsLuxury != nil {
+		conds = append(conds, "v.is_luxury = "+args.add(*f.IsLuxury))
+	}
+	if f.Status != "" {
+		conds = append(conds, "v.status = "+args.add(f.Status))
+	}
+	if (f.AvailableFrom == nil) != (f.AvailableTo == nil) {
+		return nil, &FilterError{"available_from y available_to deben indicarse juntos"}
+	}
+	if f.AvailableFrom != nil {
+		if !f.AvailableFrom.Before(*f.AvailableTo) {
+			return nil, &FilterError{"available_from debe ser anterior a available_to"}
+		}
+		from := args.add(*f.AvailableFrom)
+		to := args.add(*f.AvailableTo)
+		conds = append(conds, reservableCond(from, to))
+	}
+	return conds, nil
+}
+
+// SearchVehicles lists vehicles matching the filter, ordered by the requested key with the id as
+// tie-breaker so that cursor pagination is stable
+func SearchVehicles(db *sql.DB, f VehicleFilter) (VehiclePage, error) {
+	var page VehiclePage
+	var args sqlArgs
+
+	sortExpr, err := f.sortExpression(&args)
+	if err != nil {
+		return page, err
+	}
+	conds, err := f.where(&args)
+	if err != nil {
+		return page, err
+	}
+
+	dir, cmp := "ASC", ">"
+	if f.Descending {
+		dir, cmp = "DESC", "<"
+	}
+	if f.Cursor != "" {
+		cur, err := decodeVehicleCursor(f.Cursor)
+		if err != nil {
+			return page, err
+		}
+		conds = append(conds, fmt.Sprintf("(%s, v.id) %s (%s, %s)", sortExpr, cmp, args.add(cur.Value), args.add(cur.ID)))
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultVehiclePageSize
+	}
+	if limit > MaxVehiclePageSize {
+		limit = MaxVehiclePageSize
+	}
+
+	var sb strings.Builder
+	sb.WriteString(vehicleSelect)
+	sb.WriteString(", " + sortExpr + " AS sort_key")
+	sb.WriteString(vehicleFrom)
+	if len(conds) > 0 {
+		sb.WriteString("\n              WHERE " + strings.Join(conds, "\n              AND "))
+	}
+	fmt.Fprintf(&sb, "\n              ORDER BY sort_key %s, v.id %s LIMIT %s", dir, dir, args.add(limit+1))
+
+	rows, err := db.Query(sb.String(), args...)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+
+	var sortKeys []float64
+	for rows.Next() {
+		var v Vehicle
+		var sortKey float64
+		var fuelTypeStr, statusStr sql.NullString
+		if err := rows.Scan(&v.ID, &v.Brand, &v.Model, &v.LicensePlate, &v.Latitude, &v.Longitude,
+			&fuelTypeStr, &v.Distance, &v.FuelEfficiency, &v.FuelConsumption,
+			&v.PricePerMinute, &v.PricePerMile, &statusStr, &v.Rating, &v.RatingCount,
+			&v.IsEconomic, &v.IsLuxury, &sortKey,
+		); err != nil {
+			return page, err
+		}
+		if fuelTypeStr.Valid {
+			v.FuelType = &fuelTypeStr.String
+		}
+		if statusStr.Valid {
+			v.Status = &statusStr.String
+		}
+		page.Vehicles = append(page.Vehicles, v)
+		sortKeys = append(sortKeys, sortKey)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+	rows.Close()
+
+	if len(page.Vehicles) > limit {
+		page.Vehicles = page.Vehicles[:limit]
+		last := page.Vehicles[limit-1]
+		page.NextCursor = encodeVehicleCursor(vehicleCursor{Value: sortKeys[limit-1], ID: last.ID})
+	}
+
+	// Images are loaded in one batch once the listing cursor is closed
+	if err := attachVehicleImages(db, page.Vehicles); err != nil {
+		return page, err
+	}
+	if err := MarkFavorites(db, f.UserID, page.Vehicles); err != nil {
+		return page, err
+	}
+	if err := attachAvailability(db, page.Vehicles, time.Now()); err != nil {
+		return page, err
+	}
+	if page.Vehicles == nil {
+		page.Vehicles = []Vehicle{}
+	}
+	return page, nil
+}
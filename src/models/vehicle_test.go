@@ -0,0 +1,53 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"go-auth-api/src/testdb"
+	"testing"
+)
+
+// seedTestVehicle inserts an available vehicle and returns its id
+func seedTestVehicle(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`WITH b AS (INSERT INTO brand (name) VALUES ('Toyota') RETURNING id),
+                             m AS (INSERT INTO model (name) VALUES ('Corolla') RETURNING id)
+                        INSERT INTO vehicles (brand_id, model_id, license_plate, latitude, longitude, status)
+                        SELECT b.id, m.id, 'A123456', 18.47, -69.9, 'available' FROM b, m
+                        RETURNING id`).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestUpdateStatus(t *testing.T) {
+	db := testdb.Open(t)
+	id := seedTestVehicle(t, db)
+
+	v := Vehicle{ID: id}
+	previous, err := v.UpdateStatus(db, VehicleStatusMaintenance)
+	if err != nil || previous != VehicleStatusAvailable {
+		t.Fatalf("UpdateStatus = %q, %v; want %q, nil", previous, err, VehicleStatusAvailable)
+	}
+	if v.Latitude != 18.47 || v.Longitude != -69.9 {
+		t.Errorf("position = %v, %v; want the stored one", v.Latitude, v.Longitude)
+	}
+
+	retired := Vehicle{ID: id}
+	if previous, err := retired.Retire(db); err != nil || previous != VehicleStatusMaintenance {
+		t.Fatalf("Retire = %q, %v; want %q, nil", previous, err, VehicleStatusMaintenance)
+	}
+	if retired.Latitude != 18.47 || retired.Longitude != -69.9 {
+		t.Errorf("retired position = %v, %v; want the stored one", retired.Latitude, retired.Longitude)
+	}
+	if _, err := v.UpdateStatus(db, VehicleStatusAvailable); !errors.Is(err, ErrConflict) {
+		t.Errorf("UpdateStatus on a retired vehicle = %v, want ErrConflict", err)
+	}
+
+	missing := Vehicle{ID: id + 1}
+	if _, err := missing.UpdateStatus(db, VehicleStatusAvailable); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("UpdateStatus on a missing vehicle = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Webhook event types
+const (
+	WebhookReservationCreated   = "reservation.created"
+	WebhookReservationCancelled = "reservation.cancelled"
+	WebhookTripStarted          = "trip.started"  // Start checklist submitted
+	WebhookTripFinished         = "trip.finished" // Reservation completed
+	WebhookPaymentCaptured      = "payment.captured"
+)
+
+// WebhookEventTypes lists every event a subscription can filter on
+var WebhookEventTypes = []string{WebhookReservationCreated, WebhookReservationCancelled, WebhookTripStarted,
+	WebhookTripFinished, WebhookPaymentCaptured}
+
+// ValidWebhookEventType reports whether t is one of WebhookEventTypes
+func ValidWebhookEventType(t string) bool {
+	for _, et := range WebhookEventTypes {
+		if et == t {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSubscription is an endpoint of an organization that receives its events
+type WebhookSubscription struct {
+	ID             int       `json:"id"`
+	OrganizationID int       `json:"organization_id"`
+	URL            string    `json:"url" binding:"required"`
+	Description    string    `json:"description"`
+	EventTypes     []string  `json:"event_types"` // Empty receives every event
+	Active         bool      `json:"active"`
+	Secret         string    `json:"-"` // Signing key; only returned when created or rotated
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+const webhookSubscriptionColumns = `id, organization_id, url, description, event_types, active, secret, created_at, updated_at`
+
+func (s *WebhookSubscription) scanFields() []interface{} {
+	return []interface{}{&s.ID, &s.OrganizationID, &s.URL, &s.Description, pq.Array(&s.EventTypes), &s.Active,
+		&s.Secret, &s.CreatedAt, &s.UpdatedAt}
+}
+
+// Validate checks the URL and the event filter. Only https URLs are accepted: the payloads are
+// signed but not encrypted. The worker checks the resolved address of every delivery.
+func (s *WebhookSubscription) Validate() error {
+	s.URL = strings.TrimSpace(s.URL)
+	u, err := url.Parse(s.URL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return &ValidationError{"url debe ser una dirección https absoluta"}
+	}
+	if s.EventTypes == nil {
+		s.EventTypes = []string{}
+	}
+	for _, t := range s.EventTypes {
+		if !ValidWebhookEventType(t) {
+			return &ValidationError{"tipo de evento inválido: " + t}
+		}
+	}
+	return nil
+}
+
+// newWebhookSecret generates a 256-bit signing key
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Create stores the subscription with a new signing key
+func (s *WebhookSubscription) Create(db *sql.DB) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return err
+	}
+	err = db.QueryRow(`INSERT INTO webhook_subscriptions (organization_id, url, description, event_types, active, secret)
+                       VALUES ($1, $2, $3, $4, $5, $6) RETURNING `+webhookSubscriptionColumns,
+		s.OrganizationID, s.URL, s.Description, pq.Array(s.EventTypes), s.Active, secret).Scan(s.scanFields()...)
+	if isForeignKeyViolation(err) {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// Update changes the URL, description, event filter and active flag; the signing key is kept
+func (s *WebhookSubscription) Update(db *sql.DB) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	return db.QueryRow(`UPDATE webhook_subscriptions
+                        SET url = $1, description = $2, event_types = $3, active = $4, updated_at = NOW()
+                        WHERE id = $5 AND organization_id = $6 RETURNING `+webhookSubscriptionColumns,
+		s.URL, s.Description, pq.Array(s.EventTypes), s.Active, s.ID, s.OrganizationID).Scan(s.scanFields()...)
+}
+
+// GetWebhookSubscriptions lists the subscriptions of the organization
+func GetWebhookSubscriptions(db *sql.DB, organizationID int) ([]WebhookSubscription, error) {
+	rows, err := db.Query(`SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions
+                           WHERE organization_id = $1 ORDER BY id`, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	subscriptions := []WebhookSubscription{}
+	for rows.Next() {
+		var s WebhookSubscription
+		if err := rows.Scan(s.scanFields()...); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
+
+// GetWebhookSubscription returns one subscription of the organization
+func GetWebhookSubscription(db *sql.DB, organizationID, id int) (WebhookSubscription, error) {
+	var s WebhookSubscription
+	err := db.QueryRow(`SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions
+                        WHERE id = $1 AND organization_id = $2`, id, organizationID).Scan(s.scanFields()...)
+	return s, err
+}
+
+// RotateWebhookSecret replaces the signing key; deliveries signed from now on use the new one
+func RotateWebhookSecret(db *sql.DB, organizationID, id int) (WebhookSubscription, error) {
+	var s WebhookSubscription
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return s, err
+	}
+	err = db.QueryRow(`UPDATE webhook_subscriptions SET secret = $1, updated_at = NOW()
+                       WHERE id = $2 AND organization_id = $3 RETURNING `+webhookSubscriptionColumns,
+		secret, id, organizationID).Scan(s.scanFields()...)
+	return s, err
+}
+
+// DeleteWebhookSubscription removes the subscription together with its delivery log
+func DeleteWebhookSubscription(db *sql.DB, organizationID, id int) error {
+	return expectOneRow(db.Exec(`DELETE FROM webhook_subscriptions WHERE id = $1 AND organization_id = $2`,
+		id, organizationID))
+}
+
+// EmitWebhookEvent records an event about the user and queues one delivery per active subscription of
+// their organization that wants it. Users outside an organization emit nothing. Pass the transaction
+// of the change so the event exists if and only if the change commits.
+func EmitWebhookEvent(db DBTX, userID int, eventType string, data interface{}) error {
+	membership, err := GetOrganizationMembership(db, userID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var eventID int64
+	err = db.QueryRow(`INSERT INTO webhook_events (organization_id, user_id, type, payload)
+                       VALUES ($1, $2, $3, $4) RETURNING id`, membership.OrganizationID, userID, eventType, payload).
+		Scan(&eventID)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`INSERT INTO webhook_deliveries (subscription_id, event_id)
+                      SELECT id, $1 FROM webhook_subscriptions
+                      WHERE organization_id = $2 AND active AND (cardinality(event_types) = 0 OR $3 = ANY (event_types))`,
+		eventID, membership.OrganizationID, eventType)
+	return err
+}
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Webhook delivery statuses; pending and sending mean the same as for notification deliveries
+const (
+	WebhookPending   = "pending"
+	WebhookSending   = "sending"
+	WebhookDelivered = "delivered"
+	WebhookDead      = "dead" // Out of attempts or rejected for good; redelivered by hand
+)
+
+// WebhookDelivery is one event sent to one subscription
+type WebhookDelivery struct {
+	ID             int64            `json:"id"`
+	SubscriptionID int              `json:"subscription_id"`
+	EventID        int64            `json:"event_id"`
+	EventType      string           `json:"event_type"`
+	Status         string           `json:"status"`
+	Attempts       int              `json:"attempts"`
+	MaxAttempts    int              `json:"max_attempts"`
+	NextAttemptAt  time.Time        `json:"next_attempt_at"`
+	LastError      *string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	DeliveredAt    *time.Time       `json:"delivered_at,omitempty"`
+	History        []WebhookAttempt `json:"history,omitempty"` // Only filled by GetWebhookDelivery
+}
+
+const webhookDeliveryColumns = `d.id, d.subscription_id, d.event_id, e.type, d.status, d.attempts, d.max_attempts,
+                                d.next_attempt_at, d.last_error, d.created_at, d.delivered_at`
+
+func (d *WebhookDelivery) scanFields() []interface{} {
+	return []interface{}{&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.MaxAttempts,
+		&d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.DeliveredAt}
+}
+
+// WebhookAttempt is one HTTP request of a delivery, as shown in the delivery log
+type WebhookAttempt struct {
+	ID          int64     `json:"id"`
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  *int      `json:"status_code,omitempty"` // Nil when no response arrived; the body is never kept
+	Error       *string   `json:"error,omitempty"`
+	DurationMs  int       `json:"duration_ms"`
+}
+
+// WebhookJob is a claimed delivery with everything the worker needs to send it
+type WebhookJob struct {
+	WebhookDelivery
+	URL            string
+	Secret         string
+	OrganizationID int
+	UserID         *int
+	Payload        json.RawMessage
+	EventCreatedAt time.Time
+}
+
+// ClaimWebhookDeliveries leases up to limit due deliveries of active subscriptions to the calling
+// worker, like ClaimDeliveries
+func ClaimWebhookDeliveries(db *sql.DB, limit int, lease time.Duration) ([]WebhookJob, error) {
+	rows, err := db.Query(`WITH claimed AS (
+                               UPDATE webhook_deliveries
+                               SET status = 'sending', locked_until = NOW() + $2 * INTERVAL '1 second'
+                               WHERE id IN (SELECT wd.id FROM webhook_deliveries wd
+                                            JOIN webhook_subscriptions ws ON ws.id = wd.subscription_id
+                                            WHERE ws.active
+                                            AND ((wd.status = 'pending' AND wd.next_attempt_at <= NOW())
+                                                 OR (wd.status = 'sending' AND wd.locked_until < NOW()))
+                                            ORDER BY wd.next_attempt_at
+                                            LIMIT $1 FOR UPDATE OF wd SKIP LOCKED)
+                               RETURNING *)
+                           SELECT `+webhookDeliveryColumns+`, s.url, s.secret, e.organization_id, e.user_id,
+                                  e.payload, e.created_at
+                           FROM claimed d
+                           JOIN webhook_subscriptions s ON s.id = d.subscription_id
+                           JOIN webhook_events e ON e.id = d.event_id`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var jobs []WebhookJob
+	for rows.Next() {
+		var j WebhookJob
+		fields := append(j.scanFields(), &j.URL, &j.Secret, &j.OrganizationID, &j.UserID, &j.Payload, &j.EventCreatedAt)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// RecordWebhookAttempt logs an attempt and moves the delivery on: delivered, scheduled again with
+// backoff, or dead when the failure is permanent or it ran out of attempts
+func RecordWebhookAttempt(db *sql.DB, j WebhookJob, a WebhookAttempt, delivered, permanent bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO webhook_attempts (delivery_id, attempted_at, status_code, error, duration_ms)
+                      VALUES ($1, $2, $3, $4, $5)`, j.ID, a.AttemptedAt, a.StatusCode, a.Error, a.DurationMs)
+	if err != nil {
+		return err
+	}
+
+	attempts := j.Attempts + 1
+	if delivered {
+		err = expectOneRow(tx.Exec(`UPDATE webhook_deliveries
+                                    SET status = 'delivered', attempts = $1, delivered_at = NOW(), locked_until = NULL,
+                                        last_error = NULL
+                                    WHERE id = $2`, attempts, j.ID))
+	} else {
+		status, next := WebhookPending, time.Now().Add(DeliveryBackoff(attempts))
+		if permanent || attempts >= j.MaxAttempts {
+			status, next = WebhookDead, time.Now()
+		}
+		err = expectOneRow(tx.Exec(`UPDATE webhook_deliveries
+                                    SET status = $1, attempts = $2, next_attempt_at = $3, locked_until = NULL,
+                                        last_error = $4
+                                    WHERE id = $5`, status, attempts, next, a.Error, j.ID))
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetWebhookDeliveries lists the deliveries of a subscription of the organization, newest first,
+// optionally by status
+func GetWebhookDeliveries(db *sql.DB, organizationID, subscriptionID int, status string, limit int) ([]WebhookDelivery, error) {
+	rows, err := db.Query(`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries d
+                           JOIN webhook_events e ON e.id = d.event_id
+                           WHERE d.subscription_id = $1 AND e.organization_id = $2 AND ($3 = '' OR d.status = $3)
+                           ORDER BY d.id DESC LIMIT $4`, subscriptionID, organizationID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(d.scanFields()...); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetWebhookDelivery returns a delivery of the organization with the log of its attempts
+func GetWebhookDelivery(db *sql.DB, organizationID int, id int64) (WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := db.QueryRow(`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries d
+                        JOIN webhook_events e ON e.id = d.event_id
+                        WHERE d.id = $1 AND e.organization_id = $2`, id, organizationID).Scan(d.scanFields()...)
+	if err != nil {
+		return d, err
+	}
+
+	rows, err := db.Query(`SELECT id, attempted_at, status_code, error, duration_ms
+                           FROM webhook_attempts WHERE delivery_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return d, err
+	}
+	defer rows.Close()
+	d.History = []WebhookAttempt{}
+	for rows.Next() {
+		var a WebhookAttempt
+		if err := rows.Scan(&a.ID, &a.AttemptedAt, &a.StatusCode, &a.Error, &a.DurationMs); err != nil {
+			return d, err
+		}
+		d.History = append(d.History, a)
+	}
+	return d, rows.Err()
+}
+
+// RedeliverWebhook queues a delivery of the organization again with a fresh set of attempts, whether
+// it died or was already delivered. A delivery being sent right now cannot be redelivered.
+func RedeliverWebhook(db *sql.DB, organizationID int, id int64) error {
+	err := expectOneRow(db.Exec(`UPDATE webhook_deliveries d
+                                 SET status = 'pending', attempts = 0, next_attempt_at = NOW(), locked_until = NULL
+                                 FROM webhook_events e
+                                 WHERE d.id = $1 AND e.id = d.event_id AND e.organization_id = $2
+                                 AND d.status <> 'sending'`, id, organizationID))
+	if err == sql.ErrNoRows {
+		if _, getErr := GetWebhookDelivery(db, organizationID, id); getErr == nil {
+			return ErrConflict
+		}
+	}
+	return err
+}
@@ -0,0 +1,251 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"go-auth-api/src/geo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Zone kinds
+const (
+	ZoneServiceArea = "service_area" // Vehicles must stay inside during a trip and trips must end inside
+	ZoneNoGo        = "no_go"        // Vehicles must not enter
+)
+
+// Geofence alert kinds
+const (
+	AlertLeftServiceArea = "left_service_area"
+	AlertEnteredNoGo     = "entered_no_go"
+)
+
+// Zone is a polygon with a rule attached
+type Zone struct {
+	ID        int         `json:"id"`
+	Name      string      `json:"name" binding:"required"`
+	Kind      string      `json:"kind" binding:"required"`
+	Polygon   geo.Polygon `json:"polygon" binding:"required"`
+	Active    bool        `json:"active"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// ZoneViolation is a rule broken by a position
+type ZoneViolation struct {
+	Kind   string `json:"kind"`
+	ZoneID *int   `json:"zone_id,omitempty"` // The no-go zone entered; nil when outside every service area
+	Zone   string `json:"zone,omitempty"`
+}
+
+func (z *Zone) Validate() error {
+	z.Name = strings.TrimSpace(z.Name)
+	if z.Name == "" {
+		return &ValidationError{"el nombre es requerido"}
+	}
+	if z.Kind != ZoneServiceArea && z.Kind != ZoneNoGo {
+		return &ValidationError{"kind debe ser service_area o no_go"}
+	}
+	if err := z.Polygon.Validate(); err != nil {
+		return &ValidationError{err.Error()}
+	}
+	return nil
+}
+
+const zoneColumns = `id, name, kind, polygon, active, created_at, updated_at`
+
+func scanZone(row interface{ Scan(...interface{}) error }) (Zone, error) {
+	var z Zone
+	var polygon []byte
+	if err := row.Scan(&z.ID, &z.Name, &z.Kind, &polygon, &z.Active, &z.CreatedAt, &z.UpdatedAt); err != nil {
+		return z, err
+	}
+	return z, json.Unmarshal(polygon, &z.Polygon)
+}
+
+// GetZones lists the zones; with activeOnly only those currently enforced
+func GetZones(db *sql.DB, activeOnly bool) ([]Zone, error) {
+	rows, err := db.Query(`SELECT `+zoneColumns+` FROM zones WHERE active OR NOT $1 ORDER BY id`, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []Zone{}
+	for rows.Next() {
+		z, err := scanZone(rows)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, z)
+	}
+	return zones, rows.Err()
+}
+
+func (z *Zone) Create(db *sql.DB) error {
+	if err := z.Validate(); err != nil {
+		return err
+	}
+	polygon, err := json.Marshal(z.Polygon)
+	if err != nil {
+		return err
+	}
+	return db.QueryRow(`INSERT INTO zones (name, kind, polygon, active) VALUES ($1, $2, $3, $4)
+                        RETURNING id, created_at, updated_at`, z.Name, z.Kind, polygon, z.Active).
+		Scan(&z.ID, &z.CreatedAt, &z.UpdatedAt)
+}
+
+func (z *Zone) Update(db *sql.DB) error {
+	if err := z.Validate(); err != nil {
+		return err
+	}
+	polygon, err := json.Marshal(z.Polygon)
+	if err != nil {
+		return err
+	}
+	return db.QueryRow(`UPDATE zones SET name = $1, kind = $2, polygon = $3, active = $4, updated_at = NOW()
+                        WHERE id = $5 RETURNING created_at, updated_at`, z.Name, z.Kind, polygon, z.Active, z.ID).
+		Scan(&z.CreatedAt, &z.UpdatedAt)
+}
+
+func DeleteZone(db *sql.DB, id int) error {
+	return expectOneRow(db.Exec(`DELETE FROM zones WHERE id = $1`, id))
+}
+
+// CheckZones returns the rules broken by a position. When no service area is defined the
+// whole map is allowed.
+func CheckZones(zones []Zone, p geo.Point) []ZoneViolation {
+	var violations []ZoneViolation
+	hasServiceArea, inServiceArea := false, false
+	for i := range zones {
+		z := &zones[i]
+		if !z.Active {
+			continue
+		}
+		switch z.Kind {
+		case ZoneServiceArea:
+			hasServiceArea = true
+			if z.Polygon.Contains(p) {
+				inServiceArea = true
+			}
+		case ZoneNoGo:
+			if z.Polygon.Contains(p) {
+				violations = append(violations, ZoneViolation{Kind: AlertEnteredNoGo, ZoneID: &z.ID, Zone: z.Name})
+			}
+		}
+	}
+	if hasServiceArea && !inServiceArea {
+		violations = append(violations, ZoneViolation{Kind: AlertLeftServiceArea})
+	}
+	return violations
+}
+
+// CheckPosition loads the active zones and returns the rules broken by a position
+func CheckPosition(db *sql.DB, lat, lng float64) ([]ZoneViolation, error) {
+	zones, err := GetZones(db, true)
+	if err != nil {
+		return nil, err
+	}
+	return CheckZones(zones, geo.Point{Lat: lat, Lng: lng}), nil
+}
+
+// GeofenceAlert records a vehicle breaking a zone rule during a reservation
+type GeofenceAlert struct {
+	ID            int       `json:"id"`
+	VehicleID     int       `json:"vehicle_id"`
+	ReservationID *int      `json:"reservation_id"`
+	UserID        *int      `json:"user_id,omitempty"`
+	ZoneID        *int      `json:"zone_id"`
+	Kind          string    `json:"kind"`
+	Latitude      float64   `json:"latitude"`
+	Longitude     float64   `json:"longitude"`
+	RecordedAt    time.Time `json:"recorded_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func violationKey(v ZoneViolation) string {
+	if v.ZoneID != nil {
+		return v.Kind + ":" + strconv.Itoa(*v.ZoneID)
+	}
+	return v.Kind
+}
+
+// EvaluateGeofences walks the path a vehicle followed since its previous known position and records an
+// alert for every rule that starts being broken while the vehicle is under an active reservation.
+// Rules that were already broken at the previous position do not alert again.
+func EvaluateGeofences(db *sql.DB, vehicleID int, previous *geo.Point, path []TrackedPoint) ([]GeofenceAlert, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	zones, err := GetZones(db, true)
+	if err != nil || len(zones) == 0 {
+		return nil, err
+	}
+
+	active := map[string]bool{}
+	if previous != nil {
+		for _, v := range CheckZones(zones, *previous) {
+			active[violationKey(v)] = true
+		}
+	}
+
+	var alerts []GeofenceAlert
+	for _, p := range path {
+		current := map[string]bool{}
+		for _, v := range CheckZones(zones, p.Point) {
+			key := violationKey(v)
+			current[key] = true
+			if active[key] {
+				continue
+			}
+
+			alert := GeofenceAlert{VehicleID: vehicleID, ZoneID: v.ZoneID, Kind: v.Kind,
+				Latitude: p.Lat, Longitude: p.Lng, RecordedAt: p.At}
+			err := db.QueryRow(`SELECT id, user_id FROM reservations
+                                WHERE vehicle_id = $1 AND status = 'activa' AND start_time <= $2 AND end_time >= $2
+                                ORDER BY start_time DESC LIMIT 1`, vehicleID, p.At).Scan(&alert.ReservationID, &alert.UserID)
+			if err == sql.ErrNoRows {
+				continue // Only trips are monitored
+			}
+			if err != nil {
+				return alerts, err
+			}
+
+			err = db.QueryRow(`INSERT INTO geofence_alerts (vehicle_id, reservation_id, zone_id, kind, latitude, longitude, recorded_at)
+                               VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+				alert.VehicleID, alert.ReservationID, alert.ZoneID, alert.Kind, alert.Latitude, alert.Longitude, alert.RecordedAt).
+				Scan(&alert.ID, &alert.CreatedAt)
+			if err != nil {
+				return alerts, err
+			}
+			alerts = append(alerts, alert)
+		}
+		active = current
+	}
+	return alerts, nil
+}
+
+// GetGeofenceAlerts lists the most recent alerts, optionally for one vehicle
+func GetGeofenceAlerts(db *sql.DB, vehicleID *int, limit int) ([]GeofenceAlert, error) {
+	rows, err := db.Query(`SELECT a.id, a.vehicle_id, a.reservation_id, r.user_id, a.zone_id, a.kind, a.latitude,
+                                  a.longitude, a.recorded_at, a.created_at
+                           FROM geofence_alerts a LEFT JOIN reservations r ON r.id = a.reservation_id
+                           WHERE ($1::int IS NULL OR a.vehicle_id = $1)
+                           ORDER BY a.created_at DESC, a.id DESC LIMIT $2`, vehicleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []GeofenceAlert{}
+	for rows.Next() {
+		var a GeofenceAlert
+		if err := rows.Scan(&a.ID, &a.VehicleID, &a.ReservationID, &a.UserID, &a.ZoneID, &a.Kind, &a.Latitude,
+			&a.Longitude, &a.RecordedAt, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"go-auth-api/src/geo"
+	"go-auth-api/src/testdb"
+	"reflect"
+	"testing"
+)
+
+// Service area [0,10] x [0,10] with a no-go zone [4,6] x [4,6] in the middle
+var (
+	testServiceArea = geo.Polygon{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 10}, {Lat: 10, Lng: 10}, {Lat: 10, Lng: 0}}
+	testNoGo        = geo.Polygon{{Lat: 4, Lng: 4}, {Lat: 4, Lng: 6}, {Lat: 6, Lng: 6}, {Lat: 6, Lng: 4}}
+	testFarNoGo     = geo.Polygon{{Lat: 20, Lng: 20}, {Lat: 20, Lng: 22}, {Lat: 22, Lng: 22}, {Lat: 22, Lng: 20}}
+)
+
+func TestCheckZones(t *testing.T) {
+	serviceArea := Zone{ID: 1, Name: "Centro", Kind: ZoneServiceArea, Polygon: testServiceArea, Active: true}
+	noGo := Zone{ID: 2, Name: "Aeropuerto", Kind: ZoneNoGo, Polygon: testNoGo, Active: true}
+	farNoGo := Zone{ID: 3, Name: "Puerto", Kind: ZoneNoGo, Polygon: testFarNoGo, Active: true}
+	inactive := func(z Zone) Zone { z.Active = false; return z }
+
+	left := ZoneViolation{Kind: AlertLeftServiceArea}
+	entered := func(z Zone) ZoneViolation {
+		id := z.ID
+		return ZoneViolation{Kind: AlertEnteredNoGo, ZoneID: &id, Zone: z.Name}
+	}
+
+	tests := []struct {
+		name  string
+		zones []Zone
+		point geo.Point
+		want  []ZoneViolation
+	}{
+		{"no zones", nil, geo.Point{Lat: 50, Lng: 50}, nil},
+		{"inside the service area", []Zone{serviceArea, noGo}, geo.Point{Lat: 1, Lng: 1}, nil},
+		{"on the service area boundary", []Zone{serviceArea}, geo.Point{Lat: 0, Lng: 5}, nil},
+		{"outside the service area", []Zone{serviceArea, noGo}, geo.Point{Lat: 11, Lng: 5}, []ZoneViolation{left}},
+		{"inside a no-go zone", []Zone{serviceArea, noGo}, geo.Point{Lat: 5, Lng: 5}, []ZoneViolation{entered(noGo)}},
+		{"on a no-go boundary", []Zone{serviceArea, noGo}, geo.Point{Lat: 4, Lng: 5}, []ZoneViolation{entered(noGo)}},
+		{"no-go zone outside the service area", []Zone{serviceArea, farNoGo}, geo.Point{Lat: 21, Lng: 21},
+			[]ZoneViolation{entered(farNoGo), left}},
+		{"only no-go zones allow the rest of the map", []Zone{noGo}, geo.Point{Lat: 50, Lng: 50}, nil},
+		{"inactive service area is ignored", []Zone{inactive(serviceArea)}, geo.Point{Lat: 50, Lng: 50}, nil},
+		{"inactive no-go zone is ignored", []Zone{serviceArea, inactive(noGo)}, geo.Point{Lat: 5, Lng: 5}, nil},
+		{"inside any of several service areas", []Zone{
+			{ID: 4, Kind: ZoneServiceArea, Polygon: testFarNoGo, Active: true}, serviceArea,
+		}, geo.Point{Lat: 1, Lng: 1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckZones(tt.zones, tt.point); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckZones() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPosition(t *testing.T) {
+	db := testdb.Open(t)
+	for _, z := range []Zone{
+		{Name: "Centro", Kind: ZoneServiceArea, Polygon: testServiceArea, Active: true},
+		{Name: "Aeropuerto", Kind: ZoneNoGo, Polygon: testNoGo, Active: true},
+		{Name: "Puerto", Kind: ZoneNoGo, Polygon: testFarNoGo, Active: false},
+	} {
+		if err := z.Create(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     []string
+	}{
+		{"inside the service area", 1, 1, nil},
+		{"inside the no-go zone", 5, 5, []string{AlertEnteredNoGo}},
+		{"outside the service area", 11, 5, []string{AlertLeftServiceArea}},
+		{"inside an inactive zone", 21, 21, []string{AlertLeftServiceArea}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := CheckPosition(db, tt.lat, tt.lng)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range violations {
+				got = append(got, v.Kind)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckPosition(%v, %v) = %v, want %v", tt.lat, tt.lng, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package realtime
+
+import (
+	"go-auth-api/src/geo"
+	"sync"
+	"time"
+)
+
+// Tipos de evento publicados para los vehículos
+const (
+	EventPosition = "position"
+	EventStatus   = "status"
+)
+
+// Event es un cambio de posición o de estado de un vehículo
+type Event struct {
+	Type      string    `json:"type"`
+	VehicleID int       `json:"vehicle_id"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Status    string    `json:"status,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// BBox es un rectángulo geográfico [MinLat, MaxLat] x [MinLng, MaxLng]
+type BBox struct {
+	MinLat, MinLng, MaxLat, MaxLng float64
+}
+
+// Contains indica si el punto está dentro del rectángulo
+func (b BBox) Contains(p geo.Point) bool {
+	return p.Lat >= b.MinLat && p.Lat <= b.MaxLat && p.Lng >= b.MinLng && p.Lng <= b.MaxLng
+}
+
+// Filter selecciona los eventos de una suscripción; sin IDs ni BBox recibe todos
+type Filter struct {
+	VehicleIDs map[int]bool
+	BBox       *BBox
+}
+
+func (f Filter) matches(e Event) bool {
+	if len(f.VehicleIDs) > 0 && !f.VehicleIDs[e.VehicleID] {
+		return false
+	}
+	if f.BBox != nil && !f.BBox.Contains(geo.Point{Lat: e.Latitude, Lng: e.Longitude}) {
+		return false
+	}
+	return true
+}
+
+// Subscription recibe los eventos que cumplen su filtro por C. Si el cliente no consume a
+// tiempo se descartan los eventos más antiguos; tras demasiados descartes seguidos, sin que el
+// cliente llegue a vaciar su buffer, se cierra C.
+type Subscription struct {
+	C       <-chan Event
+	ch      chan Event
+	filter  Filter
+	dropped int // Descartes desde la última entrega sin buffer lleno
+	closed  bool
+}
+
+// Hub reparte los eventos de vehículos entre los suscriptores del proceso
+type Hub struct {
+	mu        sync.Mutex
+	subs      map[*Subscription]struct{}
+	positions map[int]geo.Point // Última posición conocida, para filtrar eventos de estado por zona
+	buffer    int
+	maxDrops  int
+}
+
+// NewHub crea un hub con buffer eventos por suscriptor; un suscriptor que acumula maxDrops
+// descartes seguidos se desconecta
+func NewHub(buffer, maxDrops int) *Hub {
+	return &Hub{
+		subs:      map[*Subscription]struct{}{},
+		positions: map[int]geo.Point{},
+		buffer:    buffer,
+		maxDrops:  maxDrops,
+	}
+}
+
+// Vehicles es el hub de eventos de vehículos de la aplicación
+var Vehicles = NewHub(64, 512)
+
+// Subscribe registra un suscriptor; debe liberarse con Unsubscribe
+func (h *Hub) Subscribe(filter Filter) *Subscription {
+	ch := make(chan Event, h.buffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe elimina el suscriptor y cierra su canal
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	delete(h.subs, sub)
+	sub.closed = true
+	close(sub.ch)
+}
+
+// Publish entrega el evento sin bloquear: la lentitud de un cliente nunca frena al publicador
+func (h *Hub) Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if e.Type == EventPosition {
+		h.positions[e.VehicleID] = geo.Point{Lat: e.Latitude, Lng: e.Longitude}
+	} else if p, ok := h.positions[e.VehicleID]; ok && e.Latitude == 0 && e.Longitude == 0 {
+		e.Latitude, e.Longitude = p.Lat, p.Lng
+	}
+
+	for sub := range h.subs {
+		if !sub.filter.matches(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+			// El cliente se puso al día: los descartes anteriores ya no cuentan
+			sub.dropped = 0
+			continue
+		default:
+		}
+
+		// Buffer lleno: descartar el evento más antiguo para conservar el estado más reciente
+		select {
+		case <-sub.ch:
+			sub.dropped++
+		default:
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			sub.dropped++
+		}
+		if sub.dropped >= h.maxDrops {
+			h.remove(sub)
+		}
+	}
+}
+
+// PublishPosition es un atajo para publicar un cambio de posición
+func PublishPosition(vehicleID int, lat, lng float64, at time.Time) {
+	Vehicles.Publish(Event{Type: EventPosition, VehicleID: vehicleID, Latitude: lat, Longitude: lng, At: at})
+}
+
+// PublishStatus es un atajo para publicar un cambio de estado
+func PublishStatus(vehicleID int, status string, lat, lng float64) {
+	Vehicles.Publish(Event{Type: EventStatus, VehicleID: vehicleID, Status: status, Latitude: lat, Longitude: lng})
+}
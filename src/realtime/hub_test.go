@@ -0,0 +1,139 @@
+package realtime
+
+import (
+	"slices"
+	"testing"
+)
+
+// event numera los eventos por la latitud para poder comprobar cuáles quedaron en el buffer
+func event(n int) Event {
+	return Event{Type: EventPosition, VehicleID: 1, Latitude: float64(n), Longitude: 1}
+}
+
+// drain lee lo que hay en el buffer sin bloquear y dice si el canal quedó cerrado
+func drain(sub *Subscription) (got []int, closed bool) {
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return got, true
+			}
+			got = append(got, int(e.Latitude))
+		default:
+			return got, false
+		}
+	}
+}
+
+func TestHubDropsOldest(t *testing.T) {
+	h := NewHub(3, 100)
+	sub := h.Subscribe(Filter{})
+	for n := 1; n <= 5; n++ {
+		h.Publish(event(n))
+	}
+	got, closed := drain(sub)
+	if want := []int{3, 4, 5}; !slices.Equal(got, want) {
+		t.Errorf("buffer = %v, se esperaban los más recientes %v", got, want)
+	}
+	if closed {
+		t.Error("el suscriptor se desconectó con menos descartes que el máximo")
+	}
+}
+
+func TestHubDisconnectsAfterConsecutiveDrops(t *testing.T) {
+	steps := []struct {
+		name        string
+		publish     int  // Eventos publicados en el paso
+		drain       bool // El cliente vacía su buffer al final del paso
+		wantClosed  bool
+		wantDropped int
+	}{
+		{"llena el buffer", 2, false, false, 0},
+		{"dos descartes", 2, false, false, 2},
+		{"el cliente se pone al día", 0, true, false, 2},
+		{"una entrega reinicia la cuenta", 1, false, false, 0},
+		{"vuelve a llenarse", 1, false, false, 0},
+		{"dos descartes más", 2, false, false, 2},
+		{"el tercero seguido lo desconecta", 1, false, true, 3},
+	}
+
+	h := NewHub(2, 3)
+	sub := h.Subscribe(Filter{})
+	n := 0
+	for _, s := range steps {
+		for i := 0; i < s.publish; i++ {
+			n++
+			h.Publish(event(n))
+		}
+		h.mu.Lock()
+		dropped, closed := sub.dropped, sub.closed
+		_, subscribed := h.subs[sub]
+		h.mu.Unlock()
+		if dropped != s.wantDropped || closed != s.wantClosed || subscribed == s.wantClosed {
+			t.Fatalf("%s: descartes=%d cerrado=%t suscrito=%t; se esperaba descartes=%d cerrado=%t",
+				s.name, dropped, closed, subscribed, s.wantDropped, s.wantClosed)
+		}
+		if s.drain {
+			drain(sub)
+		}
+	}
+
+	// El canal conserva los últimos eventos y después queda cerrado
+	got, closed := drain(sub)
+	if want := []int{n - 1, n}; !slices.Equal(got, want) || !closed {
+		t.Errorf("tras desconectar: buffer=%v cerrado=%t, se esperaba %v y el canal cerrado", got, closed, want)
+	}
+	// Publicar después no escribe en el canal cerrado y Unsubscribe no lo cierra otra vez
+	h.Publish(event(n + 1))
+	h.Unsubscribe(sub)
+}
+
+func TestHubSlowSubscriberDoesNotAffectOthers(t *testing.T) {
+	h := NewHub(2, 3)
+	slow := h.Subscribe(Filter{})
+	fast := h.Subscribe(Filter{})
+	var received []int
+	for n := 1; n <= 10; n++ {
+		h.Publish(event(n))
+		got, closed := drain(fast)
+		if closed {
+			t.Fatalf("evento %d: se desconectó al suscriptor que consume a tiempo", n)
+		}
+		received = append(received, got...)
+	}
+	if len(received) != 10 {
+		t.Errorf("el suscriptor rápido recibió %v, se esperaban los 10 eventos", received)
+	}
+	if _, closed := drain(slow); !closed {
+		t.Error("el suscriptor lento sigue conectado tras superar el máximo de descartes")
+	}
+}
+
+func TestHubFilteredEventsDoNotCountAsDrops(t *testing.T) {
+	h := NewHub(1, 2)
+	sub := h.Subscribe(Filter{VehicleIDs: map[int]bool{1: true}})
+	h.Publish(event(1)) // Llena el buffer
+	for i := 0; i < 10; i++ {
+		h.Publish(Event{Type: EventPosition, VehicleID: 2, Latitude: 50, Longitude: 50})
+	}
+	got, closed := drain(sub)
+	if !slices.Equal(got, []int{1}) || closed {
+		t.Errorf("buffer=%v cerrado=%t; los eventos de otros vehículos no debían afectar al suscriptor", got, closed)
+	}
+}
+
+func TestHubStatusUsesLastPosition(t *testing.T) {
+	h := NewHub(4, 4)
+	inside := h.Subscribe(Filter{BBox: &BBox{MinLat: 18, MinLng: -70, MaxLat: 19, MaxLng: -69}})
+	h.Publish(Event{Type: EventPosition, VehicleID: 7, Latitude: 18.47, Longitude: -69.9})
+	h.Publish(Event{Type: EventStatus, VehicleID: 7, Status: "retired"})   // Sin posición: usa la última
+	h.Publish(Event{Type: EventStatus, VehicleID: 8, Status: "available"}) // Sin posición conocida: fuera del área
+
+	var got []Event
+	for len(inside.C) > 0 {
+		got = append(got, <-inside.C)
+	}
+	if len(got) != 2 || got[1].Status != "retired" || got[1].Latitude != 18.47 || got[1].Longitude != -69.9 {
+		t.Errorf("eventos recibidos %+v; se esperaba la posición y el estado del vehículo 7 con su última posición", got)
+	}
+}
@@ -0,0 +1,51 @@
+package realtime
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// listenerPing comprueba cada tanto la conexión de LISTEN; sin tráfico una conexión caída no se nota
+const listenerPing = 90 * time.Second
+
+// Listen reenvía a este proceso los avisos publicados con pg_notify en el canal de PostgreSQL: el
+// payload es la clave a despertar. Así una unidad esperando en una réplica se entera de los comandos
+// encolados en otra. Tras una reconexión despierta a todos, porque pudo perderse algún aviso.
+func (s *Signal) Listen(ctx context.Context, dsn, channel string) error {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("LISTEN %s: %v", channel, err)
+		}
+	})
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return err
+	}
+
+	go func() {
+		defer listener.Close()
+		ping := time.NewTicker(listenerPing)
+		defer ping.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.Notify:
+				if n == nil {
+					s.NotifyAll()
+					continue
+				}
+				if key, err := strconv.Atoi(n.Extra); err == nil {
+					s.Notify(key)
+				}
+			case <-ping.C:
+				go listener.Ping()
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,49 @@
+package realtime
+
+import "sync"
+
+// Signal despierta a quienes esperan novedades de una clave (p. ej. comandos de un vehículo)
+type Signal struct {
+	mu      sync.Mutex
+	waiters map[int]chan struct{}
+}
+
+// NewSignal crea un Signal vacío
+func NewSignal() *Signal {
+	return &Signal{waiters: map[int]chan struct{}{}}
+}
+
+// Commands avisa a las unidades conectadas de que su vehículo tiene comandos nuevos
+var Commands = NewSignal()
+
+// Wait devuelve un canal que se cierra en el próximo Notify de la clave
+func (s *Signal) Wait(key int) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch, ok := s.waiters[key]
+	if !ok {
+		ch = make(chan struct{})
+		s.waiters[key] = ch
+	}
+	return ch
+}
+
+// Notify despierta a todos los que esperan la clave
+func (s *Signal) Notify(key int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ch, ok := s.waiters[key]; ok {
+		close(ch)
+		delete(s.waiters, key)
+	}
+}
+
+// NotifyAll despierta a todos los que esperan, sea cual sea su clave
+func (s *Signal) NotifyAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, ch := range s.waiters {
+		close(ch)
+		delete(s.waiters, key)
+	}
+}
@@ -0,0 +1,36 @@
+package realtime
+
+import "testing"
+
+func closed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestSignal(t *testing.T) {
+	s := NewSignal()
+	a1, a2, b := s.Wait(1), s.Wait(1), s.Wait(2)
+	if a1 != a2 {
+		t.Error("dos esperas de la misma clave deben compartir el canal")
+	}
+
+	s.Notify(1)
+	if !closed(a1) || closed(b) {
+		t.Errorf("tras Notify(1): clave 1 despierta=%t, clave 2 despierta=%t; se esperaba solo la 1", closed(a1), closed(b))
+	}
+	if next := s.Wait(1); closed(next) {
+		t.Error("una espera nueva tras el aviso no debe estar despierta")
+	}
+	s.Notify(3) // Sin esperas: no hace nada
+
+	// Tras una reconexión del LISTEN se despierta a todos
+	c := s.Wait(1)
+	s.NotifyAll()
+	if !closed(b) || !closed(c) {
+		t.Error("NotifyAll no despertó todas las esperas")
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"go-auth-api/src/controllers"
 	middlewares "go-auth-api/src/middleware"
+	"go-auth-api/src/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,6 +14,9 @@ func SetupRoutes(r *gin.Engine) {
 	r.POST("/register", controllers.Register)
 	r.POST("/login", controllers.Login)
 	r.POST("/token", controllers.GenerateToken)
+	// Enlace para dejar de recibir un tipo de correo; el token firmado identifica al usuario
+	r.GET("/unsubscribe", controllers.ShowUnsubscribe)
+	r.POST("/unsubscribe", controllers.Unsubscribe)
 	// Rutas protegidas con autenticación
 	protected := r.Group("/api")
 	protected.Use(middlewares.AuthMiddleware())
@@ -30,21 +34,176 @@ func SetupRoutes(r *gin.Engine) {
 		protected.PUT("/reservations/:id", controllers.UpdateReservation)    // Actualizar una reserva
 		protected.DELETE("/reservations/:id", controllers.DeleteReservation) // Eliminar una reserva
 		protected.POST("/reservations/check-availability", controllers.CheckVehicleAvailability)
+		// Cobros recibidos en mostrador o en la terminal; los registra el personal, nunca el cliente
+		protected.POST("/payments", middlewares.RequireRole(models.RoleOperator, models.RoleAdmin), controllers.ProcessReservationPayment)
 
-		// Rutas de notificaciones
-		protected.POST("/notifications", controllers.SendNotification)
-		protected.POST("/notifications/reminder", controllers.SendNotificationReminder)
-		protected.POST("/notifications/bot", controllers.SendBothNotifications)
+		// Envío manual de notificaciones a cualquier usuario, reservado al personal
+		notificationStaff := middlewares.RequireRole(models.RoleOperator, models.RoleAdmin)
+		protected.POST("/notifications", notificationStaff, controllers.SendNotification)
+		protected.POST("/notifications/reminder", notificationStaff, controllers.SendNotificationReminder)
+		protected.POST("/notifications/bot", notificationStaff, controllers.SendBothNotifications)
 
-		protected.GET("/notifications/:user_id", controllers.GetUserNotifications) // Obtener notificaciones del usuario
+		// Bandeja de notificaciones del usuario autenticado
+		protected.GET("/me/notifications", controllers.ListMyNotifications)
+		protected.GET("/me/notifications/unread-count", controllers.CountMyUnreadNotifications)
+		protected.POST("/me/notifications/read-all", controllers.MarkAllNotificationsRead)
+		protected.POST("/me/notifications/:id/read", controllers.MarkNotificationRead)
+		protected.DELETE("/me/notifications/:id", controllers.DeleteNotification)
 
 		// Rutas de vehículos
-		protected.GET("/vehicles", controllers.ListVehicles) // Listar vehículos disponibles
+		protected.GET("/vehicles", controllers.ListVehicles)                                                                          // Listar vehículos disponibles
+		protected.GET("/vehicles/stream", middlewares.RequireRole(models.RoleOperator, models.RoleAdmin), controllers.StreamVehicles) // Cambios en tiempo real (SSE)
 		protected.GET("/vehicules/available-vehicles", controllers.GetAvailableVehicles)
 		protected.GET("/vehicles/:id", controllers.GetVehicle) // Obtener información de un vehículo específico
 		protected.POST("/vehicles/check-availability", controllers.CheckVehicleAvailability)
+
+		// Galería de imágenes del vehículo, gestionada por operadores
+		fleetStaff := middlewares.RequireRole(models.RoleOperator, models.RoleAdmin)
+		protected.POST("/vehicles/:id/images", fleetStaff, controllers.UploadVehicleImage)
+		protected.PATCH("/vehicles/:id/images", fleetStaff, controllers.ReorderVehicleImages)
+		protected.PUT("/vehicles/:id/images/:image_id/primary", fleetStaff, controllers.SetPrimaryVehicleImage)
+		protected.DELETE("/vehicles/:id/images/:image_id", fleetStaff, controllers.DeleteVehicleImage)
+
+		// Ubicación y estado, reportados por operadores
+		protected.PATCH("/vehicles/:id/location", fleetStaff, controllers.UpdateVehicleLocation)
+		protected.PATCH("/vehicles/:id/status", fleetStaff, controllers.UpdateVehicleStatus)
+
+		// Telemetría y recorridos
+		protected.GET("/vehicles/:id/trail", fleetStaff, controllers.GetVehicleTrail)
+		protected.GET("/reservations/:id/trail", controllers.GetReservationTrail)
+		protected.GET("/zones", controllers.ListZones)
+
+		// Comandos remotos (apertura sin llave)
+		protected.POST("/reservations/:id/commands", controllers.CreateReservationCommand)
+		protected.GET("/commands/:id", controllers.GetVehicleCommand)
+
+		// Estado del vehículo y daños
+		protected.POST("/reservations/:id/checklists", controllers.SubmitChecklist)
+		protected.POST("/reservations/:id/damage-reports", controllers.CreateDamageReport)
+		protected.GET("/damage-reports/:id", controllers.GetDamageReport)
+		protected.POST("/damage-reports/:id/photos", controllers.UploadDamagePhoto)
+
+		// Reseñas
+		protected.POST("/reservations/:id/review", controllers.CreateReview)
+		protected.GET("/vehicles/:id/reviews", controllers.ListVehicleReviews)
+
+		// Favoritos del usuario autenticado
+		protected.GET("/me/favorites", controllers.ListFavorites)
+		protected.PUT("/me/favorites/:vehicle_id", controllers.SaveFavorite)
+		protected.DELETE("/me/favorites/:vehicle_id", controllers.RemoveFavorite)
+
+		// Canales de notificación del usuario autenticado
+		protected.GET("/me/notification-channels", controllers.GetChannelPreferences)
+		protected.PUT("/me/notification-channels", controllers.UpdateChannelPreferences)
+		protected.POST("/me/push-tokens", controllers.RegisterPushToken)
+		protected.DELETE("/me/push-tokens/:token", controllers.RemovePushToken)
+		protected.PUT("/me/language", controllers.UpdateLanguage)
+		protected.GET("/me/notification-preferences", controllers.GetNotificationPreferences)
+		protected.PUT("/me/notification-preferences", controllers.UpdateNotificationPreferences)
+
+		// Webhooks de la organización, gestionados por sus administradores
+		protected.GET("/organizations/:org_id/webhooks", controllers.ListWebhooks)
+		protected.POST("/organizations/:org_id/webhooks", controllers.CreateWebhook)
+		protected.PUT("/organizations/:org_id/webhooks/:id", controllers.UpdateWebhook)
+		protected.DELETE("/organizations/:org_id/webhooks/:id", controllers.DeleteWebhook)
+		protected.POST("/organizations/:org_id/webhooks/:id/rotate-secret", controllers.RotateWebhookSecret)
+		protected.GET("/organizations/:org_id/webhooks/:id/deliveries", controllers.ListWebhookDeliveries)
+		protected.GET("/organizations/:org_id/webhook-deliveries/:delivery_id", controllers.GetWebhookDelivery)
+		protected.POST("/organizations/:org_id/webhook-deliveries/:delivery_id/redeliver", controllers.RedeliverWebhook)
+
 		// Rutas de personajes
 		protected.GET("/characters/fetch-all", controllers.FetchAndSaveAllCharacters) // Obtener y guardar todos los personajes
 		protected.GET("/characters", controllers.GetPaginatedCharacters)              // Obtener personajes con paginación y búsqueda
 	}
+
+	// Rutas de administración de la flota, solo para operadores y administradores
+	admin := r.Group("/api/admin")
+	admin.Use(middlewares.AuthMiddleware(), middlewares.RequireRole(models.RoleOperator, models.RoleAdmin))
+	{
+		admin.POST("/vehicles", controllers.CreateVehicle)
+		admin.PUT("/vehicles/:id", controllers.UpdateVehicle)
+		admin.PUT("/vehicles/:id/pricing", controllers.SetVehiclePricing)
+		admin.POST("/vehicles/:id/retire", controllers.RetireVehicle)
+		admin.DELETE("/vehicles/:id", controllers.DeleteVehicle)
+		admin.POST("/vehicles/:id/commands", controllers.CreateVehicleCommand)
+
+		// Mantenimiento
+		admin.GET("/vehicles/:id/maintenance", controllers.ListMaintenanceRecords)
+		admin.POST("/vehicles/:id/maintenance", controllers.CreateMaintenanceRecord)
+		admin.GET("/vehicles/:id/downtime", controllers.ListDowntime)
+		admin.POST("/vehicles/:id/downtime", controllers.CreateDowntime)
+		admin.DELETE("/downtime/:id", controllers.DeleteDowntime)
+		admin.GET("/vehicles/:id/maintenance-schedules", controllers.ListMaintenanceSchedules)
+		admin.POST("/vehicles/:id/maintenance-schedules", controllers.CreateMaintenanceSchedule)
+		admin.PUT("/maintenance-schedules/:id", controllers.UpdateMaintenanceSchedule)
+		admin.DELETE("/maintenance-schedules/:id", controllers.DeleteMaintenanceSchedule)
+		admin.GET("/maintenance/due", controllers.ListDueMaintenance)
+		admin.POST("/maintenance/reminders", controllers.RunMaintenanceReminders)
+
+		// Reportes de daños
+		admin.GET("/damage-reports", controllers.ListDamageReports)
+		admin.POST("/damage-reports/:id/charge", controllers.ChargeDamageReport)
+		admin.POST("/damage-reports/:id/dismiss", controllers.DismissDamageReport)
+
+		// Moderación de reseñas
+		admin.GET("/reviews", controllers.ListReviews)
+		admin.PATCH("/reviews/:id", controllers.ModerateReview)
+		admin.DELETE("/reviews/:id", controllers.DeleteReview)
+
+		// Outbox de notificaciones (correo, SMS y push)
+		admin.GET("/deliveries", controllers.ListDeliveries)
+		admin.POST("/deliveries/:id/retry", controllers.RetryDelivery)
+		admin.GET("/notification-templates", controllers.ListNotificationTemplates)
+		admin.GET("/notification-templates/:name/preview", controllers.PreviewNotificationTemplate)
+
+		// Eventos de dominio y registro de auditoría
+		admin.GET("/events", controllers.ListDomainEvents)
+		admin.POST("/events/:id/handlers/:subscriber/retry", controllers.RetryEventHandler)
+		admin.GET("/audit-log", controllers.ListAuditLog)
+
+		// Clientes corporativos
+		platformAdmin := middlewares.RequireRole(models.RoleAdmin)
+		admin.GET("/organizations", platformAdmin, controllers.ListOrganizations)
+		admin.POST("/organizations", platformAdmin, controllers.CreateOrganization)
+		admin.PUT("/organizations/:id/members/:user_id", platformAdmin, controllers.SetOrganizationMember)
+		admin.DELETE("/organizations/:id/members/:user_id", platformAdmin, controllers.RemoveOrganizationMember)
+
+		// Catálogos
+		admin.GET("/brands", controllers.ListBrands)
+		admin.POST("/brands", controllers.CreateBrand)
+		admin.PUT("/brands/:id", controllers.UpdateBrand)
+		admin.DELETE("/brands/:id", controllers.DeleteBrand)
+		admin.GET("/models", controllers.ListCarModels)
+		admin.POST("/models", controllers.CreateCarModel)
+		admin.PUT("/models/:id", controllers.UpdateCarModel)
+		admin.DELETE("/models/:id", controllers.DeleteCarModel)
+		admin.GET("/fuel-types", controllers.ListFuelTypes)
+		admin.POST("/fuel-types", controllers.CreateFuelType)
+		admin.PUT("/fuel-types/:id", controllers.UpdateFuelType)
+		admin.DELETE("/fuel-types/:id", controllers.DeleteFuelType)
+
+		// Unidades telemáticas y sus credenciales
+		admin.GET("/devices", controllers.ListDevices)
+		admin.POST("/devices", controllers.IssueDevice)
+		admin.POST("/devices/:id/rotate", controllers.RotateDeviceCredential)
+		admin.POST("/devices/:id/revoke", controllers.RevokeDevice)
+
+		// Geocercas
+		admin.POST("/zones", controllers.CreateZone)
+		admin.PUT("/zones/:id", controllers.UpdateZone)
+		admin.DELETE("/zones/:id", controllers.DeleteZone)
+		admin.GET("/geofence-alerts", controllers.ListGeofenceAlerts)
+	}
+
+	// Rutas de las unidades telemáticas, autenticadas por dispositivo y no con el JWT de clientes
+	device := r.Group("/device")
+	device.Use(middlewares.DeviceAuthMiddleware())
+	{
+		device.POST("/telemetry", controllers.DeviceIngestTelemetry)
+		device.PATCH("/location", controllers.DeviceUpdateLocation)
+		device.PATCH("/status", controllers.DeviceUpdateStatus)
+		device.GET("/commands", controllers.DeviceFetchCommands)
+		device.GET("/commands/stream", controllers.DeviceStreamCommands)
+		device.POST("/commands/:id/ack", controllers.DeviceAckCommand)
+	}
 }
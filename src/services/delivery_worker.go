@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-auth-api/src/mail"
+	"go-auth-api/src/models"
+	"log"
+	"net/textproto"
+	"regexp"
+	"time"
+)
+
+// sendTimeout limita cuánto puede tardar un canal en entregar un mensaje
+const sendTimeout = 30 * time.Second
+
+// DeliveryWorker entrega los mensajes del outbox, cada uno por el Notifier de su canal, con reintentos
+type DeliveryWorker struct {
+	DB           *sql.DB
+	Notifiers    map[string]Notifier // Por canal: email, sms, push
+	BatchSize    int                 // Mensajes reclamados por ronda
+	PollInterval time.Duration       // Espera entre rondas cuando no hay trabajo
+	Lease        time.Duration       // Tiempo tras el cual otro worker puede reclamar un envío que no terminó
+}
+
+// NewDeliveryWorker crea un worker con los valores por defecto
+func NewDeliveryWorker(db *sql.DB, notifiers map[string]Notifier) *DeliveryWorker {
+	return &DeliveryWorker{DB: db, Notifiers: notifiers, BatchSize: 20, PollInterval: 5 * time.Second, Lease: 2 * time.Minute}
+}
+
+// Start procesa el outbox en segundo plano hasta que se cancele ctx
+func (w *DeliveryWorker) Start(ctx context.Context) {
+	go func() {
+		for {
+			processed, err := w.RunOnce(ctx)
+			if err != nil {
+				log.Printf("Error procesando el outbox de notificaciones: %v", err)
+			}
+			// Si la ronda vino llena probablemente queda más trabajo: seguir sin esperar
+			if err == nil && processed == w.BatchSize {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.PollInterval):
+			}
+		}
+	}()
+}
+
+// RunOnce reclama y entrega una ronda de mensajes; devuelve cuántos procesó
+func (w *DeliveryWorker) RunOnce(ctx context.Context) (int, error) {
+	deliveries, err := models.ClaimDeliveries(w.DB, w.BatchSize, w.Lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range deliveries {
+		if sendErr := w.deliver(ctx, d); sendErr != nil {
+			permanent := IsPermanent(sendErr)
+			if err := models.MarkDeliveryFailed(w.DB, d, sendErr, permanent); err != nil {
+				return 0, err
+			}
+			log.Printf("Envío %d por %s a %s falló (intento %d, permanente=%t): %v",
+				d.ID, d.Channel, d.Recipient, d.Attempts+1, permanent, sendErr)
+			continue
+		}
+		if err := models.MarkDeliverySent(w.DB, d.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+func (w *DeliveryWorker) deliver(ctx context.Context, d models.Delivery) error {
+	notifier, ok := w.Notifiers[d.Channel]
+	if !ok {
+		// Queda en dead para reintentarlo a mano una vez configurado el canal
+		return Permanent(fmt.Errorf("el canal %s no está configurado", d.Channel))
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return notifier.Notify(ctx, Message{To: d.Recipient, Subject: d.Subject, Body: d.Body, Text: d.TextBody})
+}
+
+// Los errores de gomail llegan como texto, así que además del tipo se reconoce el código SMTP 5xx
+var permanentEmailError = regexp.MustCompile(`(^|: )5[0-9]{2} |invalid address`)
+
+// IsPermanentEmailError indica si reintentar no tiene sentido: el servidor rechazó el mensaje con
+// un código 5xx o la dirección es inválida. Los 4xx y los fallos de red se reintentan.
+func IsPermanentEmailError(err error) bool {
+	if errors.Is(err, mail.ErrInvalidAddress) {
+		return true
+	}
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code >= 500
+	}
+	return permanentEmailError.MatchString(err.Error())
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-auth-api/src/models"
+	"go-auth-api/src/testdb"
+	"testing"
+)
+
+func TestDeliverRoutesByChannel(t *testing.T) {
+	email, sms, push := NewFakeNotifier(models.ChannelEmail), NewFakeNotifier(models.ChannelSMS), NewFakeNotifier(models.ChannelPush)
+	w := NewDeliveryWorker(nil, map[string]Notifier{models.ChannelEmail: email, models.ChannelSMS: sms, models.ChannelPush: push})
+
+	deliveries := []models.Delivery{
+		{Channel: models.ChannelEmail, Recipient: "ana@example.com", Subject: "Reserva", Body: "<p>Hola</p>", TextBody: "Hola"},
+		{Channel: models.ChannelSMS, Recipient: "+18095550100", Subject: "Reserva", Body: "Hola"},
+		{Channel: models.ChannelPush, Recipient: "token-1", Subject: "Reserva", Body: "Hola"},
+	}
+	for _, d := range deliveries {
+		if err := w.deliver(context.Background(), d); err != nil {
+			t.Fatalf("deliver(%s): %v", d.Channel, err)
+		}
+	}
+
+	tests := []struct {
+		notifier *FakeNotifier
+		want     Message
+	}{
+		{email, Message{To: "ana@example.com", Subject: "Reserva", Body: "<p>Hola</p>", Text: "Hola"}},
+		{sms, Message{To: "+18095550100", Subject: "Reserva", Body: "Hola"}},
+		{push, Message{To: "token-1", Subject: "Reserva", Body: "Hola"}},
+	}
+	for _, tt := range tests {
+		sent := tt.notifier.Sent()
+		if len(sent) != 1 || sent[0] != tt.want {
+			t.Errorf("%s: enviados %+v, se esperaba solo %+v", tt.notifier.Channel, sent, tt.want)
+		}
+	}
+}
+
+func TestDeliverUnknownChannelIsPermanent(t *testing.T) {
+	w := NewDeliveryWorker(nil, map[string]Notifier{models.ChannelEmail: NewFakeNotifier(models.ChannelEmail)})
+	err := w.deliver(context.Background(), models.Delivery{Channel: models.ChannelSMS, Recipient: "+18095550100"})
+	if !IsPermanent(err) {
+		t.Errorf("deliver sin notifier para el canal = %v, se esperaba un error permanente", err)
+	}
+}
+
+// Cada canal de una misma notificación avanza por su cuenta: el correo se entrega, el SMS falla de forma
+// transitoria y queda pendiente, y el push falla de forma permanente y queda en dead
+func TestRunOnceDeliveryStatusPerChannel(t *testing.T) {
+	db := testdb.Open(t)
+
+	user := models.User{Username: "ana", Password: "secreto123", Email: "ana@example.com", Phone: "+18095550100"}
+	if err := user.Register(db); err != nil {
+		t.Fatal(err)
+	}
+	prefs := models.ChannelPreferences{models.ChannelEmail: true, models.ChannelSMS: true, models.ChannelPush: true}
+	if err := models.SetChannelPreferences(db, user.ID, prefs); err != nil {
+		t.Fatal(err)
+	}
+	token := models.PushToken{UserID: user.ID, Token: "token-1", Platform: models.PlatformAndroid}
+	if err := token.Register(db); err != nil {
+		t.Fatal(err)
+	}
+	n := models.Notification{UserID: user.ID, Subject: "Reserva confirmada", Message: "Tu reserva está confirmada"}
+	if err := n.Send(db); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Deliveries) != 3 {
+		t.Fatalf("Send encoló %d envíos, se esperaban 3 (email, sms, push)", len(n.Deliveries))
+	}
+
+	email, sms, push := NewFakeNotifier(models.ChannelEmail), NewFakeNotifier(models.ChannelSMS), NewFakeNotifier(models.ChannelPush)
+	sms.Err = errors.New("proveedor no disponible")
+	push.Err = Permanent(errors.New("token no registrado"))
+	w := NewDeliveryWorker(db, map[string]Notifier{models.ChannelEmail: email, models.ChannelSMS: sms, models.ChannelPush: push})
+
+	processed, err := w.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed != 3 {
+		t.Fatalf("RunOnce procesó %d envíos, se esperaban 3", processed)
+	}
+	if sent := email.Sent(); len(sent) != 1 || sent[0].To != user.Email {
+		t.Errorf("correos enviados = %+v, se esperaba uno a %s", sent, user.Email)
+	}
+
+	deliveries, err := models.GetDeliveries(db, models.DeliveryFilter{NotificationID: n.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]struct {
+		status   string
+		attempts int
+	}{
+		models.ChannelEmail: {models.DeliverySent, 1},
+		models.ChannelSMS:   {models.DeliveryPending, 1},
+		models.ChannelPush:  {models.DeliveryDead, 1},
+	}
+	if len(deliveries) != len(want) {
+		t.Fatalf("la notificación tiene %d envíos, se esperaban %d", len(deliveries), len(want))
+	}
+	for _, d := range deliveries {
+		exp := want[d.Channel]
+		if d.Status != exp.status || d.Attempts != exp.attempts {
+			t.Errorf("%s: estado %s con %d intentos, se esperaba %s con %d", d.Channel, d.Status, d.Attempts, exp.status, exp.attempts)
+		}
+		if d.Status != models.DeliverySent && d.LastError == nil {
+			t.Errorf("%s: falta last_error", d.Channel)
+		}
+	}
+
+	// El SMS pendiente espera su backoff, así que una segunda ronda no lo vuelve a intentar todavía
+	if processed, err := w.RunOnce(context.Background()); err != nil || processed != 0 {
+		t.Errorf("segunda ronda: procesados %d, err %v; se esperaba 0", processed, err)
+	}
+}
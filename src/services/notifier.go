@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-auth-api/src/mail"
+	"log"
+	"sync"
+)
+
+// Message es lo que un canal entrega: el destinatario depende del canal (correo, teléfono o token
+// del dispositivo)
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	Text    string // Alternativa en texto plano de un cuerpo HTML; solo la usa el correo
+}
+
+// Notifier entrega mensajes por un canal concreto
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// PermanentError marca un fallo que no se corrige reintentando, por ejemplo un destinatario inválido
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent envuelve err como PermanentError
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent indica si err, o alguno de los errores que envuelve, es un PermanentError
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// EmailNotifier entrega notificaciones por correo con el servicio de correo
+type EmailNotifier struct {
+	Mail *mail.Service
+}
+
+// Notify envía el correo; los rechazos 5xx del servidor y las direcciones inválidas se marcan como
+// permanentes
+func (n EmailNotifier) Notify(ctx context.Context, msg Message) error {
+	err := n.Mail.Send(ctx, mail.Message{To: []string{msg.To}, Subject: msg.Subject, HTML: msg.Body, Text: msg.Text})
+	if err != nil {
+		if IsPermanentEmailError(err) {
+			return Permanent(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// FakeNotifier guarda los mensajes en memoria en lugar de entregarlos. Sirve en pruebas y en
+// entornos locales donde el canal no está configurado.
+type FakeNotifier struct {
+	Channel string
+	Err     error // Si no es nil, Notify lo devuelve sin registrar el mensaje
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+// NewFakeNotifier crea un FakeNotifier para el canal indicado
+func NewFakeNotifier(channel string) *FakeNotifier {
+	return &FakeNotifier{Channel: channel}
+}
+
+// Notify registra el mensaje
+func (n *FakeNotifier) Notify(ctx context.Context, msg Message) error {
+	if n.Err != nil {
+		return n.Err
+	}
+	n.mu.Lock()
+	n.sent = append(n.sent, msg)
+	n.mu.Unlock()
+	log.Printf("[%s simulado] a %s: %s", n.Channel, msg.To, msg.Subject)
+	return nil
+}
+
+// Sent devuelve una copia de los mensajes registrados
+func (n *FakeNotifier) Sent() []Message {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Message(nil), n.sent...)
+}
+
+// Reset olvida los mensajes registrados
+func (n *FakeNotifier) Reset() {
+	n.mu.Lock()
+	n.sent = nil
+	n.mu.Unlock()
+}
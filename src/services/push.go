@@ -0,0 +1,80 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// DefaultPushURL es el endpoint HTTP de Firebase Cloud Messaging
+const DefaultPushURL = "https://fcm.googleapis.com/fcm/send"
+
+// PushNotifier entrega notificaciones push a un dispositivo a través de FCM
+type PushNotifier struct {
+	URL       string
+	ServerKey string
+	Client    *http.Client
+
+	// OnInvalidToken, si no es nil, se llama cuando FCM indica que el token ya no existe, para
+	// dejar de enviarle notificaciones
+	OnInvalidToken func(token string)
+}
+
+// NewPushNotifier crea un PushNotifier contra FCM con un cliente HTTP con timeout
+func NewPushNotifier(serverKey string) *PushNotifier {
+	return &PushNotifier{URL: DefaultPushURL, ServerKey: serverKey, Client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// Errores de FCM que significan que el token no volverá a ser válido
+var invalidPushTokenErrors = map[string]bool{"NotRegistered": true, "InvalidRegistration": true, "MismatchSenderId": true}
+
+// Notify envía la notificación al token msg.To
+func (n *PushNotifier) Notify(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"to":           msg.To,
+		"notification": map[string]string{"title": msg.Subject, "body": msg.Body},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "key="+n.ServerKey)
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return gatewayError("FCM", resp)
+	}
+
+	// FCM responde 200 también cuando el envío falla; el detalle viene por token en results
+	var result struct {
+		Failure int `json:"failure"`
+		Results []struct {
+			Error string `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if result.Failure == 0 || len(result.Results) == 0 {
+		return nil
+	}
+	code := result.Results[0].Error
+	if invalidPushTokenErrors[code] {
+		if n.OnInvalidToken != nil {
+			n.OnInvalidToken(msg.To)
+		}
+		return Permanent(errors.New("FCM: " + code))
+	}
+	return errors.New("FCM: " + code)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SMSNotifier entrega mensajes de texto a través de una pasarela HTTP. La pasarela recibe un POST
+// JSON {"from", "to", "body"} autenticado con un token Bearer.
+type SMSNotifier struct {
+	URL    string
+	Token  string
+	From   string
+	Client *http.Client
+}
+
+// NewSMSNotifier crea un SMSNotifier con un cliente HTTP con timeout
+func NewSMSNotifier(url, token, from string) *SMSNotifier {
+	return &SMSNotifier{URL: url, Token: token, From: from, Client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// Notify envía msg.Body al teléfono msg.To. El asunto no se envía: en un SMS solo cuenta el texto.
+func (n *SMSNotifier) Notify(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(map[string]string{"from": n.From, "to": msg.To, "body": msg.Body})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+n.Token)
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	return gatewayError("pasarela SMS", resp)
+}
+
+// gatewayError convierte una respuesta de error en un error. Los 4xx (salvo 408 y 429) indican que
+// el mensaje nunca será aceptado y se marcan como permanentes; el resto se reintenta.
+func gatewayError(name string, resp *http.Response) error {
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err := fmt.Errorf("%s respondió %d: %s", name, resp.StatusCode, bytes.TrimSpace(detail))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
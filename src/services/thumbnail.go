@@ -0,0 +1,64 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Registrar decodificadores soportados por image.Decode
+	"image/jpeg"
+	_ "image/png"
+)
+
+// GenerateThumbnail reduce img para que su lado mayor no supere maxSide y la codifica como JPEG.
+// Usa un filtro de promedio por área, suficiente para miniaturas sin dependencias externas.
+func GenerateThumbnail(img image.Image, maxSide int) ([]byte, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			tw, th = maxSide, max(1, h*maxSide/w)
+		} else {
+			tw, th = max(1, w*maxSide/h), maxSide
+		}
+	}
+
+	// Cada fila de la miniatura promedia una franja de filas del original. Solo esa franja se
+	// normaliza a RGBA sobre fondo blanco (JPEG no tiene transparencia), así la memoria no crece con
+	// el tamaño del original.
+	strip := image.NewRGBA(image.Rect(0, 0, w, (h+th-1)/th+1))
+	white := image.NewUniform(color.White)
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := y*h/th, max((y+1)*h/th, y*h/th+1)
+		rows := image.Rect(0, 0, w, y1-y0)
+		draw.Draw(strip, rows, white, image.Point{}, draw.Src)
+		draw.Draw(strip, rows, img, image.Pt(b.Min.X, b.Min.Y+y0), draw.Over)
+		for x := 0; x < tw; x++ {
+			x0, x1 := x*w/tw, max((x+1)*w/tw, x*w/tw+1)
+			var r, g, bl, n uint32
+			for sy := 0; sy < y1-y0; sy++ {
+				row := strip.Pix[sy*strip.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					bl += uint32(p[2])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 82}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
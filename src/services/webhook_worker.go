@@ -0,0 +1,223 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-auth-api/src/models"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Cabeceras de cada entrega. La firma es el HMAC-SHA256, con el secreto de la suscripción, de
+// "<timestamp>.<cuerpo>"; el receptor debe rechazar timestamps viejos para evitar repeticiones.
+const (
+	WebhookIDHeader        = "X-Webhook-Id" // Id del evento, igual en los reintentos: sirve para descartar duplicados
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature" // "sha256=<hex>"
+)
+
+// webhookResponseLimit es cuánto de la respuesta se lee, y se descarta, para poder reutilizar la conexión
+const webhookResponseLimit = 2048
+
+// ErrWebhookAddressBlocked indica que la URL de un webhook no es https o resolvió a una dirección interna
+var ErrWebhookAddressBlocked = errors.New("la dirección del webhook no está permitida")
+
+// blockedWebhookPrefixes son los rangos no públicos que netip no clasifica por sí mismo
+var blockedWebhookPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),         // "Esta red"
+	netip.MustParsePrefix("100.64.0.0/10"),     // NAT de operadora; incluye metadatos de algunas nubes (100.100.100.200)
+	netip.MustParsePrefix("192.0.0.0/24"),      // Asignaciones de protocolo del IETF
+	netip.MustParsePrefix("198.18.0.0/15"),     // Pruebas de rendimiento
+	netip.MustParsePrefix("240.0.0.0/4"),       // Reservado, incluye 255.255.255.255
+	netip.MustParsePrefix("64:ff9b::/96"),      // NAT64: lleva dentro una IPv4 cualquiera
+	netip.MustParsePrefix("64:ff9b:1::/48"),    // NAT64 local
+	netip.MustParsePrefix("fd00:ec2::254/128"), // Metadatos de AWS por IPv6 (ya dentro de fc00::/7, por claridad)
+}
+
+// publicWebhookAddress indica si un webhook puede conectarse a ip: no lo puede hacer a loopback, redes
+// privadas, link-local (donde están los metadatos de la nube, 169.254.169.254) ni rangos reservados
+func publicWebhookAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range blockedWebhookPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// refuseInternalAddress es el Control del dialer de los webhooks. Se ejecuta con la IP ya resuelta
+// justo antes de cada conexión, así que tampoco pasa un nombre que resuelva a una dirección interna
+// ni uno que cambie de IP entre la validación y el envío.
+func refuseInternalAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrWebhookAddressBlocked, address)
+	}
+	if !publicWebhookAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrWebhookAddressBlocked, addrPort.Addr())
+	}
+	return nil
+}
+
+// newWebhookClient crea el cliente HTTP de las entregas: solo se conecta a direcciones públicas, sin
+// proxy (el proxy conectaría por nosotros sin pasar por la comprobación) y sin seguir redirecciones,
+// que podrían llevar la petición a otro destino
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second, Control: refuseInternalAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// WebhookEnvelope es el cuerpo JSON de cada entrega
+type WebhookEnvelope struct {
+	ID             int64           `json:"id"`
+	Type           string          `json:"type"`
+	CreatedAt      time.Time       `json:"created_at"`
+	OrganizationID int             `json:"organization_id"`
+	UserID         *int            `json:"user_id"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// SignWebhook calcula la firma del cuerpo para el timestamp dado
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookWorker entrega los eventos a las suscripciones de las organizaciones, con reintentos
+type WebhookWorker struct {
+	DB           *sql.DB
+	Client       *http.Client
+	BatchSize    int           // Entregas reclamadas por ronda
+	PollInterval time.Duration // Espera entre rondas cuando no hay trabajo
+	Lease        time.Duration // Tiempo tras el cual otro worker puede reclamar una entrega que no terminó
+}
+
+// NewWebhookWorker crea un worker con los valores por defecto; cada petición tiene 10 segundos y solo
+// puede ir a direcciones públicas
+func NewWebhookWorker(db *sql.DB) *WebhookWorker {
+	return &WebhookWorker{DB: db, Client: newWebhookClient(), BatchSize: 20,
+		PollInterval: 5 * time.Second, Lease: 2 * time.Minute}
+}
+
+// Start procesa las entregas en segundo plano hasta que se cancele ctx
+func (w *WebhookWorker) Start(ctx context.Context) {
+	go func() {
+		for {
+			processed, err := w.RunOnce(ctx)
+			if err != nil {
+				log.Printf("Error entregando webhooks: %v", err)
+			}
+			if err == nil && processed == w.BatchSize {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.PollInterval):
+			}
+		}
+	}()
+}
+
+// RunOnce reclama y entrega una ronda de webhooks; devuelve cuántos procesó
+func (w *WebhookWorker) RunOnce(ctx context.Context) (int, error) {
+	jobs, err := models.ClaimWebhookDeliveries(w.DB, w.BatchSize, w.Lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, j := range jobs {
+		attempt, delivered, permanent := w.deliver(ctx, j)
+		if err := models.RecordWebhookAttempt(w.DB, j, attempt, delivered, permanent); err != nil {
+			return 0, err
+		}
+		if !delivered {
+			log.Printf("Webhook %d (%s) a %s falló (intento %d, permanente=%t): %s",
+				j.ID, j.EventType, j.URL, j.Attempts+1, permanent, *attempt.Error)
+		}
+	}
+	return len(jobs), nil
+}
+
+// deliver hace la petición y devuelve el intento a registrar. Solo las respuestas 2xx cuentan como
+// entregadas, así que una redirección es un fallo; 410 Gone indica que el receptor ya no existe y no se
+// reintenta, igual que una URL que no es https o que resuelve a una dirección interna. Del receptor
+// solo se guarda el código de estado.
+func (w *WebhookWorker) deliver(ctx context.Context, j models.WebhookJob) (models.WebhookAttempt, bool, bool) {
+	attempt := models.WebhookAttempt{AttemptedAt: time.Now()}
+	// Las direcciones internas no dejan de serlo al reintentar
+	fail := func(err error) (models.WebhookAttempt, bool, bool) {
+		msg := err.Error()
+		attempt.Error = &msg
+		attempt.DurationMs = int(time.Since(attempt.AttemptedAt).Milliseconds())
+		return attempt, false, errors.Is(err, ErrWebhookAddressBlocked)
+	}
+
+	// Las suscripciones creadas antes de exigir https no se entregan hasta que cambien su URL
+	if u, err := url.Parse(j.URL); err != nil || u.Scheme != "https" {
+		return fail(fmt.Errorf("%w: la url debe ser https", ErrWebhookAddressBlocked))
+	}
+
+	body, err := json.Marshal(WebhookEnvelope{ID: j.EventID, Type: j.EventType, CreatedAt: j.EventCreatedAt,
+		OrganizationID: j.OrganizationID, UserID: j.UserID, Data: j.Payload})
+	if err != nil {
+		return fail(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.URL, bytes.NewReader(body))
+	if err != nil {
+		return fail(err)
+	}
+	timestamp := attempt.AttemptedAt.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GolanCar-Webhooks/1.0")
+	req.Header.Set(WebhookIDHeader, strconv.FormatInt(j.EventID, 10))
+	req.Header.Set(WebhookEventHeader, j.EventType)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(j.Secret, timestamp, body))
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseLimit))
+	attempt.DurationMs = int(time.Since(attempt.AttemptedAt).Milliseconds())
+	attempt.StatusCode = &resp.StatusCode
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return attempt, true, false
+	}
+	msg := fmt.Sprintf("el receptor respondió %d", resp.StatusCode)
+	attempt.Error = &msg
+	return attempt, false, resp.StatusCode == http.StatusGone
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"go-auth-api/src/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPublicWebhookAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false}, // Metadatos de la nube
+		{"fd00:ec2::254", false},
+		{"100.100.100.200", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false}, // IPv4 dentro de IPv6
+		{"64:ff9b::a9fe:a9fe", false},
+	}
+	for _, tt := range tests {
+		if got := publicWebhookAddress(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("publicWebhookAddress(%s) = %t, se esperaba %t", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func webhookJob(url string) models.WebhookJob {
+	return models.WebhookJob{
+		WebhookDelivery: models.WebhookDelivery{ID: 1, EventID: 7, EventType: "reservation.created"},
+		URL:             url,
+		Secret:          "whsec_test",
+		Payload:         []byte(`{}`),
+	}
+}
+
+func TestWebhookDeliverRefusesInternalAddresses(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	w := NewWebhookWorker(nil)
+	for _, url := range []string{srv.URL, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)} {
+		attempt, delivered, permanent := w.deliver(context.Background(), webhookJob(url))
+		if delivered || !permanent || attempt.Error == nil || !strings.Contains(*attempt.Error, ErrWebhookAddressBlocked.Error()) {
+			t.Errorf("%s: entregado=%t permanente=%t error=%v; se esperaba el rechazo de la dirección",
+				url, delivered, permanent, attempt.Error)
+		}
+	}
+	if hits.Load() != 0 {
+		t.Errorf("el servidor interno recibió %d peticiones", hits.Load())
+	}
+}
+
+func TestWebhookDeliverRequiresHTTPS(t *testing.T) {
+	attempt, delivered, permanent := NewWebhookWorker(nil).deliver(context.Background(), webhookJob("http://example.com/hook"))
+	if delivered || !permanent || attempt.StatusCode != nil {
+		t.Errorf("entregado=%t permanente=%t; una url http no debe enviarse", delivered, permanent)
+	}
+}
+
+func TestWebhookDeliverDoesNotFollowRedirects(t *testing.T) {
+	var followed atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	})
+	mux.HandleFunc("/internal", func(w http.ResponseWriter, r *http.Request) {
+		followed.Store(true)
+	})
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+
+	// El cliente de los webhooks, pero con un transporte que confía en el servidor de prueba y le
+	// permite conectarse a loopback
+	w := NewWebhookWorker(nil)
+	w.Client.Transport = srv.Client().Transport
+
+	attempt, delivered, permanent := w.deliver(context.Background(), webhookJob(srv.URL+"/hook"))
+	if followed.Load() {
+		t.Fatal("el worker siguió la redirección")
+	}
+	if delivered || permanent {
+		t.Errorf("entregado=%t permanente=%t; una redirección es un fallo que se reintenta", delivered, permanent)
+	}
+	if attempt.StatusCode == nil || *attempt.StatusCode != http.StatusFound {
+		t.Errorf("código registrado %v, se esperaba %d", attempt.StatusCode, http.StatusFound)
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"id":7,"type":"reservation.created"}`)
+	// Calculado aparte con HMAC-SHA256 sobre "1760860800." seguido del cuerpo
+	want := "sha256=e9dc1e741d48b0dd26c5d3ae89cda67de50ede86fbc70b3fbc00471582b5457d"
+	if got := SignWebhook("whsec_test", 1760860800, body); got != want {
+		t.Fatalf("SignWebhook = %s, se esperaba %s", got, want)
+	}
+
+	// Cambiar el secreto, el timestamp o el cuerpo cambia la firma
+	changed := map[string]string{
+		"secreto":   SignWebhook("whsec_otro", 1760860800, body),
+		"timestamp": SignWebhook("whsec_test", 1760860801, body),
+		"cuerpo":    SignWebhook("whsec_test", 1760860800, []byte(`{"id":8,"type":"reservation.created"}`)),
+	}
+	for what, got := range changed {
+		if got == want {
+			t.Errorf("la firma no cambia con otro %s", what)
+		}
+	}
+}
+
+func TestWebhookDeliverSignsRequest(t *testing.T) {
+	var header http.Header
+	var body []byte
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	w := NewWebhookWorker(nil)
+	w.Client.Transport = srv.Client().Transport
+	job := webhookJob(srv.URL)
+	attempt, delivered, _ := w.deliver(context.Background(), job)
+	if !delivered {
+		t.Fatalf("no se entregó: %v", attempt.Error)
+	}
+
+	// El receptor verifica la firma con su secreto, el timestamp de la cabecera y el cuerpo recibido
+	timestamp, err := strconv.ParseInt(header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("timestamp inválido %q", header.Get(WebhookTimestampHeader))
+	}
+	if timestamp != attempt.AttemptedAt.Unix() {
+		t.Errorf("timestamp %d, se esperaba la hora del intento %d", timestamp, attempt.AttemptedAt.Unix())
+	}
+	if got, want := header.Get(WebhookSignatureHeader), SignWebhook(job.Secret, timestamp, body); got != want {
+		t.Errorf("firma %s, se esperaba %s", got, want)
+	}
+	if header.Get(WebhookIDHeader) != "7" || header.Get(WebhookEventHeader) != "reservation.created" {
+		t.Errorf("cabeceras %s=%q %s=%q", WebhookIDHeader, header.Get(WebhookIDHeader),
+			WebhookEventHeader, header.Get(WebhookEventHeader))
+	}
+	var envelope WebhookEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.ID != job.EventID || envelope.Type != job.EventType {
+		t.Errorf("cuerpo %s, se esperaba el sobre del evento %d", body, job.EventID)
+	}
+}
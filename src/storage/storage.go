@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BlobStore guarda archivos binarios (imágenes, adjuntos) y expone su URL pública
+type BlobStore interface {
+	// Put guarda data bajo key y devuelve la URL pública del objeto
+	Put(ctx context.Context, key string, data []byte, contentType string) (string, error)
+	// Delete elimina el objeto; no falla si ya no existe
+	Delete(ctx context.Context, key string) error
+}
+
+// Store es el almacenamiento configurado para la aplicación
+var Store BlobStore
+
+// Config elige y configura el almacenamiento. Driver es "local" (por defecto) o "s3" para un
+// servicio compatible con S3 (AWS, MinIO, R2...); los campos S3 solo se usan con este último.
+type Config struct {
+	Driver   string
+	LocalDir string // Directorio del almacenamiento local, que main sirve como estático
+	LocalURL string // Prefijo público con el que se sirve LocalDir
+
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PublicURL string
+	S3PathStyle bool
+}
+
+// Validate comprueba que el driver existe y tiene lo que necesita
+func (c Config) Validate() error {
+	switch c.Driver {
+	case "local":
+		if c.LocalDir == "" {
+			return errors.New("falta el directorio del almacenamiento local")
+		}
+	case "s3":
+		if c.S3Bucket == "" || c.S3AccessKey == "" || c.S3SecretKey == "" {
+			return errors.New("S3_BUCKET, S3_ACCESS_KEY_ID y S3_SECRET_ACCESS_KEY son requeridos con STORAGE_DRIVER=s3")
+		}
+	default:
+		return fmt.Errorf("STORAGE_DRIVER no soportado: %s", c.Driver)
+	}
+	return nil
+}
+
+// Init configura Store
+func Init(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	switch cfg.Driver {
+	case "local":
+		Store = &LocalStore{Dir: cfg.LocalDir, BaseURL: cfg.LocalURL}
+	case "s3":
+		Store = &S3Store{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PublicURL: cfg.S3PublicURL,
+			PathStyle: cfg.S3PathStyle,
+		}
+	}
+	return nil
+}
+
+// IsLocal indica si el almacenamiento configurado es el sistema de archivos local
+func IsLocal() bool {
+	_, ok := Store.(*LocalStore)
+	return ok
+}
@@ -0,0 +1,98 @@
+package templates
+
+import "time"
+
+// View es lo que reciben las plantillas: el nombre del destinatario en .Name y los datos propios de
+// la plantilla en .Data. Con UnsubscribeURL el pie del correo incluye el enlace para dejar de
+// recibir ese tipo de mensajes. Las fechas se muestran en Location, la zona horaria del
+// destinatario; sin ella, en UTC.
+type View struct {
+	Name           string
+	Data           interface{}
+	UnsubscribeURL string
+	Location       *time.Location
+}
+
+// NotificationData es el contenido de un mensaje libre
+type NotificationData struct {
+	Message string
+}
+
+// ReservationData describe una reserva: su confirmación, el recordatorio de recogida y el aviso de
+// retraso
+type ReservationData struct {
+	ReservationID int
+	Vehicle       string // Marca y modelo
+	LicensePlate  string
+	Start         time.Time
+	End           time.Time
+}
+
+// ReminderData describe el recordatorio de devolución; Vehicle y End pueden faltar cuando el
+// recordatorio no está ligado a una reserva concreta
+type ReminderData struct {
+	Vehicle string
+	End     *time.Time
+}
+
+// ReceiptData describe un pago recibido
+type ReceiptData struct {
+	PaymentID     int
+	ReservationID int
+	Amount        float64
+	Description   string
+	PaidAt        time.Time
+}
+
+// PasswordResetData lleva el enlace para elegir una nueva contraseña
+type PasswordResetData struct {
+	ResetURL  string
+	ExpiresIn time.Duration
+}
+
+// Minutes devuelve la vigencia del enlace en minutos, para mostrarla en la plantilla
+func (d PasswordResetData) Minutes() int {
+	return int(d.ExpiresIn / time.Minute)
+}
+
+// samples son datos de ejemplo para la vista previa de cada plantilla
+var samples = map[string]func() interface{}{
+	Notification: func() interface{} {
+		return NotificationData{Message: "Su vehículo fue limpiado y está listo para el viaje."}
+	},
+	ReservationConfirmed: func() interface{} {
+		start := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+		return ReservationData{ReservationID: 1024, Vehicle: "Toyota Corolla", LicensePlate: "1234ABC",
+			Start: start, End: start.Add(2 * time.Hour)}
+	},
+	PickupReminder: func() interface{} {
+		start := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+		return ReservationData{ReservationID: 1024, Vehicle: "Toyota Corolla", LicensePlate: "1234ABC",
+			Start: start, End: start.Add(2 * time.Hour)}
+	},
+	ReservationOverdue: func() interface{} {
+		start := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+		return ReservationData{ReservationID: 1024, Vehicle: "Toyota Corolla", LicensePlate: "1234ABC",
+			Start: start, End: start.Add(2 * time.Hour)}
+	},
+	ReturnReminder: func() interface{} {
+		end := time.Date(2024, 5, 10, 11, 0, 0, 0, time.UTC)
+		return ReminderData{Vehicle: "Toyota Corolla", End: &end}
+	},
+	PaymentReceipt: func() interface{} {
+		return ReceiptData{PaymentID: 512, ReservationID: 1024, Amount: 45.5,
+			Description: "Alquiler", PaidAt: time.Date(2024, 5, 10, 11, 5, 0, 0, time.UTC)}
+	},
+	PasswordReset: func() interface{} {
+		return PasswordResetData{ResetURL: "https://example.com/reset?token=abc123", ExpiresIn: time.Hour}
+	},
+}
+
+// Sample devuelve los datos de ejemplo de la plantilla name
+func Sample(name string) (interface{}, bool) {
+	sample, ok := samples[name]
+	if !ok {
+		return nil, false
+	}
+	return sample(), true
+}
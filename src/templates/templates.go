@@ -0,0 +1,154 @@
+// Package templates genera el contenido de las notificaciones a partir de plantillas con nombre,
+// traducidas a cada idioma soportado. El asunto y los textos se renderizan con text/template y el
+// cuerpo HTML del correo con html/template, que escapa los datos del usuario.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Idiomas soportados
+const (
+	LanguageSpanish = "es"
+	LanguageEnglish = "en"
+	DefaultLanguage = LanguageSpanish
+)
+
+// Languages lista los idiomas en los que existen todas las plantillas
+var Languages = []string{LanguageSpanish, LanguageEnglish}
+
+// Nombres de las plantillas
+const (
+	Notification         = "notification" // Mensaje libre enviado por un operador
+	ReservationConfirmed = "reservation_confirmed"
+	PickupReminder       = "pickup_reminder"
+	ReturnReminder       = "return_reminder"
+	ReservationOverdue   = "reservation_overdue"
+	PaymentReceipt       = "payment_receipt"
+	PasswordReset        = "password_reset"
+)
+
+// Rendered es el contenido de una notificación ya generado
+type Rendered struct {
+	Subject string `json:"subject"` // Asunto del correo y título del push
+	Message string `json:"message"` // Texto corto: bandeja de notificaciones, SMS y push
+	Text    string `json:"text"`    // Alternativa en texto plano del correo
+	HTML    string `json:"html"`    // Cuerpo HTML del correo
+}
+
+//go:embed notifications
+var files embed.FS
+
+// Cada archivo <idioma>/<nombre>.tmpl define los bloques subject, message, text y html; el bloque
+// html se muestra dentro de <idioma>/layout.tmpl, que también define el pie text_footer del texto plano.
+// Las plantillas del registro no se ejecutan: Render usa una copia con las funciones de la zona horaria
+// del destinatario, y html/template no permite copiar una plantilla ya ejecutada.
+type compiled struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var registry = map[string]map[string]compiled{}
+
+func init() {
+	for _, lang := range Languages {
+		registry[lang] = map[string]compiled{}
+		for _, name := range Names() {
+			file := "notifications/" + lang + "/" + name + ".tmpl"
+			text := texttemplate.Must(texttemplate.New(name).Funcs(texttemplate.FuncMap(funcs(lang, time.UTC))).
+				ParseFS(files, "notifications/"+lang+"/layout.tmpl", file))
+			html := htmltemplate.Must(htmltemplate.New(name).Funcs(htmltemplate.FuncMap(funcs(lang, time.UTC))).
+				ParseFS(files, "notifications/"+lang+"/layout.tmpl", file))
+			registry[lang][name] = compiled{text: text, html: html}
+		}
+	}
+}
+
+// Names lista las plantillas disponibles
+func Names() []string {
+	names := make([]string, 0, len(samples))
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SupportedLanguage indica si lang es uno de Languages
+func SupportedLanguage(lang string) bool {
+	for _, l := range Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Render genera la plantilla name con view en el idioma lang; si el idioma no está soportado se usa
+// DefaultLanguage
+func Render(name, lang string, view View) (Rendered, error) {
+	if !SupportedLanguage(lang) {
+		lang = DefaultLanguage
+	}
+	t, ok := registry[lang][name]
+	if !ok {
+		return Rendered{}, fmt.Errorf("plantilla desconocida: %s", name)
+	}
+	text, err := t.text.Clone()
+	if err != nil {
+		return Rendered{}, err
+	}
+	html, err := t.html.Clone()
+	if err != nil {
+		return Rendered{}, err
+	}
+	fm := funcs(lang, view.Location)
+	text.Funcs(texttemplate.FuncMap(fm))
+	html.Funcs(htmltemplate.FuncMap(fm))
+
+	var r Rendered
+	blocks := map[string]*string{"subject": &r.Subject, "message": &r.Message, "text": &r.Text}
+	for block, dst := range blocks {
+		var buf bytes.Buffer
+		if err := text.ExecuteTemplate(&buf, block, view); err != nil {
+			return Rendered{}, err
+		}
+		*dst = strings.TrimSpace(buf.String())
+	}
+	var footer bytes.Buffer
+	if err := text.ExecuteTemplate(&footer, "text_footer", view); err != nil {
+		return Rendered{}, err
+	}
+	if f := strings.TrimSpace(footer.String()); f != "" {
+		r.Text += "\n\n--\n" + f
+	}
+	var buf bytes.Buffer
+	if err := html.ExecuteTemplate(&buf, "layout", view); err != nil {
+		return Rendered{}, err
+	}
+	r.HTML = buf.String()
+	return r, nil
+}
+
+// funcs son las funciones disponibles en las plantillas, con el formato del idioma. Las fechas se
+// muestran en loc con la abreviatura de la zona
+func funcs(lang string, loc *time.Location) map[string]interface{} {
+	if loc == nil {
+		loc = time.UTC
+	}
+	dateLayout := "02/01/2006 15:04 MST"
+	if lang == LanguageEnglish {
+		dateLayout = "Jan 2, 2006 3:04 PM MST"
+	}
+	return map[string]interface{}{
+		"date":  func(t time.Time) string { return t.In(loc).Format(dateLayout) },
+		"money": func(amount float64) string { return fmt.Sprintf("$%.2f", amount) },
+	}
+}
@@ -0,0 +1,75 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderDatesInRecipientTimezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("sin datos de zonas horarias: %v", err)
+	}
+	data := ReservationData{
+		ReservationID: 42, Vehicle: "Toyota Corolla",
+		Start: time.Date(2026, 7, 1, 2, 30, 0, 0, time.UTC),
+		End:   time.Date(2026, 12, 1, 18, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name     string
+		lang     string
+		loc      *time.Location
+		want     []string
+		unwanted string
+	}{
+		{"sin zona se usa UTC", LanguageSpanish, nil,
+			[]string{"01/07/2026 02:30 UTC", "01/12/2026 18:00 UTC"}, "EDT"},
+		// La recogida cae el día anterior en Nueva York y la devolución ya en horario de invierno
+		{"zona del destinatario", LanguageSpanish, newYork,
+			[]string{"30/06/2026 22:30 EDT", "01/12/2026 13:00 EST"}, "UTC"},
+		{"formato en inglés", LanguageEnglish, newYork,
+			[]string{"Jun 30, 2026 10:30 PM EDT", "Dec 1, 2026 1:00 PM EST"}, "UTC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Render("pickup_reminder", tt.lang, View{Name: "Ana", Data: data, Location: tt.loc})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, body := range []string{r.Text, r.HTML} {
+				for _, want := range tt.want {
+					if !strings.Contains(body, want) {
+						t.Errorf("falta la fecha %q en:\n%s", want, body)
+					}
+				}
+				if strings.Contains(body, tt.unwanted) {
+					t.Errorf("aparece %q en:\n%s", tt.unwanted, body)
+				}
+			}
+			if !strings.Contains(r.Message, tt.want[0]) {
+				t.Errorf("mensaje %q, se esperaba la recogida %q", r.Message, tt.want[0])
+			}
+		})
+	}
+}
+
+// Cada Render usa su propia copia de las plantillas, así que una zona no se filtra a la siguiente
+func TestRenderDoesNotLeakTimezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("sin datos de zonas horarias: %v", err)
+	}
+	data := ReservationData{Start: time.Date(2026, 7, 1, 2, 30, 0, 0, time.UTC)}
+	if _, err := Render("pickup_reminder", LanguageSpanish, View{Data: data, Location: newYork}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Render("pickup_reminder", LanguageSpanish, View{Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(r.Message, "01/07/2026 02:30 UTC") {
+		t.Errorf("mensaje %q, se esperaba la fecha en UTC", r.Message)
+	}
+}
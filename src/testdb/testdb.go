@@ -0,0 +1,60 @@
+// Package testdb prepares PostgreSQL schemas for the tests that need a real database
+package testdb
+
+import (
+	"database/sql"
+	"fmt"
+	"go-auth-api/src/config"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// Open returns a connection to a scratch schema of the database named by TEST_DATABASE_URL,
+// migrated to the latest version and dropped when the test finishes. Tests that need PostgreSQL
+// are skipped when the variable is not set.
+func Open(tb testing.TB) *sql.DB {
+	tb.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		tb.Skip("TEST_DATABASE_URL no está definido")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+	// Every connection of this pool resolves unqualified tables to the scratch schema
+	db, err := sql.Open("postgres", withSearchPath(dsn, schema))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	if err := config.Migrate(db); err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+// withSearchPath adds the search_path run-time parameter to a URL or key=value connection string
+func withSearchPath(dsn, schema string) string {
+	if strings.Contains(dsn, "://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		return dsn + sep + "search_path=" + schema
+	}
+	return dsn + " search_path=" + schema
+}